@@ -0,0 +1,16 @@
+package handler
+
+import (
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"packet_cloud/biz/mw"
+)
+
+// clientName returns the client authenticated by its API key, falling back to
+// the name the request claims when no key was sent.
+func clientName(c *app.RequestContext, claimed string) string {
+	if name, ok := mw.Client(c); ok {
+		return name
+	}
+	return claimed
+}
@@ -7,13 +7,18 @@ import (
 	"fmt"
 	"log"
 	packet "packet_cloud/biz/model/hertz/packet"
+	"packet_cloud/biz/mw"
+	"packet_cloud/service/auth"
+	"packet_cloud/service/push"
 	"packet_cloud/service/readwriter"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
 )
 
-// DeletePacket .
+// DeletePacket moves the packets matching every selector of the request to
+// the recycle bin. A dry run only reports them, with the confirmation token
+// a large delete needs.
 // @router /v1/packet/delete [DELETE]
 func DeletePacket(ctx context.Context, c *app.RequestContext) {
 	var err error
@@ -25,34 +30,71 @@ func DeletePacket(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	var (
-		deletedIDs = make([]int32, 0)
-	)
+	sel := readwriter.Selector{
+		IDs:        req.GetIds(),
+		From:       req.GetFrom(),
+		To:         req.GetTo(),
+		OpenEnded:  req.GetOpenEnded(),
+		Uploader:   req.GetUploader(),
+		Region:     req.GetRegion(),
+		Channel:    req.GetChannel(),
+		Name:       req.GetName(),
+		TimeBefore: req.GetTimeBefore(),
+	}
+	if sel.Validate() != nil {
+		c.String(consts.StatusBadRequest, "invalid params")
+		return
+	}
+	admin := c.GetString(mw.AdminKey)
 
-	packets, err := readwriter.ReadPacket(readwriter.LFS)
+	ids, err := readwriter.Select(ctx, sel, readwriter.LFS)
 	if err != nil {
-		log.Println("[DeletePacket] read file error:", err)
+		log.Println("[DeletePacket] select packets error:", err)
 		c.JSON(consts.StatusInternalServerError, err)
 		return
 	}
 
-	remaining := make([]*packet.CloudPacket, 0)
-	for _, p := range packets {
-		if p.Id >= req.GetFrom() && p.Id <= req.GetTo() {
-			deletedIDs = append(deletedIDs, p.Id)
-		} else {
-			remaining = append(remaining, p)
+	if req.GetDryRun() {
+		resp := &packet.DeletePacketResp{
+			Code: 0,
+			Msg:  fmt.Sprintf("预览成功, 将删除 %d 个数据包", len(ids)),
+			Ids:  ids,
 		}
+		if auth.ConfirmationRequired(len(ids)) {
+			if resp.Confirm, err = auth.NewDeleteConfirmation(admin, ids); err != nil {
+				log.Println("[DeletePacket] new confirmation error:", err)
+				c.JSON(consts.StatusInternalServerError, err)
+				return
+			}
+		}
+		c.JSON(consts.StatusOK, resp)
+		return
 	}
 
-	err = readwriter.SavePacket(remaining, readwriter.LFS)
-	if err != nil {
-		c.JSON(consts.StatusInternalServerError, err)
+	if auth.ConfirmationRequired(len(ids)) && !auth.ConfirmDelete(req.GetConfirm(), admin, ids) {
+		c.String(consts.StatusPreconditionRequired,
+			fmt.Sprintf("deleting %d packets needs the confirm token of a dry run of the same selection", len(ids)))
 		return
 	}
 
+	// Delete only what was selected and confirmed above, even if packets
+	// matching the selectors were uploaded since.
+	sel.IDs = ids
+	deletedIDs := ids
+	if len(ids) > 0 {
+		deletedIDs, err = readwriter.Trash(ctx, sel, admin, readwriter.LFS)
+		if err != nil {
+			log.Println("[DeletePacket] delete packets error:", err)
+			c.JSON(consts.StatusInternalServerError, err)
+			return
+		}
+	}
+	push.Publish(push.Delete, deletedIDs)
+
+	log.Printf("[DeletePacket] admin=%s deleted %v\n", admin, deletedIDs)
 	c.JSON(consts.StatusOK, &packet.DeletePacketResp{
 		Code: 0,
-		Msg:  fmt.Sprintf("删除成功, 共删除 %d 个数据包, 被删除的数据包 ID 为 %v", len(deletedIDs), deletedIDs),
+		Msg:  fmt.Sprintf("删除成功, 共 %d 个数据包移入回收站, 被删除的数据包 ID 为 %v", len(deletedIDs), deletedIDs),
+		Ids:  deletedIDs,
 	})
 }
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"packet_cloud/util"
+)
+
+// notModified sets the validators of a response and answers 304 Not Modified
+// when the If-None-Match header matches etag. A zero modified time leaves out
+// Last-Modified. It reports whether the response is complete.
+func notModified(c *app.RequestContext, etag string, modified time.Time) bool {
+	match := util.MatchETag(string(c.GetHeader("If-None-Match")), etag)
+	if match {
+		c.NotModified()
+	}
+	// Caches may keep the body but must check the tag before reusing it.
+	c.Response.Header.Set("Cache-Control", "no-cache")
+	c.Response.Header.Set("ETag", etag)
+	if !modified.IsZero() {
+		c.Response.Header.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	return match
+}
@@ -4,7 +4,9 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"log"
+	"packet_cloud/service/aeskey"
 	"packet_cloud/service/readwriter"
 	"packet_cloud/util"
 
@@ -26,43 +28,81 @@ func GetPacketByID(ctx context.Context, c *app.RequestContext) {
 		c.String(consts.StatusBadRequest, err.Error())
 		return
 	}
+	username := clientName(c, req.Username)
 
-	packets, err := readwriter.ReadPacket(readwriter.LFS)
+	mode, ok := cipherMode(c, req.Cipher)
+	if !ok {
+		c.String(consts.StatusBadRequest, "invalid cipher")
+		return
+	}
+
+	p, err := readwriter.Get(ctx, req.GetId(), readwriter.LFS)
+	if errors.Is(err, readwriter.ErrNotFound) {
+		log.Printf("[GetPacketByID] packet not found, username=%s, time=%s, id=%d\n", username, req.Time, req.GetId())
+		c.JSON(consts.StatusOK, nil)
+		return
+	}
 	if err != nil {
-		log.Printf("[GetPacketByID] username=%s, time=%s, error=%s\n", req.Username, req.Time, err)
+		log.Printf("[GetPacketByID] username=%s, time=%s, error=%s\n", username, req.Time, err)
 		c.JSON(consts.StatusInternalServerError, err)
 		return
 	}
 
-	for _, p := range packets {
-		if p.Id != req.GetId() {
-			continue
-		}
-
-		bs, err := sonic.Marshal(p)
-		if err != nil {
-			log.Printf("[GetPacketByID] marshal error, username=%s, time=%s, id=%d, error=%s\n", req.Username, req.Time, req.GetId(), err)
-			c.JSON(consts.StatusInternalServerError, err)
-			return
-		}
+	bs, err := sonic.Marshal(p)
+	if err != nil {
+		log.Printf("[GetPacketByID] marshal error, username=%s, time=%s, id=%d, error=%s\n", username, req.Time, req.GetId(), err)
+		c.JSON(consts.StatusInternalServerError, err)
+		return
+	}
 
-		encrypted, err := util.AESCBCEncrypt(bs)
-		if err != nil {
-			log.Printf("[GetPacketByID] aes error, username=%s, time=%s, id=%d, error=%s\n", req.Username, req.Time, req.GetId(), err)
-			c.JSON(consts.StatusInternalServerError, err)
-			return
-		}
+	keys, err := aeskey.Keyring()
+	if err != nil {
+		log.Printf("[GetPacketByID] load aes keys error, username=%s, time=%s, id=%d, error=%s\n", username, req.Time, req.GetId(), err)
+		c.JSON(consts.StatusInternalServerError, err)
+		return
+	}
 
-		c.JSON(consts.StatusOK, packet.GetPacketByIDResp{
-			Code:        0,
-			Msg:         "获取云数据包成功",
-			UserPackets: encrypted,
-		})
+	// The body is encrypted with a random IV, so the tag comes from the
+	// plaintext and the key it is encrypted with: after a rotation clients
+	// must fetch the packet again before the old key is retired.
+	modified, err := readwriter.Modified(ctx, p.Id, readwriter.LFS)
+	if err != nil {
+		log.Printf("[GetPacketByID] read modification time error, username=%s, id=%d, error=%s\n", username, req.GetId(), err)
+	}
+	if notModified(c, util.HashETag([]byte(mode), []byte(keys.Active()), bs), modified) {
 		return
+	}
 
+	encrypted, err := keys.EncryptMode(mode, bs)
+	if err != nil {
+		log.Printf("[GetPacketByID] aes error, username=%s, time=%s, id=%d, error=%s\n", username, req.Time, req.GetId(), err)
+		c.JSON(consts.StatusInternalServerError, err)
+		return
 	}
 
-	log.Printf("[GetPacketByID] packet not found, username=%s, time=%s, id=%d\n", req.Username, req.Time, req.GetId())
+	c.JSON(consts.StatusOK, packet.GetPacketByIDResp{
+		Code:        0,
+		Msg:         "获取云数据包成功",
+		UserPackets: encrypted,
+	})
+}
+
+// CipherHeader negotiates the encryption of GetPacketByID when the cipher
+// query parameter is not set.
+const CipherHeader = "X-Packet-Cipher"
 
-	c.JSON(consts.StatusOK, nil)
+// cipherMode picks the cipher from the query parameter, then the header, and
+// defaults to CBC for clients that predate GCM.
+func cipherMode(c *app.RequestContext, query string) (string, bool) {
+	mode := query
+	if mode == "" {
+		mode = string(c.GetHeader(CipherHeader))
+	}
+	switch mode {
+	case "", util.CipherCBC:
+		return util.CipherCBC, true
+	case util.CipherGCM:
+		return util.CipherGCM, true
+	}
+	return "", false
 }
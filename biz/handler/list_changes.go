@@ -0,0 +1,49 @@
+// Code generated by hertztool.
+
+package handler
+
+import (
+	"context"
+	"log"
+	"packet_cloud/service/readwriter"
+
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	packet "packet_cloud/biz/model/hertz/packet"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// ListChanges returns the packets inserted, updated or deleted after the
+// revision the client synced last, so it does not have to list everything.
+// @router /v1/packet/changes [GET]
+func ListChanges(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req packet.ListChangesReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Since < 0 {
+		c.String(consts.StatusBadRequest, "invalid params")
+		return
+	}
+	username := clientName(c, req.Username)
+
+	changes, err := readwriter.Changes(ctx, req.Since, readwriter.LFS)
+	if err != nil {
+		log.Printf("[ListChanges] username=%s, since=%d, error=%s\n", username, req.Since, err)
+		c.JSON(consts.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(consts.StatusOK, &packet.ListChangesResp{
+		Code:         0,
+		Msg:          "获取变更成功",
+		Revision:     changes.Revision,
+		Reset_:       changes.Reset,
+		CloudPackets: changes.Packets,
+		Deleted:      changes.Deleted,
+	})
+}
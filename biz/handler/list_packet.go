@@ -4,8 +4,11 @@ package handler
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"packet_cloud/service/readwriter"
+	"packet_cloud/util"
+	"strconv"
 
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
 
@@ -24,22 +27,87 @@ func ListPacket(ctx context.Context, c *app.RequestContext) {
 		c.String(consts.StatusBadRequest, err.Error())
 		return
 	}
+	username := clientName(c, req.Username)
 
-	packets, err := readwriter.ReadPacket(readwriter.LFS)
+	filter, ok := listFilter(&req)
+	if !ok {
+		c.String(consts.StatusBadRequest, "invalid params")
+		return
+	}
+
+	// Read the revision before listing: a write in between leaves the tag older
+	// than the body, which costs a full response later but never a wrong 304.
+	rev, err := readwriter.Revision(ctx, readwriter.LFS)
 	if err != nil {
-		log.Printf("[ListPacket] username=%s, time=%s, error=%s\n", req.Username, req.Time, err)
+		log.Printf("[ListPacket] username=%s, time=%s, error=%s\n", username, req.Time, err)
 		c.JSON(consts.StatusInternalServerError, err)
 		return
 	}
+	modified, err := readwriter.Modified(ctx, 0, readwriter.LFS)
+	if err != nil {
+		log.Printf("[ListPacket] read modification time error, username=%s, error=%s\n", username, err)
+	}
+	etag := util.HashETag([]byte(strconv.FormatInt(rev, 10)), []byte(fmt.Sprintf("%#v %d %d", filter, req.Page, req.PageSize)))
+	if notModified(c, etag, modified) {
+		return
+	}
 
-	// 数据太多，行数据改为接口获取
-	for i := range packets {
-		packets[i].UserPackets = make([]*packet.UserPacket, 0)
+	packets, total, err := readwriter.List(ctx, filter, readwriter.LFS)
+	if err != nil {
+		log.Printf("[ListPacket] username=%s, time=%s, error=%s\n", username, req.Time, err)
+		c.JSON(consts.StatusInternalServerError, err)
+		return
 	}
 
 	c.JSON(consts.StatusOK, &packet.ListPacketResp{
 		Code:         0,
 		Msg:          "获取云数据包成功",
 		CloudPackets: packets,
+		Total:        total,
+		Page:         req.Page,
+		PageSize:     req.PageSize,
 	})
 }
+
+// maxListPageSize caps page_size so a single request cannot ask for an unbounded page.
+const maxListPageSize = 500
+
+// listFilter converts the list request into a storage filter. Row contents are
+// fetched through GetPacketByID, so the listing only carries metadata.
+func listFilter(req *packet.ListPacketReq) (readwriter.Filter, bool) {
+	filter := readwriter.Filter{
+		Region:   req.Region,
+		Channel:  req.Channel,
+		Uploader: req.Uploader,
+		Name:     req.Name,
+		TimeFrom: req.TimeFrom,
+		TimeTo:   req.TimeTo,
+		Summary:  true,
+	}
+
+	switch req.Sort {
+	case "", readwriter.SortByID, readwriter.SortByTime:
+		filter.SortBy = req.Sort
+	default:
+		return filter, false
+	}
+	switch req.Order {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return filter, false
+	}
+
+	if req.Page < 0 || req.PageSize < 0 || req.PageSize > maxListPageSize {
+		return filter, false
+	}
+	if req.PageSize > 0 {
+		if req.Page == 0 {
+			req.Page = 1
+		}
+		filter.Offset = int(req.Page-1) * int(req.PageSize)
+		filter.Limit = int(req.PageSize)
+	}
+	return filter, true
+}
@@ -5,6 +5,7 @@ package handler
 import (
 	"context"
 	"log"
+	"packet_cloud/service/push"
 	"packet_cloud/service/readwriter"
 
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
@@ -20,15 +21,25 @@ func MUploadAllChannelsPacket(ctx context.Context, c *app.RequestContext) {
 	var err error
 	var req packet.MUploadAllChannelsPacketReq
 	err = c.BindAndValidate(&req)
-	if err != nil || req.McloudPacket == nil {
+	if err != nil {
 		c.String(consts.StatusBadRequest, err.Error())
 		return
 	}
 
-	packets, err := readwriter.ReadPacket(readwriter.LFS)
+	plain := req.McloudPacket != nil
+	if !plain {
+		req.McloudPacket = &packet.MCloudPacket{}
+	}
+	err = uploadPayload(plain, req.EncryptedMcloudPacket, req.McloudPacket)
 	if err != nil {
-		log.Println("[MUploadAllChannelsPacket] read packets error", err)
-		c.JSON(consts.StatusInternalServerError, nil)
+		log.Printf("[MUploadAllChannelsPacket] rejected payload, client=%s, error=%s\n", clientName(c, ""), err)
+		c.String(consts.StatusBadRequest, "invalid params")
+		return
+	}
+
+	uploader := clientName(c, req.McloudPacket.Uploader)
+	if req.McloudPacket.Name == "" || len(req.McloudPacket.Channel) == 0 || uploader == "" {
+		c.String(consts.StatusBadRequest, "invalid params")
 		return
 	}
 
@@ -44,31 +55,35 @@ func MUploadAllChannelsPacket(ctx context.Context, c *app.RequestContext) {
 		8: "跨8",
 	}
 
+	packets := make([]*packet.CloudPacket, 0, len(req.McloudPacket.Channel))
 	for idx, channel := range req.McloudPacket.Channel {
 		inserted := &packet.CloudPacket{
-			Id:          0,
 			Region:      m[idx],
 			Name:        req.McloudPacket.Name,
 			Channel:     channel,
-			Uploader:    req.McloudPacket.Uploader,
+			Uploader:    uploader,
 			Time:        req.McloudPacket.Time,
 			UserPackets: req.McloudPacket.UserPackets,
 		}
-
-		if len(packets) > 0 {
-			inserted.Id = packets[len(packets)-1].Id + 1
-		} else {
-			inserted.Id = 1
-		}
 		packets = append(packets, inserted)
 	}
 
-	err = readwriter.SavePacket(packets, readwriter.LFS)
-
+	err = readwriter.Insert(ctx, packets, readwriter.LFS)
 	if err != nil {
+		log.Printf("[MUploadAllChannelsPacket] insert packets error, uploader=%s, error=%s\n", uploader, err)
 		c.JSON(consts.StatusInternalServerError, nil)
 		return
 	}
 
-	c.JSON(consts.StatusOK, nil)
+	ids := make([]int32, 0, len(packets))
+	for _, p := range packets {
+		ids = append(ids, p.Id)
+	}
+	push.Publish(push.Upload, ids)
+
+	c.JSON(consts.StatusOK, &packet.MUploadAllChannelsPacketResp{
+		Code: 0,
+		Msg:  "上传成功",
+		Ids:  ids,
+	})
 }
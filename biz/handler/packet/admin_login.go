@@ -0,0 +1,53 @@
+package packet
+
+import (
+	"context"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"log"
+	"net/http"
+	"packet_cloud/biz/mw"
+	cfg "packet_cloud/config"
+	"packet_cloud/service/auth"
+	"time"
+)
+
+// AdminLoginPage .
+// @router /v1/admin/login [GET]
+func AdminLoginPage(ctx context.Context, c *app.RequestContext) {
+	c.HTML(http.StatusOK, "packet/login.html", utils.H{"error": ""})
+}
+
+// AdminLogin checks the submitted credentials and starts an admin session.
+// @router /v1/admin/login [POST]
+func AdminLogin(ctx context.Context, c *app.RequestContext) {
+	username := c.PostForm("username")
+	if !auth.CheckPassword(username, c.PostForm("password")) {
+		log.Printf("[AdminLogin] failed login, username=%s, ip=%s\n", username, c.ClientIP())
+		c.HTML(http.StatusUnauthorized, "packet/login.html", utils.H{"error": "用户名或密码错误"})
+		return
+	}
+
+	s, err := auth.NewSession(username)
+	if err != nil {
+		log.Println("[AdminLogin] create session error", err)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	maxAge := int(time.Until(s.ExpiresAt).Seconds())
+	c.SetCookie(mw.SessionCookie, s.ID, maxAge, "/", "", protocol.CookieSameSiteStrictMode, cfg.Get().Admin.SecureCookie, true)
+	c.Redirect(http.StatusFound, []byte("/v1/packet/edit"))
+}
+
+// AdminLogout ends the current admin session.
+// @router /v1/admin/logout [POST]
+func AdminLogout(ctx context.Context, c *app.RequestContext) {
+	if s, ok := c.Get(mw.SessionKey); ok {
+		auth.DeleteSession(s.(*auth.Session).ID)
+	}
+
+	c.SetCookie(mw.SessionCookie, "", -1, "/", "", protocol.CookieSameSiteStrictMode, cfg.Get().Admin.SecureCookie, true)
+	c.JSON(http.StatusOK, utils.H{"code": 0, "msg": "已退出登录"})
+}
@@ -0,0 +1,107 @@
+package packet
+
+import (
+	"context"
+	"errors"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+	"log"
+	"net/http"
+	"packet_cloud/biz/mw"
+	"packet_cloud/service/auth"
+	"packet_cloud/service/readwriter"
+	"strconv"
+)
+
+// apiKeyView is an API key as shown to admins; the hash never leaves the server.
+type apiKeyView struct {
+	ID        int32  `json:"id"`
+	Name      string `json:"name"`
+	Scopes    string `json:"scopes"`
+	CreatedAt string `json:"created_at"`
+	RevokedAt string `json:"revoked_at"`
+}
+
+func newAPIKeyView(k *readwriter.APIKey) apiKeyView {
+	return apiKeyView{ID: k.ID, Name: k.Name, Scopes: k.Scopes, CreatedAt: k.CreatedAt, RevokedAt: k.RevokedAt}
+}
+
+func listAPIKeyViews(ctx context.Context) ([]apiKeyView, error) {
+	keys, err := readwriter.ListKeys(ctx, readwriter.LFS)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]apiKeyView, len(keys))
+	for i, k := range keys {
+		views[i] = newAPIKeyView(k)
+	}
+	return views, nil
+}
+
+// ListAPIKeys .
+// @router /v1/admin/keys [GET]
+func ListAPIKeys(ctx context.Context, c *app.RequestContext) {
+	views, err := listAPIKeyViews(ctx)
+	if err != nil {
+		log.Println("[ListAPIKeys] list keys error", err)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.H{"code": 0, "msg": "获取密钥成功", "keys": views})
+}
+
+type issueAPIKeyReq struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// IssueAPIKey creates a key and returns its secret. The secret is only shown this once.
+// @router /v1/admin/keys [POST]
+func IssueAPIKey(ctx context.Context, c *app.RequestContext) {
+	var req issueAPIKeyReq
+	if err := c.BindJSON(&req); err != nil || req.Name == "" || len(req.Scopes) == 0 {
+		c.String(http.StatusBadRequest, "invalid params")
+		return
+	}
+	for _, s := range req.Scopes {
+		if !auth.ValidScope(s) {
+			c.String(http.StatusBadRequest, "invalid scope "+s)
+			return
+		}
+	}
+
+	secret, k, err := auth.IssueKey(ctx, req.Name, req.Scopes)
+	if err != nil {
+		log.Println("[IssueAPIKey] issue key error", err)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	log.Printf("[IssueAPIKey] admin=%s issued key id=%d name=%s scopes=%s\n", c.GetString(mw.AdminKey), k.ID, k.Name, k.Scopes)
+	c.JSON(http.StatusOK, utils.H{"code": 0, "msg": "创建密钥成功", "key": newAPIKeyView(k), "secret": secret})
+}
+
+// RevokeAPIKey .
+// @router /v1/admin/keys/:id [DELETE]
+func RevokeAPIKey(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.String(http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	err = readwriter.RevokeKey(ctx, int32(id), readwriter.LFS)
+	if errors.Is(err, readwriter.ErrNotFound) {
+		c.String(http.StatusNotFound, "api key not found")
+		return
+	}
+	if err != nil {
+		log.Println("[RevokeAPIKey] revoke key error", err)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	log.Printf("[RevokeAPIKey] admin=%s revoked key id=%d\n", c.GetString(mw.AdminKey), id)
+	c.JSON(http.StatusOK, utils.H{"code": 0, "msg": "吊销密钥成功"})
+}
@@ -0,0 +1,141 @@
+package packet
+
+import (
+	"context"
+	"errors"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+	"log"
+	"net/http"
+	model "packet_cloud/biz/model/hertz/packet"
+	"packet_cloud/biz/mw"
+	"packet_cloud/service/backup"
+	"packet_cloud/service/push"
+	"strconv"
+)
+
+// backupPacketView is a packet of a backup without its user packets.
+type backupPacketView struct {
+	ID       int32  `json:"id"`
+	Name     string `json:"name"`
+	Region   string `json:"region"`
+	Channel  string `json:"channel"`
+	Uploader string `json:"uploader"`
+	Time     string `json:"time"`
+	Users    int    `json:"users"`
+}
+
+func newBackupPacketView(p *model.CloudPacket) backupPacketView {
+	return backupPacketView{ID: p.Id, Name: p.Name, Region: p.Region, Channel: p.Channel, Uploader: p.Uploader, Time: p.Time, Users: len(p.UserPackets)}
+}
+
+// ListBackups .
+// @router /v1/admin/backups [GET]
+func ListBackups(ctx context.Context, c *app.RequestContext) {
+	files, err := backup.List(backup.Dir())
+	if err != nil {
+		log.Println("[ListBackups] list backups error", err)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	if files == nil {
+		files = []backup.File{}
+	}
+
+	c.JSON(http.StatusOK, utils.H{"code": 0, "msg": "获取备份成功", "backups": files})
+}
+
+// CreateBackup backs up the current data right away.
+// @router /v1/admin/backups [POST]
+func CreateBackup(ctx context.Context, c *app.RequestContext) {
+	f, err := backup.Create(ctx, "manual")
+	if err != nil {
+		log.Println("[CreateBackup] create backup error", err)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	log.Printf("[CreateBackup] admin=%s created %s\n", c.GetString(mw.AdminKey), f.Name)
+	c.JSON(http.StatusOK, utils.H{"code": 0, "msg": "备份成功", "backup": f})
+}
+
+// backupRange reads the optional from and to query parameters.
+func backupRange(c *app.RequestContext) (backup.Range, bool) {
+	var r backup.Range
+	for _, p := range []struct {
+		key string
+		dst *int32
+	}{{"from", &r.From}, {"to", &r.To}} {
+		v := c.Query(p.key)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			return r, false
+		}
+		*p.dst = int32(n)
+	}
+	return r, r.To == 0 || r.From <= r.To
+}
+
+// PreviewBackup lists the packets of a backup in the requested ID range and
+// what restoring them would change.
+// @router /v1/admin/backups/:name/preview [GET]
+func PreviewBackup(ctx context.Context, c *app.RequestContext) {
+	r, ok := backupRange(c)
+	if !ok {
+		c.String(http.StatusBadRequest, "invalid params")
+		return
+	}
+	name := c.Param("name")
+
+	data, diff, err := backup.Preview(ctx, name, r)
+	if errors.Is(err, backup.ErrNotFound) {
+		c.String(http.StatusNotFound, "backup not found")
+		return
+	}
+	if err != nil {
+		log.Println("[PreviewBackup] preview backup error", err)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	packets := make([]backupPacketView, 0, len(data.Packets))
+	for _, p := range data.Packets {
+		if r.Contains(p.Id) {
+			packets = append(packets, newBackupPacketView(p))
+		}
+	}
+	c.JSON(http.StatusOK, utils.H{"code": 0, "msg": "预览备份成功", "packets": packets, "keys": len(data.Keys), "diff": diff})
+}
+
+// RestoreBackup replaces the packets in the requested ID range with those of
+// a backup, after backing up the current data.
+// @router /v1/admin/backups/:name/restore [POST]
+func RestoreBackup(ctx context.Context, c *app.RequestContext) {
+	r, ok := backupRange(c)
+	if !ok {
+		c.String(http.StatusBadRequest, "invalid params")
+		return
+	}
+	name := c.Param("name")
+
+	result, err := backup.Restore(ctx, name, r)
+	if errors.Is(err, backup.ErrNotFound) {
+		c.String(http.StatusNotFound, "backup not found")
+		return
+	}
+	if err != nil {
+		log.Println("[RestoreBackup] restore error", err)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	push.Publish(push.Upload, result.Added)
+	push.Publish(push.Update, result.Changed)
+	push.Publish(push.Delete, result.Removed)
+
+	log.Printf("[RestoreBackup] admin=%s restored %s range=%d-%d added=%d removed=%d changed=%d, undo with %s\n",
+		c.GetString(mw.AdminKey), name, r.From, r.To, len(result.Added), len(result.Removed), len(result.Changed), result.PreRestore)
+	c.JSON(http.StatusOK, utils.H{"code": 0, "msg": "恢复备份成功", "result": result})
+}
@@ -2,27 +2,68 @@ package packet
 
 import (
 	"context"
+	"errors"
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/common/utils"
 	"log"
 	"net/http"
+	"packet_cloud/biz/mw"
+	"packet_cloud/service/auth"
 	"packet_cloud/service/readwriter"
+	"strconv"
 )
 
 // OnlineEdit .
 // @router /edit [GET]
 func OnlineEdit(ctx context.Context, c *app.RequestContext) {
-	packets, err := readwriter.ReadPacket(readwriter.LFS)
+	packets, _, err := readwriter.List(ctx, readwriter.Filter{Summary: true}, readwriter.LFS)
 	if err != nil {
 		log.Println("[OnlineEdit] read file error", err)
+		c.String(http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	for _, packet := range packets {
-		for _, userPacket := range packet.UserPackets {
-			userPacket.Content = "内容暂时不展示"
-		}
+	keys, err := listAPIKeyViews(ctx)
+	if err != nil {
+		log.Println("[OnlineEdit] list api keys error", err)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	deleted, err := listDeletedPacketViews(ctx)
+	if err != nil {
+		log.Println("[OnlineEdit] list recycle bin error", err)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	csrf := ""
+	if s, ok := c.Get(mw.SessionKey); ok {
+		csrf = s.(*auth.Session).CSRF
+	}
+
+	c.HTML(http.StatusOK, "packet/online_edit.html", utils.H{"packets": packets, "keys": keys, "deleted": deleted, "csrf": csrf})
+}
+
+// OnlineEditPacket returns a single packet including its contents for the edit form.
+// @router /v1/packet/edit/:id [GET]
+func OnlineEditPacket(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.String(http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	p, err := readwriter.Get(ctx, int32(id), readwriter.LFS)
+	if errors.Is(err, readwriter.ErrNotFound) {
+		c.String(http.StatusNotFound, "packet not found")
+		return
+	}
+	if err != nil {
+		log.Println("[OnlineEditPacket] read packet error", err)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
 	}
 
-	c.HTML(http.StatusOK, "packet/online_edit.html", utils.H{"packets": packets})
+	c.JSON(http.StatusOK, p)
 }
@@ -0,0 +1,111 @@
+package packet
+
+import (
+	"context"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+	"log"
+	"net/http"
+	"packet_cloud/biz/mw"
+	"packet_cloud/service/push"
+	"packet_cloud/service/readwriter"
+	"packet_cloud/service/recyclebin"
+)
+
+// deletedPacketView is a packet in the recycle bin as shown to admins.
+type deletedPacketView struct {
+	ID        int32  `json:"id"`
+	Name      string `json:"name"`
+	Region    string `json:"region"`
+	Channel   string `json:"channel"`
+	Uploader  string `json:"uploader"`
+	Time      string `json:"time"`
+	Users     int    `json:"users"`
+	DeletedBy string `json:"deleted_by"`
+	DeletedAt string `json:"deleted_at"`
+	// PurgeAt is when the packet is purged automatically; empty without a retention.
+	PurgeAt string `json:"purge_at"`
+}
+
+func newDeletedPacketView(d *readwriter.DeletedPacket) deletedPacketView {
+	p := d.Packet
+	v := deletedPacketView{
+		ID: p.Id, Name: p.Name, Region: p.Region, Channel: p.Channel, Uploader: p.Uploader, Time: p.Time, Users: len(p.UserPackets),
+		DeletedBy: d.DeletedBy, DeletedAt: d.DeletedAt.Format("2006-01-02 15:04:05"),
+	}
+	if retention := recyclebin.Retention(); retention > 0 {
+		v.PurgeAt = d.DeletedAt.Add(retention).Format("2006-01-02 15:04:05")
+	}
+	return v
+}
+
+func listDeletedPacketViews(ctx context.Context) ([]deletedPacketView, error) {
+	deleted, err := readwriter.ListTrash(ctx, readwriter.LFS)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]deletedPacketView, len(deleted))
+	for i, d := range deleted {
+		views[i] = newDeletedPacketView(d)
+	}
+	return views, nil
+}
+
+// ListRecycleBin .
+// @router /v1/admin/recycle-bin [GET]
+func ListRecycleBin(ctx context.Context, c *app.RequestContext) {
+	views, err := listDeletedPacketViews(ctx)
+	if err != nil {
+		log.Println("[ListRecycleBin] list recycle bin error", err)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.H{"code": 0, "msg": "获取回收站成功", "packets": views})
+}
+
+type recycleBinReq struct {
+	IDs []int32 `json:"ids"`
+}
+
+// RestoreFromRecycleBin moves packets back from the recycle bin. Packets
+// whose ID is in use again stay in the bin.
+// @router /v1/admin/recycle-bin/restore [POST]
+func RestoreFromRecycleBin(ctx context.Context, c *app.RequestContext) {
+	var req recycleBinReq
+	if err := c.BindJSON(&req); err != nil || len(req.IDs) == 0 {
+		c.String(http.StatusBadRequest, "invalid params")
+		return
+	}
+
+	restored, err := readwriter.RestoreTrash(ctx, req.IDs, readwriter.LFS)
+	if err != nil {
+		log.Println("[RestoreFromRecycleBin] restore error", err)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	push.Publish(push.Upload, restored)
+
+	log.Printf("[RestoreFromRecycleBin] admin=%s restored %v\n", c.GetString(mw.AdminKey), restored)
+	c.JSON(http.StatusOK, utils.H{"code": 0, "msg": "恢复成功", "restored": restored})
+}
+
+// PurgeRecycleBin removes packets from the recycle bin for good.
+// @router /v1/admin/recycle-bin/purge [POST]
+func PurgeRecycleBin(ctx context.Context, c *app.RequestContext) {
+	var req recycleBinReq
+	if err := c.BindJSON(&req); err != nil || len(req.IDs) == 0 {
+		c.String(http.StatusBadRequest, "invalid params")
+		return
+	}
+
+	purged, err := readwriter.PurgeTrash(ctx, req.IDs, readwriter.LFS)
+	if err != nil {
+		log.Println("[PurgeRecycleBin] purge error", err)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	log.Printf("[PurgeRecycleBin] admin=%s purged %v\n", c.GetString(mw.AdminKey), purged)
+	c.JSON(http.StatusOK, utils.H{"code": 0, "msg": "彻底删除成功", "purged": purged})
+}
@@ -0,0 +1,59 @@
+// Code generated by hertztool.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"packet_cloud/service/push"
+	"packet_cloud/service/readwriter"
+
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	packet "packet_cloud/biz/model/hertz/packet"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// PatchPacket .
+// @router /v1/packet/:id [PATCH]
+func PatchPacket(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req packet.PatchPacketReq
+	err = c.BindAndValidate(&req)
+	if err != nil || req.Id <= 0 ||
+		(req.Name != nil && *req.Name == "") || (req.Region != nil && *req.Region == "") || (req.Channel != nil && *req.Channel == "") {
+		c.String(consts.StatusBadRequest, "invalid params")
+		return
+	}
+
+	fields := readwriter.PatchFields{
+		Name:    req.Name,
+		Region:  req.Region,
+		Channel: req.Channel,
+	}
+	// Repeated fields have no presence in proto3: a user_packets list in the
+	// body replaces the old one even when empty, a missing one keeps it.
+	if req.UserPackets != nil {
+		fields.UserPackets = &req.UserPackets
+	}
+
+	patched, err := readwriter.Patch(ctx, req.Id, fields, readwriter.LFS)
+	if errors.Is(err, readwriter.ErrNotFound) {
+		c.String(consts.StatusNotFound, "packet not found")
+		return
+	}
+	if err != nil {
+		log.Println("[PatchPacket] patch packet error", err)
+		c.JSON(consts.StatusInternalServerError, err)
+		return
+	}
+	push.Publish(push.Update, []int32{patched.Id})
+
+	c.JSON(consts.StatusOK, &packet.PatchPacketResp{
+		Code:        0,
+		Msg:         "修改成功",
+		CloudPacket: patched,
+	})
+}
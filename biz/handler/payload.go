@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"github.com/bytedance/sonic"
+	"github.com/pkg/errors"
+
+	cfg "packet_cloud/config"
+	"packet_cloud/service/aeskey"
+)
+
+// errPlaintextUpload is returned when plaintext uploads are disabled by the config.
+var errPlaintextUpload = errors.New("plaintext uploads are not accepted")
+
+// uploadPayload resolves an upload sent either as plaintext or as an encrypted
+// envelope into v. Exactly one of them must be set; plain reports whether the
+// plaintext one was.
+func uploadPayload(plain bool, envelope string, v interface{}) error {
+	if envelope == "" {
+		if !plain {
+			return errors.New("missing payload")
+		}
+		if cfg.Get().Crypto.RequireEncryptedUploads {
+			return errPlaintextUpload
+		}
+		return nil
+	}
+	if plain {
+		return errors.New("both plaintext and encrypted payload sent")
+	}
+
+	bs, err := aeskey.Decrypt(envelope)
+	if err != nil {
+		return errors.Wrap(err, "decrypt payload")
+	}
+	if err := sonic.Unmarshal(bs, v); err != nil {
+		return errors.Wrap(err, "unmarshal payload")
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/network"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/cloudwego/hertz/pkg/protocol/http1/resp"
+
+	"packet_cloud/service/push"
+)
+
+// streamParams reads the revision to resume from and the subscription.
+// lastEventID is the Last-Event-ID header an EventSource sends when it
+// reconnects; it wins over the since query parameter.
+func streamParams(c *app.RequestContext, lastEventID string) (int64, push.Filter, bool) {
+	v := c.Query("since")
+	if lastEventID != "" {
+		v = lastEventID
+	}
+	var since int64
+	if v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return 0, push.Filter{}, false
+		}
+		since = n
+	}
+	return since, push.ParseFilter(c.Query("region"), c.Query("channel")), true
+}
+
+// PacketEvents streams packet changes as server-sent events. Each event's
+// data is a push.Message and its id the revision, so an EventSource resumes
+// where it stopped when it reconnects.
+// @router /v1/packet/events [GET]
+func PacketEvents(ctx context.Context, c *app.RequestContext) {
+	since, filter, ok := streamParams(c, string(c.GetHeader("Last-Event-ID")))
+	if !ok {
+		c.String(consts.StatusBadRequest, "invalid params")
+		return
+	}
+	username := clientName(c, c.Query("username"))
+
+	c.SetStatusCode(consts.StatusOK)
+	c.SetContentType("text/event-stream; charset=utf-8")
+	c.Response.Header.Set("Cache-Control", "no-cache")
+	c.Response.Header.Set("X-Accel-Buffering", "no")
+	c.Response.HijackWriter(resp.NewChunkedBodyWriter(&c.Response, c.GetWriter()))
+
+	log.Printf("[PacketEvents] username=%s subscribed, since=%d, filter=%+v\n", username, since, filter)
+	err := push.Follow(ctx, since, filter, push.HeartbeatInterval(), func(m *push.Message) error {
+		data, err := sonic.Marshal(m)
+		if err != nil {
+			return err
+		}
+		var b strings.Builder
+		if m.Revision > 0 {
+			fmt.Fprintf(&b, "id: %d\n", m.Revision)
+		}
+		fmt.Fprintf(&b, "data: %s\n\n", data)
+		_, _ = c.WriteString(b.String())
+		return c.Flush()
+	})
+	log.Printf("[PacketEvents] username=%s unsubscribed, error=%v\n", username, err)
+}
+
+// PacketWebSocket streams packet changes over a WebSocket, one push.Message
+// per text frame. Clients resume with the since query parameter.
+// @router /v1/packet/ws [GET]
+func PacketWebSocket(ctx context.Context, c *app.RequestContext) {
+	since, filter, ok := streamParams(c, "")
+	if !ok {
+		c.String(consts.StatusBadRequest, "invalid params")
+		return
+	}
+	key := string(c.GetHeader("Sec-WebSocket-Key"))
+	if !strings.EqualFold(string(c.GetHeader("Upgrade")), "websocket") || key == "" {
+		c.String(consts.StatusBadRequest, "websocket upgrade required")
+		return
+	}
+	if string(c.GetHeader("Sec-WebSocket-Version")) != "13" {
+		c.Response.Header.Set("Sec-WebSocket-Version", "13")
+		c.String(consts.StatusUpgradeRequired, "unsupported websocket version")
+		return
+	}
+	username := clientName(c, c.Query("username"))
+
+	c.SetStatusCode(consts.StatusSwitchingProtocols)
+	c.Response.Header.Set("Upgrade", "websocket")
+	c.Response.Header.Set("Connection", "Upgrade")
+	c.Response.Header.Set("Sec-WebSocket-Accept", push.AcceptKey(key))
+	c.Hijack(func(conn network.Conn) {
+		log.Printf("[PacketWebSocket] username=%s subscribed, since=%d, filter=%+v\n", username, since, filter)
+		push.ServeWebSocket(conn, since, filter, push.HeartbeatInterval())
+		log.Printf("[PacketWebSocket] username=%s unsubscribed\n", username)
+	})
+}
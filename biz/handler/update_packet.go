@@ -0,0 +1,57 @@
+// Code generated by hertztool.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"packet_cloud/service/push"
+	"packet_cloud/service/readwriter"
+
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	packet "packet_cloud/biz/model/hertz/packet"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// UpdatePacket .
+// @router /v1/packet/:id [PUT]
+func UpdatePacket(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req packet.UpdatePacketReq
+	err = c.BindAndValidate(&req)
+	if err != nil || req.Id <= 0 || req.CloudPacket == nil || req.CloudPacket.Name == "" || req.CloudPacket.UserPackets == nil ||
+		req.CloudPacket.Region == "" || req.CloudPacket.Channel == "" || req.CloudPacket.Uploader == "" || req.CloudPacket.Time == "" {
+		c.String(consts.StatusBadRequest, "invalid params")
+		return
+	}
+
+	updated := &packet.CloudPacket{
+		Id:          req.Id,
+		Region:      req.CloudPacket.Region,
+		Name:        req.CloudPacket.Name,
+		Channel:     req.CloudPacket.Channel,
+		Uploader:    req.CloudPacket.Uploader,
+		Time:        req.CloudPacket.Time,
+		UserPackets: req.CloudPacket.UserPackets,
+	}
+
+	err = readwriter.Update(ctx, updated, readwriter.LFS)
+	if errors.Is(err, readwriter.ErrNotFound) {
+		c.String(consts.StatusNotFound, "packet not found")
+		return
+	}
+	if err != nil {
+		log.Println("[UpdatePacket] update packet error", err)
+		c.JSON(consts.StatusInternalServerError, err)
+		return
+	}
+	push.Publish(push.Update, []int32{updated.Id})
+
+	c.JSON(consts.StatusOK, &packet.UpdatePacketResp{
+		Code: 0,
+		Msg:  "修改成功",
+	})
+}
@@ -5,6 +5,7 @@ package handler
 import (
 	"context"
 	"log"
+	"packet_cloud/service/push"
 	"packet_cloud/service/readwriter"
 
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
@@ -20,46 +21,49 @@ func UploadPacket(ctx context.Context, c *app.RequestContext) {
 	var err error
 	var req packet.UploadPacketReq
 	err = c.BindAndValidate(&req)
-	if err != nil || req.CloudPacket == nil || req.CloudPacket.Name == "" || req.CloudPacket.UserPackets == nil ||
-		req.CloudPacket.Region == "" || req.CloudPacket.Channel == "" || req.CloudPacket.Uploader == "" || req.CloudPacket.Time == "" {
+	if err != nil {
 		c.String(consts.StatusBadRequest, "invalid params")
 		return
 	}
 
-	var insertedID int32 = 0
-	packets, err := readwriter.ReadPacket(readwriter.LFS)
+	plain := req.CloudPacket != nil
+	if !plain {
+		req.CloudPacket = &packet.CloudPacket{}
+	}
+	err = uploadPayload(plain, req.EncryptedCloudPacket, req.CloudPacket)
 	if err != nil {
-		log.Println("[UploadPacket] read packets error", err)
-		c.JSON(consts.StatusInternalServerError, err)
+		log.Printf("[UploadPacket] rejected payload, client=%s, error=%s\n", clientName(c, ""), err)
+		c.String(consts.StatusBadRequest, "invalid params")
 		return
 	}
 
-	if len(packets) > 0 {
-		insertedID = packets[len(packets)-1].Id + 1
-	} else {
-		insertedID = 1
+	uploader := clientName(c, req.CloudPacket.Uploader)
+	if req.CloudPacket.Name == "" || req.CloudPacket.UserPackets == nil || req.CloudPacket.Region == "" ||
+		req.CloudPacket.Channel == "" || uploader == "" || req.CloudPacket.Time == "" {
+		c.String(consts.StatusBadRequest, "invalid params")
+		return
 	}
 
 	inserted := &packet.CloudPacket{
-		Id:          insertedID,
 		Region:      req.CloudPacket.Region,
 		Name:        req.CloudPacket.Name,
 		Channel:     req.CloudPacket.Channel,
-		Uploader:    req.CloudPacket.Uploader,
+		Uploader:    uploader,
 		Time:        req.CloudPacket.Time,
 		UserPackets: req.CloudPacket.UserPackets,
 	}
-	packets = append(packets, inserted)
 
-	err = readwriter.SavePacket(packets, readwriter.LFS)
+	err = readwriter.Insert(ctx, []*packet.CloudPacket{inserted}, readwriter.LFS)
 	if err != nil {
-		log.Println("[UploadPacket] save packets error", err)
+		log.Printf("[UploadPacket] insert packet error, uploader=%s, error=%s\n", uploader, err)
 		c.JSON(consts.StatusInternalServerError, err)
 		return
 	}
+	push.Publish(push.Upload, []int32{inserted.Id})
 
 	c.JSON(consts.StatusOK, &packet.UploadPacketResp{
 		Code: 0,
 		Msg:  "上传成功",
+		Id:   inserted.Id,
 	})
 }
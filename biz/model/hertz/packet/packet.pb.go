@@ -201,6 +201,9 @@ type UploadPacketReq struct {
 	unknownFields protoimpl.UnknownFields
 
 	CloudPacket *CloudPacket `protobuf:"bytes,1,opt,name=cloud_packet,json=cloudPacket,proto3" json:"cloud_packet,omitempty" form:"cloud_packet" query:"cloud_packet"`
+	// CloudPacket JSON in the same envelope as GetPacketByIDResp.user_packets,
+	// sent instead of cloud_packet.
+	EncryptedCloudPacket string `protobuf:"bytes,2,opt,name=encrypted_cloud_packet,json=encryptedCloudPacket,proto3" json:"encrypted_cloud_packet,omitempty" form:"encrypted_cloud_packet" query:"encrypted_cloud_packet"`
 }
 
 func (x *UploadPacketReq) Reset() {
@@ -242,6 +245,13 @@ func (x *UploadPacketReq) GetCloudPacket() *CloudPacket {
 	return nil
 }
 
+func (x *UploadPacketReq) GetEncryptedCloudPacket() string {
+	if x != nil {
+		return x.EncryptedCloudPacket
+	}
+	return ""
+}
+
 type UploadPacketResp struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -249,6 +259,7 @@ type UploadPacketResp struct {
 
 	Code int32  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty" form:"code" query:"code"`
 	Msg  string `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty" form:"msg" query:"msg"`
+	Id   int32  `protobuf:"varint,3,opt,name=id,proto3" json:"id,omitempty" form:"id" query:"id"`
 }
 
 func (x *UploadPacketResp) Reset() {
@@ -297,6 +308,13 @@ func (x *UploadPacketResp) GetMsg() string {
 	return ""
 }
 
+func (x *UploadPacketResp) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
 type ListPacketReq struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -304,6 +322,18 @@ type ListPacketReq struct {
 
 	Time     string `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty" form:"time" query:"time"`
 	Username string `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty" form:"username" query:"username"`
+	// filters, empty values match everything
+	Region   string `protobuf:"bytes,3,opt,name=region,proto3" json:"region,omitempty" form:"region" query:"region"`
+	Channel  string `protobuf:"bytes,4,opt,name=channel,proto3" json:"channel,omitempty" form:"channel" query:"channel"`
+	Uploader string `protobuf:"bytes,5,opt,name=uploader,proto3" json:"uploader,omitempty" form:"uploader" query:"uploader"`
+	Name     string `protobuf:"bytes,6,opt,name=name,proto3" json:"name,omitempty" form:"name" query:"name"`                                   // substring match
+	TimeFrom string `protobuf:"bytes,7,opt,name=time_from,json=timeFrom,proto3" json:"time_from,omitempty" form:"time_from" query:"time_from"` // inclusive, compared with the packet time as a string
+	TimeTo   string `protobuf:"bytes,8,opt,name=time_to,json=timeTo,proto3" json:"time_to,omitempty" form:"time_to" query:"time_to"`           // inclusive
+	// page starts from 1, page_size 0 returns every matching packet
+	Page     int32  `protobuf:"varint,9,opt,name=page,proto3" json:"page,omitempty" form:"page" query:"page"`
+	PageSize int32  `protobuf:"varint,10,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty" form:"page_size" query:"page_size"`
+	Sort     string `protobuf:"bytes,11,opt,name=sort,proto3" json:"sort,omitempty" form:"sort" query:"sort"`     // "id" (default) or "time"
+	Order    string `protobuf:"bytes,12,opt,name=order,proto3" json:"order,omitempty" form:"order" query:"order"` // "asc" (default) or "desc"
 }
 
 func (x *ListPacketReq) Reset() {
@@ -315,14 +345,568 @@ func (x *ListPacketReq) Reset() {
 	}
 }
 
-func (x *ListPacketReq) String() string {
+func (x *ListPacketReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPacketReq) ProtoMessage() {}
+
+func (x *ListPacketReq) ProtoReflect() protoreflect.Message {
+	mi := &file_packet_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPacketReq.ProtoReflect.Descriptor instead.
+func (*ListPacketReq) Descriptor() ([]byte, []int) {
+	return file_packet_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListPacketReq) GetTime() string {
+	if x != nil {
+		return x.Time
+	}
+	return ""
+}
+
+func (x *ListPacketReq) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *ListPacketReq) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *ListPacketReq) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+func (x *ListPacketReq) GetUploader() string {
+	if x != nil {
+		return x.Uploader
+	}
+	return ""
+}
+
+func (x *ListPacketReq) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ListPacketReq) GetTimeFrom() string {
+	if x != nil {
+		return x.TimeFrom
+	}
+	return ""
+}
+
+func (x *ListPacketReq) GetTimeTo() string {
+	if x != nil {
+		return x.TimeTo
+	}
+	return ""
+}
+
+func (x *ListPacketReq) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *ListPacketReq) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListPacketReq) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+func (x *ListPacketReq) GetOrder() string {
+	if x != nil {
+		return x.Order
+	}
+	return ""
+}
+
+type ListPacketResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code         int32          `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty" form:"code" query:"code"`
+	Msg          string         `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty" form:"msg" query:"msg"`
+	CloudPackets []*CloudPacket `protobuf:"bytes,3,rep,name=cloud_packets,json=cloudPackets,proto3" json:"cloud_packets,omitempty" form:"cloud_packets" query:"cloud_packets"`
+	Total        int64          `protobuf:"varint,4,opt,name=total,proto3" json:"total,omitempty" form:"total" query:"total"`
+	Page         int32          `protobuf:"varint,5,opt,name=page,proto3" json:"page,omitempty" form:"page" query:"page"`
+	PageSize     int32          `protobuf:"varint,6,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty" form:"page_size" query:"page_size"`
+}
+
+func (x *ListPacketResp) Reset() {
+	*x = ListPacketResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_packet_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListPacketResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPacketResp) ProtoMessage() {}
+
+func (x *ListPacketResp) ProtoReflect() protoreflect.Message {
+	mi := &file_packet_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPacketResp.ProtoReflect.Descriptor instead.
+func (*ListPacketResp) Descriptor() ([]byte, []int) {
+	return file_packet_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListPacketResp) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *ListPacketResp) GetMsg() string {
+	if x != nil {
+		return x.Msg
+	}
+	return ""
+}
+
+func (x *ListPacketResp) GetCloudPackets() []*CloudPacket {
+	if x != nil {
+		return x.CloudPackets
+	}
+	return nil
+}
+
+func (x *ListPacketResp) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *ListPacketResp) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *ListPacketResp) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+type GetPacketByIDReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Time     string `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty" form:"time" query:"time"`
+	Username string `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty" form:"username" query:"username"`
+	Id       int32  `protobuf:"varint,3,opt,name=id,proto3" json:"id,omitempty" form:"id" query:"id"`
+	// cbc (default) or gcm; can also be sent as the X-Packet-Cipher header.
+	Cipher string `protobuf:"bytes,4,opt,name=cipher,proto3" json:"cipher,omitempty" query:"cipher"`
+}
+
+func (x *GetPacketByIDReq) Reset() {
+	*x = GetPacketByIDReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_packet_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetPacketByIDReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPacketByIDReq) ProtoMessage() {}
+
+func (x *GetPacketByIDReq) ProtoReflect() protoreflect.Message {
+	mi := &file_packet_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPacketByIDReq.ProtoReflect.Descriptor instead.
+func (*GetPacketByIDReq) Descriptor() ([]byte, []int) {
+	return file_packet_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetPacketByIDReq) GetTime() string {
+	if x != nil {
+		return x.Time
+	}
+	return ""
+}
+
+func (x *GetPacketByIDReq) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *GetPacketByIDReq) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *GetPacketByIDReq) GetCipher() string {
+	if x != nil {
+		return x.Cipher
+	}
+	return ""
+}
+
+type GetPacketByIDResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code int32  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty" form:"code" query:"code"`
+	Msg  string `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty" form:"msg" query:"msg"`
+	//repeated UserPacket user_packets = 3;
+	UserPackets string `protobuf:"bytes,3,opt,name=user_packets,json=userPackets,proto3" json:"user_packets,omitempty" form:"user_packets" query:"user_packets"`
+}
+
+func (x *GetPacketByIDResp) Reset() {
+	*x = GetPacketByIDResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_packet_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetPacketByIDResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPacketByIDResp) ProtoMessage() {}
+
+func (x *GetPacketByIDResp) ProtoReflect() protoreflect.Message {
+	mi := &file_packet_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPacketByIDResp.ProtoReflect.Descriptor instead.
+func (*GetPacketByIDResp) Descriptor() ([]byte, []int) {
+	return file_packet_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetPacketByIDResp) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *GetPacketByIDResp) GetMsg() string {
+	if x != nil {
+		return x.Msg
+	}
+	return ""
+}
+
+func (x *GetPacketByIDResp) GetUserPackets() string {
+	if x != nil {
+		return x.UserPackets
+	}
+	return ""
+}
+
+// Selects the packets matching every selector that is set; at least one is
+// required.
+type DeletePacketReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	From       int32   `protobuf:"varint,1,opt,name=from,proto3" json:"from,omitempty" form:"from" query:"from"` // inclusive ID range; to 0 means no upper bound only with open_ended
+	To         int32   `protobuf:"varint,2,opt,name=to,proto3" json:"to,omitempty" form:"to" query:"to"`
+	Ids        []int32 `protobuf:"varint,3,rep,packed,name=ids,proto3" json:"ids,omitempty" form:"ids" query:"ids"`
+	Uploader   string  `protobuf:"bytes,4,opt,name=uploader,proto3" json:"uploader,omitempty" form:"uploader" query:"uploader"`
+	Region     string  `protobuf:"bytes,5,opt,name=region,proto3" json:"region,omitempty" form:"region" query:"region"`
+	Channel    string  `protobuf:"bytes,6,opt,name=channel,proto3" json:"channel,omitempty" form:"channel" query:"channel"`
+	Name       string  `protobuf:"bytes,7,opt,name=name,proto3" json:"name,omitempty" form:"name" query:"name"`                                             // pattern for the whole name, * matches any run of characters and ? one
+	TimeBefore string  `protobuf:"bytes,8,opt,name=time_before,json=timeBefore,proto3" json:"time_before,omitempty" form:"time_before" query:"time_before"` // exclusive, compared with the packet time as a string
+	// only report what would be deleted
+	DryRun bool `protobuf:"varint,9,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty" form:"dry_run" query:"dry_run"`
+	// token from a dry run of the same selection, required when it selects
+	// more packets than the server allows without confirmation
+	Confirm string `protobuf:"bytes,10,opt,name=confirm,proto3" json:"confirm,omitempty" form:"confirm" query:"confirm"`
+	// confirms that from without to selects every ID from it up, so a client
+	// that leaves to out by mistake does not delete them all
+	OpenEnded bool `protobuf:"varint,11,opt,name=open_ended,json=openEnded,proto3" json:"open_ended,omitempty" form:"open_ended" query:"open_ended"`
+}
+
+func (x *DeletePacketReq) Reset() {
+	*x = DeletePacketReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_packet_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeletePacketReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePacketReq) ProtoMessage() {}
+
+func (x *DeletePacketReq) ProtoReflect() protoreflect.Message {
+	mi := &file_packet_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePacketReq.ProtoReflect.Descriptor instead.
+func (*DeletePacketReq) Descriptor() ([]byte, []int) {
+	return file_packet_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DeletePacketReq) GetFrom() int32 {
+	if x != nil {
+		return x.From
+	}
+	return 0
+}
+
+func (x *DeletePacketReq) GetTo() int32 {
+	if x != nil {
+		return x.To
+	}
+	return 0
+}
+
+func (x *DeletePacketReq) GetIds() []int32 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *DeletePacketReq) GetUploader() string {
+	if x != nil {
+		return x.Uploader
+	}
+	return ""
+}
+
+func (x *DeletePacketReq) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *DeletePacketReq) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+func (x *DeletePacketReq) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DeletePacketReq) GetTimeBefore() string {
+	if x != nil {
+		return x.TimeBefore
+	}
+	return ""
+}
+
+func (x *DeletePacketReq) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *DeletePacketReq) GetConfirm() string {
+	if x != nil {
+		return x.Confirm
+	}
+	return ""
+}
+
+func (x *DeletePacketReq) GetOpenEnded() bool {
+	if x != nil {
+		return x.OpenEnded
+	}
+	return false
+}
+
+type DeletePacketResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code int32   `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty" form:"code" query:"code"`
+	Msg  string  `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty" form:"msg" query:"msg"`
+	Ids  []int32 `protobuf:"varint,3,rep,packed,name=ids,proto3" json:"ids,omitempty" form:"ids" query:"ids"` // deleted, or selected on a dry run
+	// set on a dry run that needs confirmation, send it back as confirm
+	Confirm string `protobuf:"bytes,4,opt,name=confirm,proto3" json:"confirm,omitempty" form:"confirm" query:"confirm"`
+}
+
+func (x *DeletePacketResp) Reset() {
+	*x = DeletePacketResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_packet_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeletePacketResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePacketResp) ProtoMessage() {}
+
+func (x *DeletePacketResp) ProtoReflect() protoreflect.Message {
+	mi := &file_packet_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePacketResp.ProtoReflect.Descriptor instead.
+func (*DeletePacketResp) Descriptor() ([]byte, []int) {
+	return file_packet_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DeletePacketResp) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *DeletePacketResp) GetMsg() string {
+	if x != nil {
+		return x.Msg
+	}
+	return ""
+}
+
+func (x *DeletePacketResp) GetIds() []int32 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *DeletePacketResp) GetConfirm() string {
+	if x != nil {
+		return x.Confirm
+	}
+	return ""
+}
+
+type UpdatePacketReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id          int32        `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty" path:"id"`
+	CloudPacket *CloudPacket `protobuf:"bytes,2,opt,name=cloud_packet,json=cloudPacket,proto3" json:"cloud_packet,omitempty" form:"cloud_packet" query:"cloud_packet"`
+}
+
+func (x *UpdatePacketReq) Reset() {
+	*x = UpdatePacketReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_packet_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UpdatePacketReq) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListPacketReq) ProtoMessage() {}
+func (*UpdatePacketReq) ProtoMessage() {}
 
-func (x *ListPacketReq) ProtoReflect() protoreflect.Message {
-	mi := &file_packet_proto_msgTypes[4]
+func (x *UpdatePacketReq) ProtoReflect() protoreflect.Message {
+	mi := &file_packet_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -333,52 +917,51 @@ func (x *ListPacketReq) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListPacketReq.ProtoReflect.Descriptor instead.
-func (*ListPacketReq) Descriptor() ([]byte, []int) {
-	return file_packet_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use UpdatePacketReq.ProtoReflect.Descriptor instead.
+func (*UpdatePacketReq) Descriptor() ([]byte, []int) {
+	return file_packet_proto_rawDescGZIP(), []int{10}
 }
 
-func (x *ListPacketReq) GetTime() string {
+func (x *UpdatePacketReq) GetId() int32 {
 	if x != nil {
-		return x.Time
+		return x.Id
 	}
-	return ""
+	return 0
 }
 
-func (x *ListPacketReq) GetUsername() string {
+func (x *UpdatePacketReq) GetCloudPacket() *CloudPacket {
 	if x != nil {
-		return x.Username
+		return x.CloudPacket
 	}
-	return ""
+	return nil
 }
 
-type ListPacketResp struct {
+type UpdatePacketResp struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Code         int32          `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty" form:"code" query:"code"`
-	Msg          string         `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty" form:"msg" query:"msg"`
-	CloudPackets []*CloudPacket `protobuf:"bytes,3,rep,name=cloud_packets,json=cloudPackets,proto3" json:"cloud_packets,omitempty" form:"cloud_packets" query:"cloud_packets"`
+	Code int32  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty" form:"code" query:"code"`
+	Msg  string `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty" form:"msg" query:"msg"`
 }
 
-func (x *ListPacketResp) Reset() {
-	*x = ListPacketResp{}
+func (x *UpdatePacketResp) Reset() {
+	*x = UpdatePacketResp{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_packet_proto_msgTypes[5]
+		mi := &file_packet_proto_msgTypes[11]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *ListPacketResp) String() string {
+func (x *UpdatePacketResp) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListPacketResp) ProtoMessage() {}
+func (*UpdatePacketResp) ProtoMessage() {}
 
-func (x *ListPacketResp) ProtoReflect() protoreflect.Message {
-	mi := &file_packet_proto_msgTypes[5]
+func (x *UpdatePacketResp) ProtoReflect() protoreflect.Message {
+	mi := &file_packet_proto_msgTypes[11]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -389,59 +972,56 @@ func (x *ListPacketResp) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListPacketResp.ProtoReflect.Descriptor instead.
-func (*ListPacketResp) Descriptor() ([]byte, []int) {
-	return file_packet_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use UpdatePacketResp.ProtoReflect.Descriptor instead.
+func (*UpdatePacketResp) Descriptor() ([]byte, []int) {
+	return file_packet_proto_rawDescGZIP(), []int{11}
 }
 
-func (x *ListPacketResp) GetCode() int32 {
+func (x *UpdatePacketResp) GetCode() int32 {
 	if x != nil {
 		return x.Code
 	}
 	return 0
 }
 
-func (x *ListPacketResp) GetMsg() string {
+func (x *UpdatePacketResp) GetMsg() string {
 	if x != nil {
 		return x.Msg
 	}
 	return ""
 }
 
-func (x *ListPacketResp) GetCloudPackets() []*CloudPacket {
-	if x != nil {
-		return x.CloudPackets
-	}
-	return nil
-}
-
-type GetPacketByIDReq struct {
+// Only the fields present in the request are changed; a non-empty
+// user_packets replaces the whole list.
+type PatchPacketReq struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Time     string `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty" form:"time" query:"time"`
-	Username string `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty" form:"username" query:"username"`
-	Id       int32  `protobuf:"varint,3,opt,name=id,proto3" json:"id,omitempty" form:"id" query:"id"`
+	Id          int32         `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty" path:"id"`
+	Name        *string       `protobuf:"bytes,2,opt,name=name,proto3,oneof" json:"name,omitempty" form:"name" query:"name"`
+	Region      *string       `protobuf:"bytes,3,opt,name=region,proto3,oneof" json:"region,omitempty" form:"region" query:"region"`
+	Channel     *string       `protobuf:"bytes,4,opt,name=channel,proto3,oneof" json:"channel,omitempty" form:"channel" query:"channel"`
+	UserPackets []*UserPacket `protobuf:"bytes,5,rep,name=user_packets,json=userPackets,proto3" json:"user_packets,omitempty" form:"user_packets" query:"user_packets"`
 }
 
-func (x *GetPacketByIDReq) Reset() {
-	*x = GetPacketByIDReq{}
+func (x *PatchPacketReq) Reset() {
+	*x = PatchPacketReq{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_packet_proto_msgTypes[6]
+		mi := &file_packet_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *GetPacketByIDReq) String() string {
+func (x *PatchPacketReq) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetPacketByIDReq) ProtoMessage() {}
+func (*PatchPacketReq) ProtoMessage() {}
 
-func (x *GetPacketByIDReq) ProtoReflect() protoreflect.Message {
-	mi := &file_packet_proto_msgTypes[6]
+func (x *PatchPacketReq) ProtoReflect() protoreflect.Message {
+	mi := &file_packet_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -452,60 +1032,73 @@ func (x *GetPacketByIDReq) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetPacketByIDReq.ProtoReflect.Descriptor instead.
-func (*GetPacketByIDReq) Descriptor() ([]byte, []int) {
-	return file_packet_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use PatchPacketReq.ProtoReflect.Descriptor instead.
+func (*PatchPacketReq) Descriptor() ([]byte, []int) {
+	return file_packet_proto_rawDescGZIP(), []int{12}
 }
 
-func (x *GetPacketByIDReq) GetTime() string {
+func (x *PatchPacketReq) GetId() int32 {
 	if x != nil {
-		return x.Time
+		return x.Id
+	}
+	return 0
+}
+
+func (x *PatchPacketReq) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
 	}
 	return ""
 }
 
-func (x *GetPacketByIDReq) GetUsername() string {
-	if x != nil {
-		return x.Username
+func (x *PatchPacketReq) GetRegion() string {
+	if x != nil && x.Region != nil {
+		return *x.Region
 	}
 	return ""
 }
 
-func (x *GetPacketByIDReq) GetId() int32 {
+func (x *PatchPacketReq) GetChannel() string {
+	if x != nil && x.Channel != nil {
+		return *x.Channel
+	}
+	return ""
+}
+
+func (x *PatchPacketReq) GetUserPackets() []*UserPacket {
 	if x != nil {
-		return x.Id
+		return x.UserPackets
 	}
-	return 0
+	return nil
 }
 
-type GetPacketByIDResp struct {
+type PatchPacketResp struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Code int32  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty" form:"code" query:"code"`
-	Msg  string `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty" form:"msg" query:"msg"`
-	//repeated UserPacket user_packets = 3;
-	UserPackets string `protobuf:"bytes,3,opt,name=user_packets,json=userPackets,proto3" json:"user_packets,omitempty" form:"user_packets" query:"user_packets"`
+	Code        int32        `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty" form:"code" query:"code"`
+	Msg         string       `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty" form:"msg" query:"msg"`
+	CloudPacket *CloudPacket `protobuf:"bytes,3,opt,name=cloud_packet,json=cloudPacket,proto3" json:"cloud_packet,omitempty" form:"cloud_packet" query:"cloud_packet"`
 }
 
-func (x *GetPacketByIDResp) Reset() {
-	*x = GetPacketByIDResp{}
+func (x *PatchPacketResp) Reset() {
+	*x = PatchPacketResp{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_packet_proto_msgTypes[7]
+		mi := &file_packet_proto_msgTypes[13]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *GetPacketByIDResp) String() string {
+func (x *PatchPacketResp) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetPacketByIDResp) ProtoMessage() {}
+func (*PatchPacketResp) ProtoMessage() {}
 
-func (x *GetPacketByIDResp) ProtoReflect() protoreflect.Message {
-	mi := &file_packet_proto_msgTypes[7]
+func (x *PatchPacketResp) ProtoReflect() protoreflect.Message {
+	mi := &file_packet_proto_msgTypes[13]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -516,58 +1109,60 @@ func (x *GetPacketByIDResp) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetPacketByIDResp.ProtoReflect.Descriptor instead.
-func (*GetPacketByIDResp) Descriptor() ([]byte, []int) {
-	return file_packet_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use PatchPacketResp.ProtoReflect.Descriptor instead.
+func (*PatchPacketResp) Descriptor() ([]byte, []int) {
+	return file_packet_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *GetPacketByIDResp) GetCode() int32 {
+func (x *PatchPacketResp) GetCode() int32 {
 	if x != nil {
 		return x.Code
 	}
 	return 0
 }
 
-func (x *GetPacketByIDResp) GetMsg() string {
+func (x *PatchPacketResp) GetMsg() string {
 	if x != nil {
 		return x.Msg
 	}
 	return ""
 }
 
-func (x *GetPacketByIDResp) GetUserPackets() string {
+func (x *PatchPacketResp) GetCloudPacket() *CloudPacket {
 	if x != nil {
-		return x.UserPackets
+		return x.CloudPacket
 	}
-	return ""
+	return nil
 }
 
-type DeletePacketReq struct {
+type ListChangesReq struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	From int32 `protobuf:"varint,1,opt,name=from,proto3" json:"from,omitempty" form:"from" query:"from"`
-	To   int32 `protobuf:"varint,2,opt,name=to,proto3" json:"to,omitempty" form:"to" query:"to"`
+	Time     string `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty" form:"time" query:"time"`
+	Username string `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty" form:"username" query:"username"`
+	// revision returned by the previous call, 0 for everything
+	Since int64 `protobuf:"varint,3,opt,name=since,proto3" json:"since,omitempty" query:"since"`
 }
 
-func (x *DeletePacketReq) Reset() {
-	*x = DeletePacketReq{}
+func (x *ListChangesReq) Reset() {
+	*x = ListChangesReq{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_packet_proto_msgTypes[8]
+		mi := &file_packet_proto_msgTypes[14]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *DeletePacketReq) String() string {
+func (x *ListChangesReq) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeletePacketReq) ProtoMessage() {}
+func (*ListChangesReq) ProtoMessage() {}
 
-func (x *DeletePacketReq) ProtoReflect() protoreflect.Message {
-	mi := &file_packet_proto_msgTypes[8]
+func (x *ListChangesReq) ProtoReflect() protoreflect.Message {
+	mi := &file_packet_proto_msgTypes[14]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -578,51 +1173,65 @@ func (x *DeletePacketReq) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeletePacketReq.ProtoReflect.Descriptor instead.
-func (*DeletePacketReq) Descriptor() ([]byte, []int) {
-	return file_packet_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use ListChangesReq.ProtoReflect.Descriptor instead.
+func (*ListChangesReq) Descriptor() ([]byte, []int) {
+	return file_packet_proto_rawDescGZIP(), []int{14}
 }
 
-func (x *DeletePacketReq) GetFrom() int32 {
+func (x *ListChangesReq) GetTime() string {
 	if x != nil {
-		return x.From
+		return x.Time
 	}
-	return 0
+	return ""
 }
 
-func (x *DeletePacketReq) GetTo() int32 {
+func (x *ListChangesReq) GetUsername() string {
 	if x != nil {
-		return x.To
+		return x.Username
+	}
+	return ""
+}
+
+func (x *ListChangesReq) GetSince() int64 {
+	if x != nil {
+		return x.Since
 	}
 	return 0
 }
 
-type DeletePacketResp struct {
+// cloud_packets carry metadata only, fetch user packets with GetPacketByID.
+type ListChangesResp struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Code int32  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty" form:"code" query:"code"`
-	Msg  string `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty" form:"msg" query:"msg"`
+	Code     int32  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty" form:"code" query:"code"`
+	Msg      string `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty" form:"msg" query:"msg"`
+	Revision int64  `protobuf:"varint,3,opt,name=revision,proto3" json:"revision,omitempty" form:"revision" query:"revision"` // pass as since next time
+	// set when since was 0 or unknown to the server: cloud_packets then holds
+	// every packet and the client should drop its copy
+	Reset_       bool           `protobuf:"varint,4,opt,name=reset,proto3" json:"reset,omitempty" form:"reset" query:"reset"`
+	CloudPackets []*CloudPacket `protobuf:"bytes,5,rep,name=cloud_packets,json=cloudPackets,proto3" json:"cloud_packets,omitempty" form:"cloud_packets" query:"cloud_packets"` // inserted or updated
+	Deleted      []int32        `protobuf:"varint,6,rep,packed,name=deleted,proto3" json:"deleted,omitempty" form:"deleted" query:"deleted"`                                   // IDs of deleted packets
 }
 
-func (x *DeletePacketResp) Reset() {
-	*x = DeletePacketResp{}
+func (x *ListChangesResp) Reset() {
+	*x = ListChangesResp{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_packet_proto_msgTypes[9]
+		mi := &file_packet_proto_msgTypes[15]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *DeletePacketResp) String() string {
+func (x *ListChangesResp) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeletePacketResp) ProtoMessage() {}
+func (*ListChangesResp) ProtoMessage() {}
 
-func (x *DeletePacketResp) ProtoReflect() protoreflect.Message {
-	mi := &file_packet_proto_msgTypes[9]
+func (x *ListChangesResp) ProtoReflect() protoreflect.Message {
+	mi := &file_packet_proto_msgTypes[15]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -633,25 +1242,53 @@ func (x *DeletePacketResp) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeletePacketResp.ProtoReflect.Descriptor instead.
-func (*DeletePacketResp) Descriptor() ([]byte, []int) {
-	return file_packet_proto_rawDescGZIP(), []int{9}
+// Deprecated: Use ListChangesResp.ProtoReflect.Descriptor instead.
+func (*ListChangesResp) Descriptor() ([]byte, []int) {
+	return file_packet_proto_rawDescGZIP(), []int{15}
 }
 
-func (x *DeletePacketResp) GetCode() int32 {
+func (x *ListChangesResp) GetCode() int32 {
 	if x != nil {
 		return x.Code
 	}
 	return 0
 }
 
-func (x *DeletePacketResp) GetMsg() string {
+func (x *ListChangesResp) GetMsg() string {
 	if x != nil {
 		return x.Msg
 	}
 	return ""
 }
 
+func (x *ListChangesResp) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *ListChangesResp) GetReset_() bool {
+	if x != nil {
+		return x.Reset_
+	}
+	return false
+}
+
+func (x *ListChangesResp) GetCloudPackets() []*CloudPacket {
+	if x != nil {
+		return x.CloudPackets
+	}
+	return nil
+}
+
+func (x *ListChangesResp) GetDeleted() []int32 {
+	if x != nil {
+		return x.Deleted
+	}
+	return nil
+}
+
 type MCloudPacket struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -669,7 +1306,7 @@ type MCloudPacket struct {
 func (x *MCloudPacket) Reset() {
 	*x = MCloudPacket{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_packet_proto_msgTypes[10]
+		mi := &file_packet_proto_msgTypes[16]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -682,7 +1319,7 @@ func (x *MCloudPacket) String() string {
 func (*MCloudPacket) ProtoMessage() {}
 
 func (x *MCloudPacket) ProtoReflect() protoreflect.Message {
-	mi := &file_packet_proto_msgTypes[10]
+	mi := &file_packet_proto_msgTypes[16]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -695,7 +1332,7 @@ func (x *MCloudPacket) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MCloudPacket.ProtoReflect.Descriptor instead.
 func (*MCloudPacket) Descriptor() ([]byte, []int) {
-	return file_packet_proto_rawDescGZIP(), []int{10}
+	return file_packet_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *MCloudPacket) GetId() int32 {
@@ -753,12 +1390,15 @@ type MUploadAllChannelsPacketReq struct {
 	unknownFields protoimpl.UnknownFields
 
 	McloudPacket *MCloudPacket `protobuf:"bytes,1,opt,name=mcloud_packet,json=mcloudPacket,proto3" json:"mcloud_packet,omitempty" form:"mcloud_packet" query:"mcloud_packet"`
+	// MCloudPacket JSON in the same envelope as GetPacketByIDResp.user_packets,
+	// sent instead of mcloud_packet.
+	EncryptedMcloudPacket string `protobuf:"bytes,2,opt,name=encrypted_mcloud_packet,json=encryptedMcloudPacket,proto3" json:"encrypted_mcloud_packet,omitempty" form:"encrypted_mcloud_packet" query:"encrypted_mcloud_packet"`
 }
 
 func (x *MUploadAllChannelsPacketReq) Reset() {
 	*x = MUploadAllChannelsPacketReq{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_packet_proto_msgTypes[11]
+		mi := &file_packet_proto_msgTypes[17]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -771,7 +1411,7 @@ func (x *MUploadAllChannelsPacketReq) String() string {
 func (*MUploadAllChannelsPacketReq) ProtoMessage() {}
 
 func (x *MUploadAllChannelsPacketReq) ProtoReflect() protoreflect.Message {
-	mi := &file_packet_proto_msgTypes[11]
+	mi := &file_packet_proto_msgTypes[17]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -784,7 +1424,7 @@ func (x *MUploadAllChannelsPacketReq) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MUploadAllChannelsPacketReq.ProtoReflect.Descriptor instead.
 func (*MUploadAllChannelsPacketReq) Descriptor() ([]byte, []int) {
-	return file_packet_proto_rawDescGZIP(), []int{11}
+	return file_packet_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *MUploadAllChannelsPacketReq) GetMcloudPacket() *MCloudPacket {
@@ -794,19 +1434,27 @@ func (x *MUploadAllChannelsPacketReq) GetMcloudPacket() *MCloudPacket {
 	return nil
 }
 
+func (x *MUploadAllChannelsPacketReq) GetEncryptedMcloudPacket() string {
+	if x != nil {
+		return x.EncryptedMcloudPacket
+	}
+	return ""
+}
+
 type MUploadAllChannelsPacketResp struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Code int32  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty" form:"code" query:"code"`
-	Msg  string `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty" form:"msg" query:"msg"`
+	Code int32   `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty" form:"code" query:"code"`
+	Msg  string  `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty" form:"msg" query:"msg"`
+	Ids  []int32 `protobuf:"varint,3,rep,packed,name=ids,proto3" json:"ids,omitempty" form:"ids" query:"ids"`
 }
 
 func (x *MUploadAllChannelsPacketResp) Reset() {
 	*x = MUploadAllChannelsPacketResp{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_packet_proto_msgTypes[12]
+		mi := &file_packet_proto_msgTypes[18]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -819,7 +1467,7 @@ func (x *MUploadAllChannelsPacketResp) String() string {
 func (*MUploadAllChannelsPacketResp) ProtoMessage() {}
 
 func (x *MUploadAllChannelsPacketResp) ProtoReflect() protoreflect.Message {
-	mi := &file_packet_proto_msgTypes[12]
+	mi := &file_packet_proto_msgTypes[18]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -832,7 +1480,7 @@ func (x *MUploadAllChannelsPacketResp) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MUploadAllChannelsPacketResp.ProtoReflect.Descriptor instead.
 func (*MUploadAllChannelsPacketResp) Descriptor() ([]byte, []int) {
-	return file_packet_proto_rawDescGZIP(), []int{12}
+	return file_packet_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *MUploadAllChannelsPacketResp) GetCode() int32 {
@@ -849,6 +1497,13 @@ func (x *MUploadAllChannelsPacketResp) GetMsg() string {
 	return ""
 }
 
+func (x *MUploadAllChannelsPacketResp) GetIds() []int32 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
 var File_packet_proto protoreflect.FileDescriptor
 
 var file_packet_proto_rawDesc = []byte{
@@ -874,100 +1529,212 @@ var file_packet_proto_rawDesc = []byte{
 	0x52, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x12, 0x33, 0x0a, 0x0c, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x70,
 	0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x75,
 	0x73, 0x65, 0x72, 0x2e, 0x55, 0x73, 0x65, 0x72, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x0b,
-	0x75, 0x73, 0x65, 0x72, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x22, 0x47, 0x0a, 0x0f, 0x55,
+	0x75, 0x73, 0x65, 0x72, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x22, 0x7d, 0x0a, 0x0f, 0x55,
 	0x70, 0x6c, 0x6f, 0x61, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x71, 0x12, 0x34,
 	0x0a, 0x0c, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x5f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x18, 0x01,
 	0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x43, 0x6c, 0x6f, 0x75,
 	0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x0b, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x50, 0x61,
-	0x63, 0x6b, 0x65, 0x74, 0x22, 0x38, 0x0a, 0x10, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x50, 0x61,
-	0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x10, 0x0a, 0x03,
-	0x6d, 0x73, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6d, 0x73, 0x67, 0x22, 0x3f,
-	0x0a, 0x0d, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x71, 0x12,
-	0x12, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74,
-	0x69, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x22,
-	0x6e, 0x0a, 0x0e, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73,
-	0x70, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52,
-	0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x73, 0x67, 0x18, 0x02, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x03, 0x6d, 0x73, 0x67, 0x12, 0x36, 0x0a, 0x0d, 0x63, 0x6c, 0x6f, 0x75, 0x64,
-	0x5f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11,
-	0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x43, 0x6c, 0x6f, 0x75, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65,
-	0x74, 0x52, 0x0c, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x22,
-	0x52, 0x0a, 0x10, 0x47, 0x65, 0x74, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x42, 0x79, 0x49, 0x44,
-	0x52, 0x65, 0x71, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e,
-	0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e,
-	0x61, 0x6d, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52,
-	0x02, 0x69, 0x64, 0x22, 0x5c, 0x0a, 0x11, 0x47, 0x65, 0x74, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74,
-	0x42, 0x79, 0x49, 0x44, 0x52, 0x65, 0x73, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x10, 0x0a, 0x03,
-	0x6d, 0x73, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6d, 0x73, 0x67, 0x12, 0x21,
-	0x0a, 0x0c, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x18, 0x03,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x75, 0x73, 0x65, 0x72, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74,
-	0x73, 0x22, 0x35, 0x0a, 0x0f, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x50, 0x61, 0x63, 0x6b, 0x65,
-	0x74, 0x52, 0x65, 0x71, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x05, 0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x6f, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x05, 0x52, 0x02, 0x74, 0x6f, 0x22, 0x38, 0x0a, 0x10, 0x44, 0x65, 0x6c, 0x65,
-	0x74, 0x65, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x12, 0x12, 0x0a, 0x04,
+	0x63, 0x6b, 0x65, 0x74, 0x12, 0x34, 0x0a, 0x16, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65,
+	0x64, 0x5f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x5f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x14, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x43,
+	0x6c, 0x6f, 0x75, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x22, 0x48, 0x0a, 0x10, 0x55, 0x70,
+	0x6c, 0x6f, 0x61, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x12, 0x12,
+	0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f,
+	0x64, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x73, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x03, 0x6d, 0x73, 0x67, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x02, 0x69, 0x64, 0x22, 0xb2, 0x02, 0x0a, 0x0d, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x61, 0x63,
+	0x6b, 0x65, 0x74, 0x52, 0x65, 0x71, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x73,
+	0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x73,
+	0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x12, 0x18,
+	0x0a, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x70, 0x6c, 0x6f,
+	0x61, 0x64, 0x65, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x70, 0x6c, 0x6f,
+	0x61, 0x64, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65,
+	0x5f, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x74, 0x69, 0x6d,
+	0x65, 0x46, 0x72, 0x6f, 0x6d, 0x12, 0x17, 0x0a, 0x07, 0x74, 0x69, 0x6d, 0x65, 0x5f, 0x74, 0x6f,
+	0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x74, 0x69, 0x6d, 0x65, 0x54, 0x6f, 0x12, 0x12,
+	0x0a, 0x04, 0x70, 0x61, 0x67, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x61,
+	0x67, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x70, 0x61, 0x67, 0x65, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18,
+	0x0a, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x70, 0x61, 0x67, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x12,
+	0x12, 0x0a, 0x04, 0x73, 0x6f, 0x72, 0x74, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x73,
+	0x6f, 0x72, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x18, 0x0c, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x22, 0xb5, 0x01, 0x0a, 0x0e, 0x4c, 0x69,
+	0x73, 0x74, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x12, 0x12, 0x0a, 0x04,
 	0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65,
 	0x12, 0x10, 0x0a, 0x03, 0x6d, 0x73, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6d,
-	0x73, 0x67, 0x22, 0xc9, 0x01, 0x0a, 0x0c, 0x4d, 0x43, 0x6c, 0x6f, 0x75, 0x64, 0x50, 0x61, 0x63,
-	0x6b, 0x65, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52,
-	0x02, 0x69, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x6e,
-	0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12,
-	0x18, 0x0a, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09,
-	0x52, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x70, 0x6c,
-	0x6f, 0x61, 0x64, 0x65, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x70, 0x6c,
-	0x6f, 0x61, 0x64, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x06, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x12, 0x33, 0x0a, 0x0c, 0x75, 0x73, 0x65,
-	0x72, 0x5f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32,
-	0x10, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x55, 0x73, 0x65, 0x72, 0x50, 0x61, 0x63, 0x6b, 0x65,
-	0x74, 0x52, 0x0b, 0x75, 0x73, 0x65, 0x72, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x22, 0x56,
-	0x0a, 0x1b, 0x4d, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x41, 0x6c, 0x6c, 0x43, 0x68, 0x61, 0x6e,
-	0x6e, 0x65, 0x6c, 0x73, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x71, 0x12, 0x37, 0x0a,
-	0x0d, 0x6d, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x5f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x4d, 0x43, 0x6c, 0x6f,
-	0x75, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x0c, 0x6d, 0x63, 0x6c, 0x6f, 0x75, 0x64,
-	0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x22, 0x44, 0x0a, 0x1c, 0x4d, 0x55, 0x70, 0x6c, 0x6f, 0x61,
-	0x64, 0x41, 0x6c, 0x6c, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x50, 0x61, 0x63, 0x6b,
-	0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x73,
-	0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6d, 0x73, 0x67, 0x32, 0xde, 0x03, 0x0a,
-	0x0d, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x54,
-	0x0a, 0x0c, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x15,
-	0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x50, 0x61, 0x63, 0x6b,
-	0x65, 0x74, 0x52, 0x65, 0x71, 0x1a, 0x16, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x55, 0x70, 0x6c,
-	0x6f, 0x61, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x22, 0x15, 0xd2,
-	0xc1, 0x18, 0x11, 0x2f, 0x76, 0x31, 0x2f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x2f, 0x75, 0x70,
-	0x6c, 0x6f, 0x61, 0x64, 0x12, 0x4c, 0x0a, 0x0a, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x61, 0x63, 0x6b,
-	0x65, 0x74, 0x12, 0x13, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x61,
-	0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x71, 0x1a, 0x14, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x4c,
-	0x69, 0x73, 0x74, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x22, 0x13, 0xca,
-	0xc1, 0x18, 0x0f, 0x2f, 0x76, 0x31, 0x2f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x2f, 0x6c, 0x69,
-	0x73, 0x74, 0x12, 0x58, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x42,
-	0x79, 0x49, 0x44, 0x12, 0x16, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x61,
-	0x63, 0x6b, 0x65, 0x74, 0x42, 0x79, 0x49, 0x44, 0x52, 0x65, 0x71, 0x1a, 0x17, 0x2e, 0x75, 0x73,
-	0x65, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x42, 0x79, 0x49, 0x44,
-	0x52, 0x65, 0x73, 0x70, 0x22, 0x16, 0xca, 0xc1, 0x18, 0x12, 0x2f, 0x76, 0x31, 0x2f, 0x70, 0x61,
-	0x63, 0x6b, 0x65, 0x74, 0x2f, 0x67, 0x65, 0x74, 0x2f, 0x3a, 0x69, 0x64, 0x12, 0x54, 0x0a, 0x0c,
-	0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x15, 0x2e, 0x75,
-	0x73, 0x65, 0x72, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74,
-	0x52, 0x65, 0x71, 0x1a, 0x16, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74,
-	0x65, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x22, 0x15, 0xe2, 0xc1, 0x18,
-	0x11, 0x2f, 0x76, 0x31, 0x2f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x2f, 0x64, 0x65, 0x6c, 0x65,
-	0x74, 0x65, 0x12, 0x79, 0x0a, 0x18, 0x4d, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x41, 0x6c, 0x6c,
-	0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x21,
-	0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x4d, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x41, 0x6c, 0x6c,
-	0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65,
-	0x71, 0x1a, 0x22, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x4d, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64,
-	0x41, 0x6c, 0x6c, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x50, 0x61, 0x63, 0x6b, 0x65,
-	0x74, 0x52, 0x65, 0x73, 0x70, 0x22, 0x16, 0xd2, 0xc1, 0x18, 0x12, 0x2f, 0x76, 0x31, 0x2f, 0x70,
-	0x61, 0x63, 0x6b, 0x65, 0x74, 0x2f, 0x6d, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x42, 0x25, 0x5a,
-	0x23, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x5f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x2f, 0x62, 0x69,
-	0x7a, 0x2f, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x2f, 0x68, 0x65, 0x72, 0x74, 0x7a, 0x2f, 0x70, 0x61,
-	0x63, 0x6b, 0x65, 0x74, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x73, 0x67, 0x12, 0x36, 0x0a, 0x0d, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x5f, 0x70, 0x61, 0x63, 0x6b,
+	0x65, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x75, 0x73, 0x65, 0x72,
+	0x2e, 0x43, 0x6c, 0x6f, 0x75, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x0c, 0x63, 0x6c,
+	0x6f, 0x75, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f,
+	0x74, 0x61, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c,
+	0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x67, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04,
+	0x70, 0x61, 0x67, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x70, 0x61, 0x67, 0x65, 0x5f, 0x73, 0x69, 0x7a,
+	0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x70, 0x61, 0x67, 0x65, 0x53, 0x69, 0x7a,
+	0x65, 0x22, 0x76, 0x0a, 0x10, 0x47, 0x65, 0x74, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x42, 0x79,
+	0x49, 0x44, 0x52, 0x65, 0x71, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x73, 0x65,
+	0x72, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x73, 0x65,
+	0x72, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x02, 0x69, 0x64, 0x12, 0x22, 0x0a, 0x06, 0x63, 0x69, 0x70, 0x68, 0x65, 0x72, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x09, 0x42, 0x0a, 0xb2, 0xbb, 0x18, 0x06, 0x63, 0x69, 0x70, 0x68, 0x65,
+	0x72, 0x52, 0x06, 0x63, 0x69, 0x70, 0x68, 0x65, 0x72, 0x22, 0x5c, 0x0a, 0x11, 0x47, 0x65, 0x74,
+	0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x42, 0x79, 0x49, 0x44, 0x52, 0x65, 0x73, 0x70, 0x12, 0x12,
+	0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f,
+	0x64, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x73, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x03, 0x6d, 0x73, 0x67, 0x12, 0x21, 0x0a, 0x0c, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x70, 0x61, 0x63,
+	0x6b, 0x65, 0x74, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x75, 0x73, 0x65, 0x72,
+	0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x22, 0x9c, 0x02, 0x0a, 0x0f, 0x44, 0x65, 0x6c, 0x65,
+	0x74, 0x65, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x71, 0x12, 0x12, 0x0a, 0x04, 0x66,
+	0x72, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12,
+	0x0e, 0x0a, 0x02, 0x74, 0x6f, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x02, 0x74, 0x6f, 0x12,
+	0x10, 0x0a, 0x03, 0x69, 0x64, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x05, 0x52, 0x03, 0x69, 0x64,
+	0x73, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x65, 0x72, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x65, 0x72, 0x12, 0x16, 0x0a,
+	0x06, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72,
+	0x65, 0x67, 0x69, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c,
+	0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x12,
+	0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x74, 0x69, 0x6d, 0x65, 0x5f, 0x62, 0x65, 0x66, 0x6f,
+	0x72, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x74, 0x69, 0x6d, 0x65, 0x42, 0x65,
+	0x66, 0x6f, 0x72, 0x65, 0x12, 0x17, 0x0a, 0x07, 0x64, 0x72, 0x79, 0x5f, 0x72, 0x75, 0x6e, 0x18,
+	0x09, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x64, 0x72, 0x79, 0x52, 0x75, 0x6e, 0x12, 0x18, 0x0a,
+	0x07, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x72, 0x6d, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07,
+	0x63, 0x6f, 0x6e, 0x66, 0x69, 0x72, 0x6d, 0x12, 0x1d, 0x0a, 0x0a, 0x6f, 0x70, 0x65, 0x6e, 0x5f,
+	0x65, 0x6e, 0x64, 0x65, 0x64, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x6f, 0x70, 0x65,
+	0x6e, 0x45, 0x6e, 0x64, 0x65, 0x64, 0x22, 0x64, 0x0a, 0x10, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65,
+	0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f,
+	0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x10,
+	0x0a, 0x03, 0x6d, 0x73, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6d, 0x73, 0x67,
+	0x12, 0x10, 0x0a, 0x03, 0x69, 0x64, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x05, 0x52, 0x03, 0x69,
+	0x64, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x72, 0x6d, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x72, 0x6d, 0x22, 0x5f, 0x0a, 0x0f,
+	0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x71, 0x12,
+	0x16, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x42, 0x06, 0xd2, 0xbb, 0x18,
+	0x02, 0x69, 0x64, 0x52, 0x02, 0x69, 0x64, 0x12, 0x34, 0x0a, 0x0c, 0x63, 0x6c, 0x6f, 0x75, 0x64,
+	0x5f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e,
+	0x75, 0x73, 0x65, 0x72, 0x2e, 0x43, 0x6c, 0x6f, 0x75, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74,
+	0x52, 0x0b, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x22, 0x38, 0x0a,
+	0x10, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73,
+	0x70, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x73, 0x67, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x6d, 0x73, 0x67, 0x22, 0xd2, 0x01, 0x0a, 0x0e, 0x50, 0x61, 0x74, 0x63,
+	0x68, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x71, 0x12, 0x16, 0x0a, 0x02, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x42, 0x06, 0xd2, 0xbb, 0x18, 0x02, 0x69, 0x64, 0x52, 0x02,
+	0x69, 0x64, 0x12, 0x17, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x48, 0x00, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x88, 0x01, 0x01, 0x12, 0x1b, 0x0a, 0x06, 0x72,
+	0x65, 0x67, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x48, 0x01, 0x52, 0x06, 0x72,
+	0x65, 0x67, 0x69, 0x6f, 0x6e, 0x88, 0x01, 0x01, 0x12, 0x1d, 0x0a, 0x07, 0x63, 0x68, 0x61, 0x6e,
+	0x6e, 0x65, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x48, 0x02, 0x52, 0x07, 0x63, 0x68, 0x61,
+	0x6e, 0x6e, 0x65, 0x6c, 0x88, 0x01, 0x01, 0x12, 0x33, 0x0a, 0x0c, 0x75, 0x73, 0x65, 0x72, 0x5f,
+	0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e,
+	0x75, 0x73, 0x65, 0x72, 0x2e, 0x55, 0x73, 0x65, 0x72, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52,
+	0x0b, 0x75, 0x73, 0x65, 0x72, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x42, 0x07, 0x0a, 0x05,
+	0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x42, 0x09, 0x0a, 0x07, 0x5f, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e,
+	0x42, 0x0a, 0x0a, 0x08, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x22, 0x6d, 0x0a, 0x0f,
+	0x50, 0x61, 0x74, 0x63, 0x68, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x12,
+	0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63,
+	0x6f, 0x64, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x73, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x6d, 0x73, 0x67, 0x12, 0x34, 0x0a, 0x0c, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x5f, 0x70,
+	0x61, 0x63, 0x6b, 0x65, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x75, 0x73,
+	0x65, 0x72, 0x2e, 0x43, 0x6c, 0x6f, 0x75, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x0b,
+	0x63, 0x6c, 0x6f, 0x75, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x22, 0x61, 0x0a, 0x0e, 0x4c,
+	0x69, 0x73, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x52, 0x65, 0x71, 0x12, 0x12, 0x0a,
+	0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x69, 0x6d,
+	0x65, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1f, 0x0a,
+	0x05, 0x73, 0x69, 0x6e, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x42, 0x09, 0xb2, 0xbb,
+	0x18, 0x05, 0x73, 0x69, 0x6e, 0x63, 0x65, 0x52, 0x05, 0x73, 0x69, 0x6e, 0x63, 0x65, 0x22, 0xbb,
+	0x01, 0x0a, 0x0f, 0x4c, 0x69, 0x73, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x52, 0x65,
+	0x73, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x73, 0x67, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x03, 0x6d, 0x73, 0x67, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x76, 0x69,
+	0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x72, 0x65, 0x76, 0x69,
+	0x73, 0x69, 0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x65, 0x73, 0x65, 0x74, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x05, 0x72, 0x65, 0x73, 0x65, 0x74, 0x12, 0x36, 0x0a, 0x0d, 0x63, 0x6c,
+	0x6f, 0x75, 0x64, 0x5f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x11, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x43, 0x6c, 0x6f, 0x75, 0x64, 0x50, 0x61,
+	0x63, 0x6b, 0x65, 0x74, 0x52, 0x0c, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65,
+	0x74, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x18, 0x06, 0x20,
+	0x03, 0x28, 0x05, 0x52, 0x07, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x22, 0xc9, 0x01, 0x0a,
+	0x0c, 0x4d, 0x43, 0x6c, 0x6f, 0x75, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x0e, 0x0a,
+	0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x02, 0x69, 0x64, 0x12, 0x16, 0x0a,
+	0x06, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72,
+	0x65, 0x67, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x68, 0x61,
+	0x6e, 0x6e, 0x65, 0x6c, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x63, 0x68, 0x61, 0x6e,
+	0x6e, 0x65, 0x6c, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x65, 0x72, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x65, 0x72, 0x12,
+	0x12, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74,
+	0x69, 0x6d, 0x65, 0x12, 0x33, 0x0a, 0x0c, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x70, 0x61, 0x63, 0x6b,
+	0x65, 0x74, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x75, 0x73, 0x65, 0x72,
+	0x2e, 0x55, 0x73, 0x65, 0x72, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x0b, 0x75, 0x73, 0x65,
+	0x72, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x22, 0x8e, 0x01, 0x0a, 0x1b, 0x4d, 0x55, 0x70,
+	0x6c, 0x6f, 0x61, 0x64, 0x41, 0x6c, 0x6c, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x50,
+	0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x71, 0x12, 0x37, 0x0a, 0x0d, 0x6d, 0x63, 0x6c, 0x6f,
+	0x75, 0x64, 0x5f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x12, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x4d, 0x43, 0x6c, 0x6f, 0x75, 0x64, 0x50, 0x61, 0x63,
+	0x6b, 0x65, 0x74, 0x52, 0x0c, 0x6d, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65,
+	0x74, 0x12, 0x36, 0x0a, 0x17, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x5f, 0x6d,
+	0x63, 0x6c, 0x6f, 0x75, 0x64, 0x5f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x15, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x4d, 0x63, 0x6c,
+	0x6f, 0x75, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x22, 0x56, 0x0a, 0x1c, 0x4d, 0x55, 0x70,
+	0x6c, 0x6f, 0x61, 0x64, 0x41, 0x6c, 0x6c, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x50,
+	0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64,
+	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x10, 0x0a,
+	0x03, 0x6d, 0x73, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6d, 0x73, 0x67, 0x12,
+	0x10, 0x0a, 0x03, 0x69, 0x64, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x05, 0x52, 0x03, 0x69, 0x64,
+	0x73, 0x32, 0xd5, 0x05, 0x0a, 0x0d, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x53, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x12, 0x54, 0x0a, 0x0c, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x50, 0x61, 0x63,
+	0x6b, 0x65, 0x74, 0x12, 0x15, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x55, 0x70, 0x6c, 0x6f, 0x61,
+	0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x71, 0x1a, 0x16, 0x2e, 0x75, 0x73, 0x65,
+	0x72, 0x2e, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65,
+	0x73, 0x70, 0x22, 0x15, 0xd2, 0xc1, 0x18, 0x11, 0x2f, 0x76, 0x31, 0x2f, 0x70, 0x61, 0x63, 0x6b,
+	0x65, 0x74, 0x2f, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x12, 0x4c, 0x0a, 0x0a, 0x4c, 0x69, 0x73,
+	0x74, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x13, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x4c,
+	0x69, 0x73, 0x74, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x71, 0x1a, 0x14, 0x2e, 0x75,
+	0x73, 0x65, 0x72, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65,
+	0x73, 0x70, 0x22, 0x13, 0xca, 0xc1, 0x18, 0x0f, 0x2f, 0x76, 0x31, 0x2f, 0x70, 0x61, 0x63, 0x6b,
+	0x65, 0x74, 0x2f, 0x6c, 0x69, 0x73, 0x74, 0x12, 0x58, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x50, 0x61,
+	0x63, 0x6b, 0x65, 0x74, 0x42, 0x79, 0x49, 0x44, 0x12, 0x16, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e,
+	0x47, 0x65, 0x74, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x42, 0x79, 0x49, 0x44, 0x52, 0x65, 0x71,
+	0x1a, 0x17, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x61, 0x63, 0x6b, 0x65,
+	0x74, 0x42, 0x79, 0x49, 0x44, 0x52, 0x65, 0x73, 0x70, 0x22, 0x16, 0xca, 0xc1, 0x18, 0x12, 0x2f,
+	0x76, 0x31, 0x2f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x2f, 0x67, 0x65, 0x74, 0x2f, 0x3a, 0x69,
+	0x64, 0x12, 0x54, 0x0a, 0x0c, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x50, 0x61, 0x63, 0x6b, 0x65,
+	0x74, 0x12, 0x15, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x50,
+	0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x71, 0x1a, 0x16, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e,
+	0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70,
+	0x22, 0x15, 0xe2, 0xc1, 0x18, 0x11, 0x2f, 0x76, 0x31, 0x2f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74,
+	0x2f, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x12, 0x79, 0x0a, 0x18, 0x4d, 0x55, 0x70, 0x6c, 0x6f,
+	0x61, 0x64, 0x41, 0x6c, 0x6c, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x50, 0x61, 0x63,
+	0x6b, 0x65, 0x74, 0x12, 0x21, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x4d, 0x55, 0x70, 0x6c, 0x6f,
+	0x61, 0x64, 0x41, 0x6c, 0x6c, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x50, 0x61, 0x63,
+	0x6b, 0x65, 0x74, 0x52, 0x65, 0x71, 0x1a, 0x22, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x4d, 0x55,
+	0x70, 0x6c, 0x6f, 0x61, 0x64, 0x41, 0x6c, 0x6c, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73,
+	0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x22, 0x16, 0xd2, 0xc1, 0x18, 0x12,
+	0x2f, 0x76, 0x31, 0x2f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x2f, 0x6d, 0x75, 0x70, 0x6c, 0x6f,
+	0x61, 0x64, 0x12, 0x51, 0x0a, 0x0c, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x50, 0x61, 0x63, 0x6b,
+	0x65, 0x74, 0x12, 0x15, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65,
+	0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x71, 0x1a, 0x16, 0x2e, 0x75, 0x73, 0x65, 0x72,
+	0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73,
+	0x70, 0x22, 0x12, 0xda, 0xc1, 0x18, 0x0e, 0x2f, 0x76, 0x31, 0x2f, 0x70, 0x61, 0x63, 0x6b, 0x65,
+	0x74, 0x2f, 0x3a, 0x69, 0x64, 0x12, 0x4e, 0x0a, 0x0b, 0x50, 0x61, 0x74, 0x63, 0x68, 0x50, 0x61,
+	0x63, 0x6b, 0x65, 0x74, 0x12, 0x14, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x50, 0x61, 0x74, 0x63,
+	0x68, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x71, 0x1a, 0x15, 0x2e, 0x75, 0x73, 0x65,
+	0x72, 0x2e, 0x50, 0x61, 0x74, 0x63, 0x68, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73,
+	0x70, 0x22, 0x12, 0xea, 0xc1, 0x18, 0x0e, 0x2f, 0x76, 0x31, 0x2f, 0x70, 0x61, 0x63, 0x6b, 0x65,
+	0x74, 0x2f, 0x3a, 0x69, 0x64, 0x12, 0x52, 0x0a, 0x0b, 0x4c, 0x69, 0x73, 0x74, 0x43, 0x68, 0x61,
+	0x6e, 0x67, 0x65, 0x73, 0x12, 0x14, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x4c, 0x69, 0x73, 0x74,
+	0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x52, 0x65, 0x71, 0x1a, 0x15, 0x2e, 0x75, 0x73, 0x65,
+	0x72, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x22, 0x16, 0xca, 0xc1, 0x18, 0x12, 0x2f, 0x76, 0x31, 0x2f, 0x70, 0x61, 0x63, 0x6b, 0x65,
+	0x74, 0x2f, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x42, 0x25, 0x5a, 0x23, 0x70, 0x61, 0x63,
+	0x6b, 0x65, 0x74, 0x5f, 0x63, 0x6c, 0x6f, 0x75, 0x64, 0x2f, 0x62, 0x69, 0x7a, 0x2f, 0x6d, 0x6f,
+	0x64, 0x65, 0x6c, 0x2f, 0x68, 0x65, 0x72, 0x74, 0x7a, 0x2f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -982,7 +1749,7 @@ func file_packet_proto_rawDescGZIP() []byte {
 	return file_packet_proto_rawDescData
 }
 
-var file_packet_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_packet_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
 var file_packet_proto_goTypes = []interface{}{
 	(*UserPacket)(nil),                   // 0: user.UserPacket
 	(*CloudPacket)(nil),                  // 1: user.CloudPacket
@@ -994,31 +1761,47 @@ var file_packet_proto_goTypes = []interface{}{
 	(*GetPacketByIDResp)(nil),            // 7: user.GetPacketByIDResp
 	(*DeletePacketReq)(nil),              // 8: user.DeletePacketReq
 	(*DeletePacketResp)(nil),             // 9: user.DeletePacketResp
-	(*MCloudPacket)(nil),                 // 10: user.MCloudPacket
-	(*MUploadAllChannelsPacketReq)(nil),  // 11: user.MUploadAllChannelsPacketReq
-	(*MUploadAllChannelsPacketResp)(nil), // 12: user.MUploadAllChannelsPacketResp
+	(*UpdatePacketReq)(nil),              // 10: user.UpdatePacketReq
+	(*UpdatePacketResp)(nil),             // 11: user.UpdatePacketResp
+	(*PatchPacketReq)(nil),               // 12: user.PatchPacketReq
+	(*PatchPacketResp)(nil),              // 13: user.PatchPacketResp
+	(*ListChangesReq)(nil),               // 14: user.ListChangesReq
+	(*ListChangesResp)(nil),              // 15: user.ListChangesResp
+	(*MCloudPacket)(nil),                 // 16: user.MCloudPacket
+	(*MUploadAllChannelsPacketReq)(nil),  // 17: user.MUploadAllChannelsPacketReq
+	(*MUploadAllChannelsPacketResp)(nil), // 18: user.MUploadAllChannelsPacketResp
 }
 var file_packet_proto_depIdxs = []int32{
 	0,  // 0: user.CloudPacket.user_packets:type_name -> user.UserPacket
 	1,  // 1: user.UploadPacketReq.cloud_packet:type_name -> user.CloudPacket
 	1,  // 2: user.ListPacketResp.cloud_packets:type_name -> user.CloudPacket
-	0,  // 3: user.MCloudPacket.user_packets:type_name -> user.UserPacket
-	10, // 4: user.MUploadAllChannelsPacketReq.mcloud_packet:type_name -> user.MCloudPacket
-	2,  // 5: user.PacketService.UploadPacket:input_type -> user.UploadPacketReq
-	4,  // 6: user.PacketService.ListPacket:input_type -> user.ListPacketReq
-	6,  // 7: user.PacketService.GetPacketByID:input_type -> user.GetPacketByIDReq
-	8,  // 8: user.PacketService.DeletePacket:input_type -> user.DeletePacketReq
-	11, // 9: user.PacketService.MUploadAllChannelsPacket:input_type -> user.MUploadAllChannelsPacketReq
-	3,  // 10: user.PacketService.UploadPacket:output_type -> user.UploadPacketResp
-	5,  // 11: user.PacketService.ListPacket:output_type -> user.ListPacketResp
-	7,  // 12: user.PacketService.GetPacketByID:output_type -> user.GetPacketByIDResp
-	9,  // 13: user.PacketService.DeletePacket:output_type -> user.DeletePacketResp
-	12, // 14: user.PacketService.MUploadAllChannelsPacket:output_type -> user.MUploadAllChannelsPacketResp
-	10, // [10:15] is the sub-list for method output_type
-	5,  // [5:10] is the sub-list for method input_type
-	5,  // [5:5] is the sub-list for extension type_name
-	5,  // [5:5] is the sub-list for extension extendee
-	0,  // [0:5] is the sub-list for field type_name
+	1,  // 3: user.UpdatePacketReq.cloud_packet:type_name -> user.CloudPacket
+	0,  // 4: user.PatchPacketReq.user_packets:type_name -> user.UserPacket
+	1,  // 5: user.PatchPacketResp.cloud_packet:type_name -> user.CloudPacket
+	1,  // 6: user.ListChangesResp.cloud_packets:type_name -> user.CloudPacket
+	0,  // 7: user.MCloudPacket.user_packets:type_name -> user.UserPacket
+	16, // 8: user.MUploadAllChannelsPacketReq.mcloud_packet:type_name -> user.MCloudPacket
+	2,  // 9: user.PacketService.UploadPacket:input_type -> user.UploadPacketReq
+	4,  // 10: user.PacketService.ListPacket:input_type -> user.ListPacketReq
+	6,  // 11: user.PacketService.GetPacketByID:input_type -> user.GetPacketByIDReq
+	8,  // 12: user.PacketService.DeletePacket:input_type -> user.DeletePacketReq
+	17, // 13: user.PacketService.MUploadAllChannelsPacket:input_type -> user.MUploadAllChannelsPacketReq
+	10, // 14: user.PacketService.UpdatePacket:input_type -> user.UpdatePacketReq
+	12, // 15: user.PacketService.PatchPacket:input_type -> user.PatchPacketReq
+	14, // 16: user.PacketService.ListChanges:input_type -> user.ListChangesReq
+	3,  // 17: user.PacketService.UploadPacket:output_type -> user.UploadPacketResp
+	5,  // 18: user.PacketService.ListPacket:output_type -> user.ListPacketResp
+	7,  // 19: user.PacketService.GetPacketByID:output_type -> user.GetPacketByIDResp
+	9,  // 20: user.PacketService.DeletePacket:output_type -> user.DeletePacketResp
+	18, // 21: user.PacketService.MUploadAllChannelsPacket:output_type -> user.MUploadAllChannelsPacketResp
+	11, // 22: user.PacketService.UpdatePacket:output_type -> user.UpdatePacketResp
+	13, // 23: user.PacketService.PatchPacket:output_type -> user.PatchPacketResp
+	15, // 24: user.PacketService.ListChanges:output_type -> user.ListChangesResp
+	17, // [17:25] is the sub-list for method output_type
+	9,  // [9:17] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
 }
 
 func init() { file_packet_proto_init() }
@@ -1148,7 +1931,7 @@ func file_packet_proto_init() {
 			}
 		}
 		file_packet_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*MCloudPacket); i {
+			switch v := v.(*UpdatePacketReq); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1160,7 +1943,7 @@ func file_packet_proto_init() {
 			}
 		}
 		file_packet_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*MUploadAllChannelsPacketReq); i {
+			switch v := v.(*UpdatePacketResp); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1172,6 +1955,78 @@ func file_packet_proto_init() {
 			}
 		}
 		file_packet_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PatchPacketReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_packet_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PatchPacketResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_packet_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListChangesReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_packet_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListChangesResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_packet_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MCloudPacket); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_packet_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MUploadAllChannelsPacketReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_packet_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*MUploadAllChannelsPacketResp); i {
 			case 0:
 				return &v.state
@@ -1184,13 +2039,14 @@ func file_packet_proto_init() {
 			}
 		}
 	}
+	file_packet_proto_msgTypes[12].OneofWrappers = []interface{}{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_packet_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   13,
+			NumMessages:   19,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -0,0 +1,61 @@
+package mw
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	cfg "packet_cloud/config"
+	"packet_cloud/service/auth"
+)
+
+const (
+	// APIKeyHeader carries the client API key.
+	APIKeyHeader = "X-Api-Key"
+	// ClientKey is the request context key holding the name of the client
+	// authenticated by its API key.
+	ClientKey = "client"
+)
+
+// APIKeyAuth checks the client API key for scope. Requests without a key are
+// let through unauthenticated unless keys are required by the config; a key
+// that is sent must always be valid.
+func APIKeyAuth(scope string) app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		secret := string(c.GetHeader(APIKeyHeader))
+		if secret == "" {
+			if cfg.Get().APIKeys.Required {
+				c.AbortWithMsg("api key required", consts.StatusUnauthorized)
+				return
+			}
+			c.Next(ctx)
+			return
+		}
+
+		k, err := auth.Authenticate(ctx, secret, scope)
+		switch {
+		case errors.Is(err, auth.ErrScope):
+			c.AbortWithMsg("api key not allowed to "+scope, consts.StatusForbidden)
+			return
+		case errors.Is(err, auth.ErrInvalidKey):
+			c.AbortWithMsg("invalid api key", consts.StatusUnauthorized)
+			return
+		case err != nil:
+			log.Println("[APIKeyAuth] authenticate error", err)
+			c.AbortWithMsg("internal error", consts.StatusInternalServerError)
+			return
+		}
+
+		c.Set(ClientKey, k.Name)
+		c.Next(ctx)
+	}
+}
+
+// Client returns the name of the client authenticated by APIKeyAuth, if any.
+func Client(c *app.RequestContext) (string, bool) {
+	name := c.GetString(ClientKey)
+	return name, name != ""
+}
@@ -0,0 +1,75 @@
+package mw
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	cfg "packet_cloud/config"
+	"packet_cloud/service/auth"
+	"packet_cloud/util"
+)
+
+// Signature verifies the HMAC request signature. Unsigned requests pass
+// unless signing is required by the config. The signer becomes the client
+// identity and must match the API key's client when both are sent.
+func Signature() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		sig := string(c.GetHeader(util.SignatureHeader))
+		if sig == "" {
+			if cfg.Get().Signing.Required {
+				c.AbortWithMsg("signature required", consts.StatusUnauthorized)
+				return
+			}
+			c.Next(ctx)
+			return
+		}
+
+		user := string(c.GetHeader(util.SignUserHeader))
+		timestamp := string(c.GetHeader(util.SignTimestampHeader))
+		if string(c.Method()) == consts.MethodGet {
+			if user == "" {
+				user = c.Query("username")
+			}
+			if timestamp == "" {
+				timestamp = c.Query("time")
+			}
+		}
+		ts, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			c.AbortWithMsg("invalid signature timestamp", consts.StatusUnauthorized)
+			return
+		}
+
+		err = auth.VerifyRequest(&auth.SignedRequest{
+			User:      user,
+			Method:    string(c.Method()),
+			URI:       string(c.Request.RequestURI()),
+			Body:      c.Request.Body(),
+			Timestamp: ts,
+			Nonce:     string(c.GetHeader(util.SignNonceHeader)),
+			Signature: sig,
+		})
+		switch {
+		case errors.Is(err, auth.ErrStaleRequest):
+			c.AbortWithMsg("request expired", consts.StatusUnauthorized)
+			return
+		case errors.Is(err, auth.ErrReplay):
+			c.AbortWithMsg("request replayed", consts.StatusUnauthorized)
+			return
+		case err != nil:
+			c.AbortWithMsg("invalid signature", consts.StatusUnauthorized)
+			return
+		}
+
+		if name, ok := Client(c); ok && name != user {
+			c.AbortWithMsg("signer does not match api key", consts.StatusUnauthorized)
+			return
+		}
+		c.Set(ClientKey, user)
+		c.Next(ctx)
+	}
+}
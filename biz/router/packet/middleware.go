@@ -4,6 +4,8 @@ package packet
 
 import (
 	"github.com/cloudwego/hertz/pkg/app"
+	"packet_cloud/biz/mw"
+	"packet_cloud/service/auth"
 )
 
 func rootMw() []app.HandlerFunc {
@@ -62,13 +64,11 @@ func _getpacketMw() []app.HandlerFunc {
 }
 
 func _uploadpacketMw() []app.HandlerFunc {
-	// your code...
-	return nil
+	return []app.HandlerFunc{mw.APIKeyAuth(auth.ScopeUpload), mw.Signature()}
 }
 
 func _deletepacketMw() []app.HandlerFunc {
-	// your code...
-	return nil
+	return []app.HandlerFunc{mw.AdminAuth(false)}
 }
 
 func _mupload_llchannelspacketMw() []app.HandlerFunc {
@@ -82,16 +82,25 @@ func _getMw() []app.HandlerFunc {
 }
 
 func _getpacketbyidMw() []app.HandlerFunc {
-	// your code...
-	return nil
+	return []app.HandlerFunc{mw.APIKeyAuth(auth.ScopeRead), mw.Signature()}
 }
 
 func _muploadallchannelspacketMw() []app.HandlerFunc {
-	// your code...
-	return nil
+	return []app.HandlerFunc{mw.APIKeyAuth(auth.ScopeMUpload), mw.Signature()}
 }
 
 func _listpacketMw() []app.HandlerFunc {
-	// your code...
-	return nil
+	return []app.HandlerFunc{mw.APIKeyAuth(auth.ScopeRead), mw.Signature()}
+}
+
+func _listchangesMw() []app.HandlerFunc {
+	return []app.HandlerFunc{mw.APIKeyAuth(auth.ScopeRead), mw.Signature()}
+}
+
+func _patchpacketMw() []app.HandlerFunc {
+	return []app.HandlerFunc{mw.AdminAuth(false)}
+}
+
+func _updatepacketMw() []app.HandlerFunc {
+	return []app.HandlerFunc{mw.AdminAuth(false)}
 }
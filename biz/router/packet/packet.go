@@ -21,7 +21,10 @@ func Register(r *server.Hertz) {
 		_v1 := root.Group("/v1", _v1Mw()...)
 		{
 			_packet := _v1.Group("/packet", _packetMw()...)
+			_packet.PATCH("/:id", append(_patchpacketMw(), handler.PatchPacket)...)
+			_packet.PUT("/:id", append(_updatepacketMw(), handler.UpdatePacket)...)
 			_packet.DELETE("/delete", append(_deletepacketMw(), handler.DeletePacket)...)
+			_packet.GET("/changes", append(_listchangesMw(), handler.ListChanges)...)
 			_packet.GET("/list", append(_listpacketMw(), handler.ListPacket)...)
 			_packet.POST("/mupload", append(_muploadallchannelspacketMw(), handler.MUploadAllChannelsPacket)...)
 			_packet.POST("/upload", append(_uploadpacketMw(), handler.UploadPacket)...)
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	cfg "packet_cloud/config"
+	"packet_cloud/service/aeskey"
+	"packet_cloud/service/backup"
+	"packet_cloud/service/readwriter"
+	"strconv"
+	"time"
+)
+
+// commands are the admin subcommands run as "packet_cloud <name> ...".
+var commands = map[string]func(args []string) error{
+	"backup":   backupCommand,
+	"keys":     keysCommand,
+	"migrate":  migrateCommand,
+	"transfer": transferCommand,
+}
+
+// runCommand runs the subcommand named by args[0] and returns the exit code.
+func runCommand(args []string) int {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+		return 2
+	}
+	if err := cmd(args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+const keysUsage = `usage:
+  keys list                  list the AES key IDs and the active one
+  keys rotate [-bits 128]    add a new active key, keeping the old ones for decryption
+  keys retire <id>           remove a key that is no longer active`
+
+func keysCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(keysUsage)
+	}
+
+	switch args[0] {
+	case "list":
+		kf, err := aeskey.Keys()
+		if err != nil {
+			return err
+		}
+		for _, id := range kf.IDs() {
+			if id == kf.Active {
+				fmt.Println(id, "(active)")
+			} else {
+				fmt.Println(id)
+			}
+		}
+		return nil
+
+	case "rotate":
+		fs := flag.NewFlagSet("keys rotate", flag.ContinueOnError)
+		bits := fs.Int("bits", 128, "key size: 128, 192 or 256")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		id, err := aeskey.Rotate(*bits / 8)
+		if err != nil {
+			return err
+		}
+		fmt.Println("active key is now", id)
+		return nil
+
+	case "retire":
+		if len(args) != 2 {
+			return errors.New(keysUsage)
+		}
+		if err := aeskey.Retire(args[1]); err != nil {
+			return err
+		}
+		fmt.Println("retired key", args[1])
+		return nil
+	}
+
+	return errors.New(keysUsage)
+}
+
+const migrateUsage = `usage:
+  migrate status             show the applied and pending MySQL migrations
+  migrate up                 apply every pending migration
+  migrate down [n]           undo the newest n migrations (default 1)
+  migrate force <version>    record version as applied without running anything`
+
+func migrateCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	m, err := readwriter.OpenMigrator()
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	ctx := context.Background()
+
+	switch args[0] {
+	case "status":
+		applied, err := m.Applied(ctx)
+		if err != nil {
+			return err
+		}
+		done := make(map[int]readwriter.SchemaMigration, len(applied))
+		for _, a := range applied {
+			done[a.Version] = a
+		}
+		for _, mg := range m.Migrations() {
+			switch a, ok := done[mg.Version]; {
+			case !ok:
+				fmt.Println(mg, "pending")
+			case a.Dirty:
+				fmt.Println(mg, "FAILED at", a.AppliedAt)
+			default:
+				fmt.Println(mg, "applied at", a.AppliedAt)
+			}
+			delete(done, mg.Version)
+		}
+		for _, a := range applied {
+			if _, ok := done[a.Version]; ok {
+				fmt.Printf("%03d_%s applied at %s, unknown to this binary\n", a.Version, a.Name, a.AppliedAt)
+			}
+		}
+		return nil
+
+	case "up":
+		done, err := m.Up(ctx)
+		for _, mg := range done {
+			fmt.Println("applied", mg)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Println("already up to date")
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) == 2 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return errors.New(migrateUsage)
+			}
+		}
+		done, err := m.Down(ctx, steps)
+		for _, mg := range done {
+			fmt.Println("undid", mg)
+		}
+		return err
+
+	case "force":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return errors.New(migrateUsage)
+		}
+		if err := m.Force(ctx, version); err != nil {
+			return err
+		}
+		fmt.Println("recorded version", version)
+		return nil
+	}
+
+	return errors.New(migrateUsage)
+}
+
+const backupUsage = `usage:
+  backup create [label]   back up the configured storage now, labelled manual
+                          unless a label is given; old backups are kept
+  backup list             list the backups, newest first
+  backup prune            remove the backups the retention policy does not keep
+  backup preview <name> [-from id] [-to id]
+                          show what restoring the backup would change
+  backup restore <name> [-from id] [-to id]
+                          restore the packets with from <= ID <= to, or all of
+                          them, after backing up the current data`
+
+func backupCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(backupUsage)
+	}
+
+	switch args[0] {
+	case "create":
+		label := "manual"
+		if len(args) == 2 {
+			label = args[1]
+		}
+		defer readwriter.Close()
+		f, err := backup.Create(context.Background(), label)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("wrote %s (%d bytes)\n", f.Name, f.Size)
+		return nil
+
+	case "list":
+		files, err := backup.List(backup.Dir())
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			fmt.Printf("%s\t%s\t%d\n", f.Name, f.Time.Format(time.DateTime), f.Size)
+		}
+		return nil
+
+	case "prune":
+		conf := cfg.Get().Backup
+		removed, err := backup.Prune(backup.Dir(), conf.KeepDaily, conf.KeepWeekly, conf.KeepLabelled)
+		for _, f := range removed {
+			fmt.Println("removed", f.Name)
+		}
+		return err
+
+	case "preview", "restore":
+		if len(args) < 2 {
+			return errors.New(backupUsage)
+		}
+		fs := flag.NewFlagSet("backup "+args[0], flag.ContinueOnError)
+		from := fs.Int("from", 0, "first packet ID")
+		to := fs.Int("to", 0, "last packet ID, 0 for no limit")
+		if err := fs.Parse(args[2:]); err != nil {
+			return err
+		}
+		r := backup.Range{From: int32(*from), To: int32(*to)}
+		defer readwriter.Close()
+
+		if args[0] == "preview" {
+			data, diff, err := backup.Preview(context.Background(), args[1], r)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("backup holds %d packets and %d api keys\n", len(data.Packets), len(data.Keys))
+			printDiff(diff)
+			return nil
+		}
+		result, err := backup.Restore(context.Background(), args[1], r)
+		if err != nil {
+			return err
+		}
+		printDiff(&result.Diff)
+		fmt.Println("restored; to undo, restore", result.PreRestore)
+		return nil
+	}
+	return errors.New(backupUsage)
+}
+
+func printDiff(d *backup.Diff) {
+	fmt.Println("added:    ", d.Added)
+	fmt.Println("removed:  ", d.Removed)
+	fmt.Println("changed:  ", d.Changed)
+	fmt.Println("unchanged:", d.Unchanged)
+}
+
+const transferUsage = `usage:
+  transfer -from lfs -to mysql [-dry-run] [-overwrite]
+
+Copies every packet, keeping its ID, the recycle bin and the API keys from one
+storage to another, then checks the target against the source. New packets in
+the target continue the ID sequence and the revision of the source. Storages are lfs, journal,
+mysql and sqlite, configured as for the server. Stop the server first.`
+
+func transferCommand(args []string) error {
+	fs := flag.NewFlagSet("transfer", flag.ContinueOnError)
+	from := fs.String("from", "", "storage to read")
+	to := fs.String("to", "", "storage to write")
+	dryRun := fs.Bool("dry-run", false, "only report what would be copied")
+	overwrite := fs.Bool("overwrite", false, "replace the packets and recycle bin of a target that is not empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" || *to == "" {
+		return errors.New(transferUsage)
+	}
+	if *from == *to || (*from == "lfs" && *to == "journal") || (*from == "journal" && *to == "lfs") {
+		return errors.New("source and target are the same data")
+	}
+
+	src, err := readwriter.OpenStorage(*from)
+	if err != nil {
+		return err
+	}
+	defer closeStorage(*from, src)
+	dst, err := readwriter.OpenStorage(*to)
+	if err != nil {
+		return err
+	}
+	defer closeStorage(*to, dst)
+
+	report, err := readwriter.Transfer(context.Background(), src, dst, readwriter.TransferOptions{DryRun: *dryRun, Overwrite: *overwrite})
+	if report != nil {
+		fmt.Printf("source: %d packets, checksum %s\n", report.Packets, report.Checksum)
+		fmt.Printf("source: %d packets in the recycle bin, last id %d, revision %d\n", report.Trashed, report.LastID, report.Revision)
+		fmt.Printf("target: %d packets before the copy\n", report.TargetPackets)
+	}
+	if errors.Is(err, readwriter.ErrTargetNotEmpty) {
+		return errors.New("target is not empty, pass -overwrite to replace its packets and recycle bin")
+	}
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		fmt.Printf("dry run: would copy %d packets and %d api keys\n", report.Packets, report.Keys)
+		return nil
+	}
+	fmt.Printf("copied %d packets and %d api keys, target verified\n", report.Packets, report.Keys)
+	return nil
+}
+
+func closeStorage(name string, rw readwriter.ReadWriter) {
+	if err := readwriter.CloseStorage(rw); err != nil {
+		fmt.Fprintf(os.Stderr, "close %s: %v\n", name, err)
+	}
+}
@@ -12,15 +12,118 @@ type MySQLConfig struct {
 	MaxOpen            int    `json:"MaxOpen"`
 	MaxIdle            int    `json:"MaxIdle"`
 	ConnMaxLifetimeMin int    `json:"ConnMaxLifetimeMin"`
-	QueryCacheTTLms    int    `json:"QueryCacheTTLms"`
 	SlowQueryMs        int    `json:"SlowQueryMs"`
 	QueryTimeoutMs     int    `json:"QueryTimeoutMs"`
+	// SkipMigrations stops the server from applying db/migrations at startup;
+	// it then refuses to start until "packet_cloud migrate up" was run.
+	SkipMigrations bool `json:"SkipMigrations"`
+}
+
+// SQLiteConfig is used when StorageMedia is "sqlite".
+type SQLiteConfig struct {
+	Path string `json:"Path"`
+}
+
+// JournalConfig is used when StorageMedia is "journal": changes are appended
+// to <PacketsFilePath>.journal and folded into the packets file after
+// CompactRecords records or every CompactIntervalMin minutes.
+type JournalConfig struct {
+	CompactRecords     int `json:"CompactRecords"`
+	CompactIntervalMin int `json:"CompactIntervalMin"`
+}
+
+// BackupConfig controls the backups of the packets and API keys. Schedule is
+// a cron spec such as "0 3 * * *"; empty disables scheduled backups. Of the
+// scheduled backups in Dir the newest of each of the last KeepDaily days and
+// of each of the last KeepWeekly weeks are kept; both zero keeps every one.
+// Of the labelled backups, such as manual and pre-restore ones, the newest
+// KeepLabelled of each label are kept; zero keeps every one.
+type BackupConfig struct {
+	Dir          string `json:"Dir"`
+	Schedule     string `json:"Schedule"`
+	KeepDaily    int    `json:"KeepDaily"`
+	KeepWeekly   int    `json:"KeepWeekly"`
+	KeepLabelled int    `json:"KeepLabelled"`
+	// Gzip compresses new backup files.
+	Gzip bool `json:"Gzip"`
+}
+
+// PushConfig controls the event streams on /v1/packet/events and
+// /v1/packet/ws. HeartbeatSec is how often an idle stream sends a heartbeat,
+// which also notices clients that went away.
+type PushConfig struct {
+	HeartbeatSec int `json:"HeartbeatSec"`
+}
+
+// RecycleBinConfig controls the recycle bin deleted packets are moved to.
+// Packets are purged RetentionDays after they were deleted; 0 keeps them
+// until an admin purges them.
+type RecycleBinConfig struct {
+	RetentionDays int `json:"RetentionDays"`
+}
+
+// DeleteConfig controls /v1/packet/delete. A delete selecting more than
+// ConfirmAbove packets needs the confirmation token of a dry run; 0 never
+// asks for one.
+type DeleteConfig struct {
+	ConfirmAbove int `json:"ConfirmAbove"`
+}
+
+// AdminConfig holds the credentials for the admin page and the destructive
+// endpoints. Empty credentials disable the corresponding login method.
+type AdminConfig struct {
+	Username string `json:"Username"`
+	Password string `json:"Password"`
+	// Token is accepted as "Authorization: Bearer <Token>" for scripted access.
+	Token         string `json:"Token"`
+	SessionTTLMin int    `json:"SessionTTLMin"`
+	SecureCookie  bool   `json:"SecureCookie"`
+}
+
+// APIKeyConfig controls client authentication on the list, get and upload
+// endpoints. While Required is off, requests without a key are still served
+// with the identity they claim, so existing clients keep working.
+type APIKeyConfig struct {
+	Required bool `json:"Required"`
+}
+
+// SigningConfig controls HMAC request signing on the list, get and upload
+// endpoints. Secrets maps each user name to its signing secret.
+type SigningConfig struct {
+	Required bool `json:"Required"`
+	// MaxSkewSec is how far the request timestamp may be from the server clock.
+	MaxSkewSec int `json:"MaxSkewSec"`
+	// NonceTTLSec is how long a used nonce is remembered; it is raised to at
+	// least twice MaxSkewSec so a replay cannot outlive the cache.
+	NonceTTLSec int               `json:"NonceTTLSec"`
+	Secrets     map[string]string `json:"Secrets"`
+}
+
+// CryptoConfig lists the AES keys for packet payloads by key ID, as base64.
+// When KeyFile is set and exists it replaces ActiveKeyID and Keys; it is
+// the file the "keys rotate" command writes.
+type CryptoConfig struct {
+	ActiveKeyID string            `json:"ActiveKeyID"`
+	Keys        map[string]string `json:"Keys"`
+	KeyFile     string            `json:"KeyFile"`
+	// RequireEncryptedUploads rejects uploads sent as plaintext.
+	RequireEncryptedUploads bool `json:"RequireEncryptedUploads"`
 }
 
 type Config struct {
-	StorageMedia    string      `json:"StorageMedia"`
-	PacketsFilePath string      `json:"PacketsFilePath"`
-	MySQL           MySQLConfig `json:"MySQL"`
+	StorageMedia    string           `json:"StorageMedia"`
+	PacketsFilePath string           `json:"PacketsFilePath"`
+	MySQL           MySQLConfig      `json:"MySQL"`
+	SQLite          SQLiteConfig     `json:"SQLite"`
+	Journal         JournalConfig    `json:"Journal"`
+	Backup          BackupConfig     `json:"Backup"`
+	Push            PushConfig       `json:"Push"`
+	RecycleBin      RecycleBinConfig `json:"RecycleBin"`
+	Delete          DeleteConfig     `json:"Delete"`
+	Admin           AdminConfig      `json:"Admin"`
+	APIKeys         APIKeyConfig     `json:"APIKeys"`
+	Signing         SigningConfig    `json:"Signing"`
+	Crypto          CryptoConfig     `json:"Crypto"`
 }
 
 var (
@@ -37,6 +140,8 @@ func Load(path string) error {
 	if err := json.Unmarshal(b, &x); err != nil {
 		return err
 	}
+	// Mark the lazy default load as done so a later Get keeps this config.
+	once.Do(func() {})
 	c = &x
 	return nil
 }
@@ -46,15 +151,29 @@ func Get() *Config {
 		p := "config/config.json"
 		b, err := os.ReadFile(p)
 		if err != nil {
-			c = &Config{StorageMedia: "lfs", PacketsFilePath: "./packets", MySQL: MySQLConfig{MaxOpen: 20, MaxIdle: 10, ConnMaxLifetimeMin: 30, QueryCacheTTLms: 500, SlowQueryMs: 200, QueryTimeoutMs: 3000}}
+			c = defaultConfig()
 			return
 		}
 		var x Config
 		if err := json.Unmarshal(b, &x); err != nil {
-			c = &Config{StorageMedia: "lfs", PacketsFilePath: "./packets", MySQL: MySQLConfig{MaxOpen: 20, MaxIdle: 10, ConnMaxLifetimeMin: 30, QueryCacheTTLms: 500, SlowQueryMs: 200, QueryTimeoutMs: 3000}}
+			c = defaultConfig()
 			return
 		}
 		c = &x
 	})
 	return c
 }
+
+func defaultConfig() *Config {
+	return &Config{
+		StorageMedia:    "lfs",
+		PacketsFilePath: "./packets",
+		MySQL:           MySQLConfig{MaxOpen: 20, MaxIdle: 10, ConnMaxLifetimeMin: 30, SlowQueryMs: 200, QueryTimeoutMs: 3000},
+		Backup:          BackupConfig{Dir: "./backups", Schedule: "0 3 * * *", KeepDaily: 7, KeepWeekly: 4, KeepLabelled: 10, Gzip: true},
+		Push:            PushConfig{HeartbeatSec: 25},
+		RecycleBin:      RecycleBinConfig{RetentionDays: 30},
+		Delete:          DeleteConfig{ConfirmAbove: 20},
+		Admin:           AdminConfig{SessionTTLMin: 720},
+		Signing:         SigningConfig{MaxSkewSec: 300, NonceTTLSec: 600},
+	}
+}
@@ -0,0 +1,11 @@
+// Package db embeds the SQL migrations, so the binary can apply them without
+// the source tree.
+package db
+
+import "embed"
+
+// Migrations holds migrations/NNN_name.sql and, where a migration can be
+// undone, migrations/NNN_name.down.sql.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
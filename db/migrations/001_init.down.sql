@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `user_packets`;
+DROP TABLE IF EXISTS `cloud_packets`;
\ No newline at end of file
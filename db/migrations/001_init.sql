@@ -1,8 +1,3 @@
-START TRANSACTION;
-
-CREATE DATABASE IF NOT EXISTS `packet_cloud` CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci;
-USE `packet_cloud`;
-
 CREATE TABLE IF NOT EXISTS `cloud_packets` (
   `id` INT NOT NULL,
   `region` VARCHAR(32) NOT NULL,
@@ -28,6 +23,4 @@ CREATE TABLE IF NOT EXISTS `user_packets` (
   PRIMARY KEY (`id`),
   INDEX `idx_cloud_packet_id` (`cloud_packet_id`),
   CONSTRAINT `fk_user_packets_cloud_packet_id` FOREIGN KEY (`cloud_packet_id`) REFERENCES `cloud_packets`(`id`) ON DELETE CASCADE ON UPDATE CASCADE
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
-
-COMMIT;
\ No newline at end of file
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
\ No newline at end of file
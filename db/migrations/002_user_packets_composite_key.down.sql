@@ -0,0 +1,2 @@
+-- Fails if two cloud packets have user packets with the same position.
+ALTER TABLE `user_packets` DROP PRIMARY KEY, ADD PRIMARY KEY (`id`);
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- user_packets.id is the entry's position inside its cloud packet, so it is
+-- only unique together with cloud_packet_id. Tables created by AutoMigrate
+-- before migrations were tracked have an AUTO_INCREMENT id, which must lose
+-- it to leave the key; for tables from 001 the MODIFY changes nothing.
+ALTER TABLE `user_packets` MODIFY `id` INT NOT NULL, DROP PRIMARY KEY, ADD PRIMARY KEY (`cloud_packet_id`, `id`);
@@ -0,0 +1,3 @@
+SET FOREIGN_KEY_CHECKS = 0;
+ALTER TABLE `cloud_packets` MODIFY `id` INT NOT NULL;
+SET FOREIGN_KEY_CHECKS = 1;
@@ -0,0 +1,7 @@
+-- Packet IDs are allocated by the database so concurrent uploads cannot
+-- collide. The referenced column can only change with foreign key checks off;
+-- tables created by AutoMigrate name the foreign key differently, so it is not
+-- dropped by name. Tables that already count up are left as they are.
+SET FOREIGN_KEY_CHECKS = 0;
+ALTER TABLE `cloud_packets` MODIFY `id` INT NOT NULL AUTO_INCREMENT;
+SET FOREIGN_KEY_CHECKS = 1;
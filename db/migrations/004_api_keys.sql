@@ -0,0 +1,11 @@
+-- Client API keys. Only the SHA-256 hash of each key is stored.
+CREATE TABLE IF NOT EXISTS `api_keys` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `name` VARCHAR(64) NOT NULL,
+  `hash` CHAR(64) NOT NULL,
+  `scopes` VARCHAR(64) NOT NULL,
+  `created_at` VARCHAR(32) NOT NULL,
+  `revoked_at` VARCHAR(32) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `uk_hash` (`hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `revision_counter`;
+DROP TABLE IF EXISTS `packet_revisions`;
\ No newline at end of file
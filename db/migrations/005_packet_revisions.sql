@@ -0,0 +1,15 @@
+-- Revision numbers for delta sync. Every write bumps the single counter row
+-- and records the new revision for the packets it touched; deleted packets
+-- keep their row as a tombstone.
+CREATE TABLE IF NOT EXISTS `packet_revisions` (
+  `packet_id` INT NOT NULL,
+  `revision` BIGINT NOT NULL,
+  PRIMARY KEY (`packet_id`),
+  INDEX `idx_revision` (`revision`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE IF NOT EXISTS `revision_counter` (
+  `id` INT NOT NULL,
+  `revision` BIGINT NOT NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+INSERT INTO `revision_counter` (`id`, `revision`) VALUES (1, 0);
\ No newline at end of file
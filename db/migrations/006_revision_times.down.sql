@@ -0,0 +1,2 @@
+ALTER TABLE `revision_counter` DROP COLUMN `modified_at`;
+ALTER TABLE `packet_revisions` DROP COLUMN `modified_at`;
\ No newline at end of file
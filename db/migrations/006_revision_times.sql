@@ -0,0 +1,4 @@
+-- When each revision was made, for the Last-Modified header of the list and
+-- get endpoints. Rows written before this migration have 0, meaning unknown.
+ALTER TABLE `packet_revisions` ADD COLUMN `modified_at` BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE `revision_counter` ADD COLUMN `modified_at` BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,15 @@
+-- The recycle bin: deleted packets are kept here until they are restored or
+-- purged. User packets are stored as JSON, since the bin is only read whole.
+CREATE TABLE IF NOT EXISTS `deleted_packets` (
+  `id` INT NOT NULL,
+  `region` VARCHAR(32) NOT NULL,
+  `name` VARCHAR(64) NOT NULL,
+  `channel` VARCHAR(32) NOT NULL,
+  `uploader` VARCHAR(64) NOT NULL,
+  `time` VARCHAR(32) NOT NULL,
+  `user_packets` LONGTEXT NOT NULL,
+  `deleted_by` VARCHAR(64) NOT NULL,
+  `deleted_at` BIGINT NOT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,4 @@
+-- Client IDs are lost: user packets are numbered by position again.
+UPDATE `user_packets` SET `id` = `position`;
+ALTER TABLE `user_packets` DROP PRIMARY KEY, ADD PRIMARY KEY (`cloud_packet_id`, `id`);
+ALTER TABLE `user_packets` DROP COLUMN `position`;
@@ -0,0 +1,6 @@
+-- user_packets.id now keeps the ID the client sent, which need not be unique,
+-- so the entry's position inside its cloud packet becomes part of the key.
+-- Existing rows were numbered by position.
+ALTER TABLE `user_packets` ADD COLUMN `position` INT NOT NULL DEFAULT 0 AFTER `cloud_packet_id`;
+UPDATE `user_packets` SET `position` = `id`;
+ALTER TABLE `user_packets` DROP PRIMARY KEY, ADD PRIMARY KEY (`cloud_packet_id`, `position`);
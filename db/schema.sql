@@ -1,8 +1,10 @@
+-- Schema after migration 008_user_packet_client_ids, for reference. The server applies
+-- db/migrations itself; add a migration for every change and update this file.
 CREATE DATABASE IF NOT EXISTS `packet_cloud` CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci;
 USE `packet_cloud`;
 
 CREATE TABLE IF NOT EXISTS `cloud_packets` (
-  `id` INT NOT NULL,
+  `id` INT NOT NULL AUTO_INCREMENT,
   `region` VARCHAR(32) NOT NULL,
   `name` VARCHAR(64) NOT NULL,
   `channel` VARCHAR(32) NOT NULL,
@@ -19,11 +21,61 @@ CREATE TABLE IF NOT EXISTS `cloud_packets` (
 CREATE TABLE IF NOT EXISTS `user_packets` (
   `id` INT NOT NULL,
   `cloud_packet_id` INT NOT NULL,
+  `position` INT NOT NULL DEFAULT 0,
   `name` VARCHAR(64) NOT NULL,
   `content` LONGTEXT NOT NULL,
   `size` INT NOT NULL,
   `send_timing` VARCHAR(32) NOT NULL,
-  PRIMARY KEY (`id`),
+  PRIMARY KEY (`cloud_packet_id`, `position`),
   INDEX `idx_cloud_packet_id` (`cloud_packet_id`),
   CONSTRAINT `fk_user_packets_cloud_packet_id` FOREIGN KEY (`cloud_packet_id`) REFERENCES `cloud_packets`(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `api_keys` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `name` VARCHAR(64) NOT NULL,
+  `hash` CHAR(64) NOT NULL,
+  `scopes` VARCHAR(64) NOT NULL,
+  `created_at` VARCHAR(32) NOT NULL,
+  `revoked_at` VARCHAR(32) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `uk_hash` (`hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `packet_revisions` (
+  `packet_id` INT NOT NULL,
+  `revision` BIGINT NOT NULL,
+  `modified_at` BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (`packet_id`),
+  INDEX `idx_revision` (`revision`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `revision_counter` (
+  `id` INT NOT NULL,
+  `revision` BIGINT NOT NULL,
+  `modified_at` BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+INSERT IGNORE INTO `revision_counter` (`id`, `revision`) VALUES (1, 0);
+
+CREATE TABLE IF NOT EXISTS `deleted_packets` (
+  `id` INT NOT NULL,
+  `region` VARCHAR(32) NOT NULL,
+  `name` VARCHAR(64) NOT NULL,
+  `channel` VARCHAR(32) NOT NULL,
+  `uploader` VARCHAR(64) NOT NULL,
+  `time` VARCHAR(32) NOT NULL,
+  `user_packets` LONGTEXT NOT NULL,
+  `deleted_by` VARCHAR(64) NOT NULL,
+  `deleted_at` BIGINT NOT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `schema_migrations` (
+  `version` BIGINT NOT NULL,
+  `name` VARCHAR(255),
+  `dirty` BOOLEAN,
+  `applied_at` VARCHAR(32),
+  PRIMARY KEY (`version`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
\ No newline at end of file
@@ -3,10 +3,37 @@
 package main
 
 import (
+	"context"
+	"log"
+	"os"
+	"packet_cloud/service/auth"
+	"packet_cloud/service/backup"
+	"packet_cloud/service/push"
+	"packet_cloud/service/readwriter"
+	"packet_cloud/service/recyclebin"
+
 	"github.com/cloudwego/hertz/pkg/app/server"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
+	if err := readwriter.Open(context.Background()); err != nil {
+		log.Fatalf("[Storage] %v", err)
+	}
+
+	stopBackups, err := backup.Start()
+	if err != nil {
+		log.Fatalf("[Backup] %v", err)
+	}
+
+	stopPurges, err := recyclebin.Start()
+	if err != nil {
+		log.Fatalf("[RecycleBin] %v", err)
+	}
+
 	h := server.Default(
 		server.WithHostPorts(":8080"),
 	)
@@ -16,5 +43,19 @@ func main() {
 	h.StaticFile("favicon.ico", "./html/packet/favicon.ico")
 
 	register(h)
+
+	if !auth.Configured() {
+		log.Println("[Admin] no admin credentials configured, the admin page and destructive endpoints reject every request")
+	}
+
+	h.OnShutdown = append(h.OnShutdown, func(ctx context.Context) {
+		stopBackups()
+		stopPurges()
+		push.Close()
+		if err := readwriter.Close(); err != nil {
+			log.Println("[Storage] close error:", err)
+		}
+	})
+
 	h.Spin()
 }
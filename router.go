@@ -4,11 +4,35 @@ package main
 
 import (
 	"github.com/cloudwego/hertz/pkg/app/server"
+	"packet_cloud/biz/handler"
 	"packet_cloud/biz/handler/packet"
+	"packet_cloud/biz/mw"
+	"packet_cloud/service/auth"
 )
 
 // customizeRegister registers customize routers.
 func customizedRegister(r *server.Hertz) {
 	// your code ...
-	r.GET("/v1/packet/edit", packet.OnlineEdit)
+	r.GET("/v1/admin/login", packet.AdminLoginPage)
+	r.POST("/v1/admin/login", packet.AdminLogin)
+	r.POST("/v1/admin/logout", mw.AdminAuth(false), packet.AdminLogout)
+
+	r.GET("/v1/packet/edit", mw.AdminAuth(true), packet.OnlineEdit)
+	r.GET("/v1/packet/edit/:id", mw.AdminAuth(false), packet.OnlineEditPacket)
+
+	r.GET("/v1/admin/keys", mw.AdminAuth(false), packet.ListAPIKeys)
+	r.POST("/v1/admin/keys", mw.AdminAuth(false), packet.IssueAPIKey)
+	r.DELETE("/v1/admin/keys/:id", mw.AdminAuth(false), packet.RevokeAPIKey)
+
+	r.GET("/v1/admin/backups", mw.AdminAuth(false), packet.ListBackups)
+	r.POST("/v1/admin/backups", mw.AdminAuth(false), packet.CreateBackup)
+	r.GET("/v1/admin/backups/:name/preview", mw.AdminAuth(false), packet.PreviewBackup)
+	r.POST("/v1/admin/backups/:name/restore", mw.AdminAuth(false), packet.RestoreBackup)
+
+	r.GET("/v1/admin/recycle-bin", mw.AdminAuth(false), packet.ListRecycleBin)
+	r.POST("/v1/admin/recycle-bin/restore", mw.AdminAuth(false), packet.RestoreFromRecycleBin)
+	r.POST("/v1/admin/recycle-bin/purge", mw.AdminAuth(false), packet.PurgeRecycleBin)
+
+	r.GET("/v1/packet/events", mw.APIKeyAuth(auth.ScopeRead), mw.Signature(), handler.PacketEvents)
+	r.GET("/v1/packet/ws", mw.APIKeyAuth(auth.ScopeRead), mw.Signature(), handler.PacketWebSocket)
 }
@@ -0,0 +1,271 @@
+package aeskey
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	cfg "packet_cloud/config"
+	"packet_cloud/util"
+)
+
+// LegacyKeyID is the ID util.LegacyKey gets when no key is configured.
+const LegacyKeyID = "0"
+
+// Environment variables consulted when no key file exists. PACKET_AES_KEYS is
+// a comma separated list of id=base64 pairs.
+const (
+	EnvKeys   = "PACKET_AES_KEYS"
+	EnvActive = "PACKET_AES_ACTIVE_KEY"
+)
+
+// KeyFile is the format of Crypto.KeyFile. Keys maps key IDs to base64 keys.
+type KeyFile struct {
+	Active string            `json:"Active"`
+	Keys   map[string]string `json:"Keys"`
+}
+
+type ringVersion struct {
+	conf    *cfg.CryptoConfig
+	size    int64
+	modTime time.Time
+}
+
+var (
+	ringLock sync.Mutex
+	ring     *util.Keyring
+	ringKey  ringVersion
+)
+
+// Keyring returns the configured keys, reloading them when the key file changed.
+func Keyring() (*util.Keyring, error) {
+	conf := &cfg.Get().Crypto
+	version := ringVersion{conf: conf}
+	if conf.KeyFile != "" {
+		if st, err := os.Stat(conf.KeyFile); err == nil {
+			version.size = st.Size()
+			version.modTime = st.ModTime()
+		}
+	}
+
+	ringLock.Lock()
+	defer ringLock.Unlock()
+
+	if ring != nil && ringKey == version {
+		return ring, nil
+	}
+
+	kf, err := currentKeys(conf)
+	if err != nil {
+		return nil, err
+	}
+	r, err := kf.keyring()
+	if err != nil {
+		return nil, err
+	}
+	if r.Active() == LegacyKeyID && len(kf.Keys) == 1 {
+		log.Println("[AESKey] no AES key configured, falling back to the legacy built-in key; run \"keys rotate\" to set one")
+	}
+
+	ring, ringKey = r, version
+	return ring, nil
+}
+
+// Encrypt encrypts src with the active key; the result starts with its key ID.
+func Encrypt(src []byte) (string, error) {
+	r, err := Keyring()
+	if err != nil {
+		return "", err
+	}
+	return r.Encrypt(src)
+}
+
+// EncryptMode encrypts src with the active key in util.CipherCBC or util.CipherGCM mode.
+func EncryptMode(mode string, src []byte) (string, error) {
+	r, err := Keyring()
+	if err != nil {
+		return "", err
+	}
+	return r.EncryptMode(mode, src)
+}
+
+// Decrypt decrypts a ciphertext made with any configured key.
+func Decrypt(src string) ([]byte, error) {
+	r, err := Keyring()
+	if err != nil {
+		return nil, err
+	}
+	return r.Decrypt(src)
+}
+
+// currentKeys reads the keys from the key file, the environment or the
+// config, in that order, and falls back to the legacy key.
+func currentKeys(conf *cfg.CryptoConfig) (*KeyFile, error) {
+	if conf.KeyFile != "" {
+		kf, err := readKeyFile(conf.KeyFile)
+		if err == nil {
+			return kf, nil
+		}
+		if !os.IsNotExist(errors.Cause(err)) {
+			return nil, err
+		}
+	}
+
+	if env := os.Getenv(EnvKeys); env != "" {
+		kf := &KeyFile{Active: os.Getenv(EnvActive), Keys: make(map[string]string)}
+		for _, pair := range strings.Split(env, ",") {
+			id, key, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				return nil, errors.Errorf("%s: malformed entry %q", EnvKeys, pair)
+			}
+			kf.Keys[id] = key
+		}
+		return kf, nil
+	}
+
+	if len(conf.Keys) > 0 {
+		return &KeyFile{Active: conf.ActiveKeyID, Keys: conf.Keys}, nil
+	}
+
+	return &KeyFile{
+		Active: LegacyKeyID,
+		Keys:   map[string]string{LegacyKeyID: base64.StdEncoding.EncodeToString(util.LegacyKey)},
+	}, nil
+}
+
+func (kf *KeyFile) keyring() (*util.Keyring, error) {
+	keys := make(map[string][]byte, len(kf.Keys))
+	for id, encoded := range kf.Keys {
+		k, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode key %q", id)
+		}
+		keys[id] = k
+	}
+	return util.NewKeyring(kf.Active, keys)
+}
+
+// IDs returns the key IDs in order.
+func (kf *KeyFile) IDs() []string {
+	ids := make([]string, 0, len(kf.Keys))
+	for id := range kf.Keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func readKeyFile(path string) (*KeyFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var kf KeyFile
+	if err := json.Unmarshal(b, &kf); err != nil {
+		return nil, errors.Wrapf(err, "parse key file %s", path)
+	}
+	return &kf, nil
+}
+
+// writeKeyFile replaces the key file atomically so a running server never
+// reads a half written one.
+func writeKeyFile(path string, kf *KeyFile) error {
+	b, err := json.MarshalIndent(kf, "", "    ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Keys returns the keys currently in use, for listing.
+func Keys() (*KeyFile, error) {
+	return currentKeys(&cfg.Get().Crypto)
+}
+
+// Rotate generates a key of the given size in bytes, makes it the active key
+// and keeps every existing key for decryption. The keys are written to the key
+// file, seeded from the current keys the first time. It returns the new key ID.
+func Rotate(size int) (string, error) {
+	conf := &cfg.Get().Crypto
+	if conf.KeyFile == "" {
+		return "", errors.New("Crypto.KeyFile is not configured")
+	}
+	if size != 16 && size != 24 && size != 32 {
+		return "", errors.Errorf("invalid key size %d", size)
+	}
+
+	kf, err := currentKeys(conf)
+	if err != nil {
+		return "", err
+	}
+
+	key := make([]byte, size)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	id := time.Now().UTC().Format("20060102150405")
+	if _, ok := kf.Keys[id]; ok {
+		return "", errors.Errorf("key %s already exists, try again in a second", id)
+	}
+
+	next := &KeyFile{Active: id, Keys: map[string]string{id: base64.StdEncoding.EncodeToString(key)}}
+	for old, k := range kf.Keys {
+		next.Keys[old] = k
+	}
+	if _, err := next.keyring(); err != nil {
+		return "", err
+	}
+	if err := writeKeyFile(conf.KeyFile, next); err != nil {
+		return "", errors.Wrap(err, "write key file")
+	}
+	return id, nil
+}
+
+// Retire removes a key that is no longer active from the key file, once no
+// client uses it anymore.
+func Retire(id string) error {
+	conf := &cfg.Get().Crypto
+	if conf.KeyFile == "" {
+		return errors.New("Crypto.KeyFile is not configured")
+	}
+
+	kf, err := readKeyFile(conf.KeyFile)
+	if err != nil {
+		return err
+	}
+	if _, ok := kf.Keys[id]; !ok {
+		return errors.Errorf("key %s not found", id)
+	}
+	if kf.Active == id {
+		return errors.Errorf("key %s is active, rotate first", id)
+	}
+
+	delete(kf.Keys, id)
+	return writeKeyFile(conf.KeyFile, kf)
+}
@@ -0,0 +1,77 @@
+package aeskey
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cfg "packet_cloud/config"
+)
+
+func useCryptoConfig(t *testing.T, crypto cfg.CryptoConfig) {
+	t.Helper()
+	cp := filepath.Join(t.TempDir(), "config.json")
+	b, _ := json.Marshal(cfg.Config{StorageMedia: "lfs", Crypto: crypto})
+	_ = os.WriteFile(cp, b, 0644)
+	if err := cfg.Load(cp); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+}
+
+func TestLegacyFallback(t *testing.T) {
+	t.Setenv(EnvKeys, "")
+	useCryptoConfig(t, cfg.CryptoConfig{})
+
+	enc, err := Encrypt([]byte("hello"))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if !strings.HasPrefix(enc, LegacyKeyID+":") {
+		t.Fatalf("expected legacy key id: %s", enc)
+	}
+}
+
+func TestRotateAndRetire(t *testing.T) {
+	t.Setenv(EnvKeys, "")
+	useCryptoConfig(t, cfg.CryptoConfig{KeyFile: filepath.Join(t.TempDir(), "aes_keys.json")})
+
+	old, err := Encrypt([]byte("hello"))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+
+	id, err := Rotate(32)
+	if err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	enc, err := Encrypt([]byte("hello"))
+	if err != nil || !strings.HasPrefix(enc, id+":") {
+		t.Fatalf("not encrypted with the new key: %s, %v", enc, err)
+	}
+	if dec, err := Decrypt(old); err != nil || string(dec) != "hello" {
+		t.Fatalf("old ciphertext after rotation: %q, %v", dec, err)
+	}
+
+	if err := Retire(id); err == nil {
+		t.Fatal("retired the active key")
+	}
+	if err := Retire(LegacyKeyID); err != nil {
+		t.Fatalf("retire: %v", err)
+	}
+	if _, err := Decrypt(old); err == nil {
+		t.Fatal("retired key still decrypts")
+	}
+}
+
+func TestEnvKeys(t *testing.T) {
+	t.Setenv(EnvKeys, "k1=MDEyMzQ1Njc4OWFiY2RlZg==")
+	t.Setenv(EnvActive, "k1")
+	useCryptoConfig(t, cfg.CryptoConfig{})
+
+	enc, err := Encrypt([]byte("hello"))
+	if err != nil || !strings.HasPrefix(enc, "k1:") {
+		t.Fatalf("env key not used: %s, %v", enc, err)
+	}
+}
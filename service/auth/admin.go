@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"os"
+	"sync"
+	"time"
+
+	cfg "packet_cloud/config"
+)
+
+// Session is a logged-in admin. CSRF must accompany every state-changing
+// request made with the session cookie.
+type Session struct {
+	ID        string
+	Username  string
+	CSRF      string
+	ExpiresAt time.Time
+}
+
+var (
+	sessionsLock sync.Mutex
+	sessions     = make(map[string]*Session)
+)
+
+// adminCredentials returns the configured admin username, password and token,
+// falling back to the ADMIN_USERNAME, ADMIN_PASSWORD and ADMIN_TOKEN environment variables.
+func adminCredentials() (username, password, token string) {
+	admin := cfg.Get().Admin
+	username, password, token = admin.Username, admin.Password, admin.Token
+	if username == "" {
+		username = os.Getenv("ADMIN_USERNAME")
+	}
+	if password == "" {
+		password = os.Getenv("ADMIN_PASSWORD")
+	}
+	if token == "" {
+		token = os.Getenv("ADMIN_TOKEN")
+	}
+	return username, password, token
+}
+
+// Configured reports whether any admin login method is available.
+func Configured() bool {
+	username, password, token := adminCredentials()
+	return (username != "" && password != "") || token != ""
+}
+
+// CheckPassword reports whether username and password match the configured
+// admin. It always fails when no password is configured.
+func CheckPassword(username, password string) bool {
+	wantUser, wantPass, _ := adminCredentials()
+	if wantUser == "" || wantPass == "" {
+		return false
+	}
+	userOK := subtle.ConstantTimeCompare([]byte(username), []byte(wantUser)) == 1
+	passOK := subtle.ConstantTimeCompare([]byte(password), []byte(wantPass)) == 1
+	return userOK && passOK
+}
+
+// CheckToken reports whether token is the configured admin bearer token.
+func CheckToken(token string) bool {
+	_, _, want := adminCredentials()
+	if want == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1
+}
+
+// NewSession starts a session for username that expires after the configured TTL.
+func NewSession(username string) (*Session, error) {
+	id, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	csrf, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := time.Duration(cfg.Get().Admin.SessionTTLMin) * time.Minute
+	if ttl <= 0 {
+		ttl = 12 * time.Hour
+	}
+	s := &Session{ID: id, Username: username, CSRF: csrf, ExpiresAt: time.Now().Add(ttl)}
+
+	sessionsLock.Lock()
+	defer sessionsLock.Unlock()
+	dropExpiredSessions()
+	sessions[id] = s
+	return s, nil
+}
+
+// LookupSession returns the live session with the given ID, or nil.
+func LookupSession(id string) *Session {
+	if id == "" {
+		return nil
+	}
+
+	sessionsLock.Lock()
+	defer sessionsLock.Unlock()
+	s := sessions[id]
+	if s == nil {
+		return nil
+	}
+	if time.Now().After(s.ExpiresAt) {
+		delete(sessions, id)
+		return nil
+	}
+	return s
+}
+
+// DeleteSession ends the session with the given ID.
+func DeleteSession(id string) {
+	sessionsLock.Lock()
+	defer sessionsLock.Unlock()
+	delete(sessions, id)
+}
+
+// CheckCSRF reports whether token is the CSRF token of s.
+func (s *Session) CheckCSRF(token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.CSRF)) == 1
+}
+
+// dropExpiredSessions must be called with sessionsLock held.
+func dropExpiredSessions() {
+	now := time.Now()
+	for id, s := range sessions {
+		if now.After(s.ExpiresAt) {
+			delete(sessions, id)
+		}
+	}
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
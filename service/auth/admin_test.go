@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cfg "packet_cloud/config"
+)
+
+func useAdminConfig(t *testing.T, admin cfg.AdminConfig) {
+	t.Helper()
+	cp := filepath.Join(t.TempDir(), "config.json")
+	b, _ := json.Marshal(cfg.Config{StorageMedia: "lfs", Admin: admin})
+	_ = os.WriteFile(cp, b, 0644)
+	if err := cfg.Load(cp); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	useAdminConfig(t, cfg.AdminConfig{Username: "admin", Password: "secret"})
+
+	if !CheckPassword("admin", "secret") {
+		t.Fatal("valid credentials rejected")
+	}
+	if CheckPassword("admin", "wrong") || CheckPassword("root", "secret") {
+		t.Fatal("invalid credentials accepted")
+	}
+	if CheckToken("") || CheckToken("secret") {
+		t.Fatal("token accepted while none is configured")
+	}
+
+	useAdminConfig(t, cfg.AdminConfig{Username: "admin"})
+	if CheckPassword("admin", "") {
+		t.Fatal("empty password must never log in")
+	}
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	useAdminConfig(t, cfg.AdminConfig{Username: "admin", Password: "secret", SessionTTLMin: 1})
+
+	s, err := NewSession("admin")
+	if err != nil {
+		t.Fatalf("new session: %v", err)
+	}
+	got := LookupSession(s.ID)
+	if got == nil || got.Username != "admin" {
+		t.Fatalf("lookup: %+v", got)
+	}
+	if !got.CheckCSRF(s.CSRF) || got.CheckCSRF("") || got.CheckCSRF(s.ID) {
+		t.Fatal("csrf check mismatch")
+	}
+
+	DeleteSession(s.ID)
+	if LookupSession(s.ID) != nil {
+		t.Fatal("session still valid after delete")
+	}
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"packet_cloud/service/readwriter"
+)
+
+// Scopes an API key can be granted.
+const (
+	ScopeRead    = "read"
+	ScopeUpload  = "upload"
+	ScopeMUpload = "mupload"
+)
+
+// apiKeyPrefix marks issued keys so they are easy to recognise in configs and logs.
+const apiKeyPrefix = "pk_"
+
+var (
+	// ErrInvalidKey is returned for unknown and revoked keys.
+	ErrInvalidKey = errors.New("invalid api key")
+	// ErrScope is returned when a valid key lacks the requested scope.
+	ErrScope = errors.New("api key scope not granted")
+)
+
+// ValidScope reports whether scope is one of the known scopes.
+func ValidScope(scope string) bool {
+	return scope == ScopeRead || scope == ScopeUpload || scope == ScopeMUpload
+}
+
+// HasScope reports whether k was granted scope.
+func HasScope(k *readwriter.APIKey, scope string) bool {
+	for _, s := range strings.Split(k.Scopes, ",") {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IssueKey creates a key for the client name with the given scopes and returns
+// its secret, which is not stored and cannot be recovered later.
+func IssueKey(ctx context.Context, name string, scopes []string) (string, *readwriter.APIKey, error) {
+	if name == "" || len(scopes) == 0 {
+		return "", nil, errors.New("name and scopes are required")
+	}
+	for _, s := range scopes {
+		if !ValidScope(s) {
+			return "", nil, errors.Errorf("unknown scope %q", s)
+		}
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+	secret := apiKeyPrefix + token
+
+	k := &readwriter.APIKey{
+		Name:      name,
+		Hash:      hashKey(secret),
+		Scopes:    strings.Join(scopes, ","),
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+	if err := readwriter.InsertKey(ctx, k, readwriter.LFS); err != nil {
+		return "", nil, errors.Wrap(err, "insert api key")
+	}
+	return secret, k, nil
+}
+
+// Authenticate returns the active key matching secret if it was granted scope.
+func Authenticate(ctx context.Context, secret, scope string) (*readwriter.APIKey, error) {
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return nil, ErrInvalidKey
+	}
+
+	k, err := readwriter.FindKey(ctx, hashKey(secret), readwriter.LFS)
+	if errors.Is(err, readwriter.ErrNotFound) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "find api key")
+	}
+	if k.RevokedAt != "" {
+		return nil, ErrInvalidKey
+	}
+	if !HasScope(k, scope) {
+		return nil, ErrScope
+	}
+	return k, nil
+}
+
+func hashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	cfg "packet_cloud/config"
+	"packet_cloud/service/readwriter"
+)
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	ctx := context.Background()
+	useAdminConfig(t, cfg.AdminConfig{})
+	cfg.Get().PacketsFilePath = filepath.Join(t.TempDir(), "packets")
+
+	secret, k, err := IssueKey(ctx, "client-a", []string{ScopeRead, ScopeUpload})
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	if k.ID == 0 || k.Hash == secret {
+		t.Fatalf("unexpected key %+v", k)
+	}
+
+	got, err := Authenticate(ctx, secret, ScopeUpload)
+	if err != nil || got.Name != "client-a" {
+		t.Fatalf("authenticate: %+v, %v", got, err)
+	}
+	if _, err := Authenticate(ctx, secret, ScopeMUpload); !errors.Is(err, ErrScope) {
+		t.Fatalf("missing scope: %v", err)
+	}
+	if _, err := Authenticate(ctx, secret+"x", ScopeRead); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("wrong secret: %v", err)
+	}
+
+	if err := readwriter.RevokeKey(ctx, k.ID, readwriter.LFS); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if _, err := Authenticate(ctx, secret, ScopeRead); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("revoked key: %v", err)
+	}
+
+	if _, _, err := IssueKey(ctx, "client-b", []string{"admin"}); err == nil {
+		t.Fatal("unknown scope accepted")
+	}
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	cfg "packet_cloud/config"
+)
+
+// confirmationTTL is how long the token of a dry run stays valid.
+const confirmationTTL = 10 * time.Minute
+
+// deleteConfirmation lets an admin delete exactly the packets a dry run
+// showed them.
+type deleteConfirmation struct {
+	admin     string
+	digest    [sha256.Size]byte
+	expiresAt time.Time
+}
+
+var (
+	confirmationsLock sync.Mutex
+	confirmations     = make(map[string]*deleteConfirmation)
+)
+
+// ConfirmationRequired reports whether deleting n packets needs a
+// confirmation token.
+func ConfirmationRequired(n int) bool {
+	above := cfg.Get().Delete.ConfirmAbove
+	return above > 0 && n > above
+}
+
+// NewDeleteConfirmation returns a token with which admin may delete the
+// packets with the given IDs within confirmationTTL.
+func NewDeleteConfirmation(admin string, ids []int32) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	confirmationsLock.Lock()
+	defer confirmationsLock.Unlock()
+	now := time.Now()
+	for t, c := range confirmations {
+		if now.After(c.expiresAt) {
+			delete(confirmations, t)
+		}
+	}
+	confirmations[token] = &deleteConfirmation{admin: admin, digest: idsDigest(ids), expiresAt: now.Add(confirmationTTL)}
+	return token, nil
+}
+
+// ConfirmDelete reports whether token was issued to admin for exactly the
+// given IDs. A token is used up by its first check, whatever the outcome,
+// so a selection that changed since the dry run needs a new one.
+func ConfirmDelete(token, admin string, ids []int32) bool {
+	if token == "" {
+		return false
+	}
+
+	confirmationsLock.Lock()
+	c := confirmations[token]
+	delete(confirmations, token)
+	confirmationsLock.Unlock()
+
+	return c != nil && time.Now().Before(c.expiresAt) && c.admin == admin && c.digest == idsDigest(ids)
+}
+
+// idsDigest hashes ascending packet IDs.
+func idsDigest(ids []int32) [sha256.Size]byte {
+	b := make([]byte, 0, 4*len(ids))
+	for _, id := range ids {
+		b = binary.BigEndian.AppendUint32(b, uint32(id))
+	}
+	return sha256.Sum256(b)
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cfg "packet_cloud/config"
+)
+
+func TestDeleteConfirmation(t *testing.T) {
+	ids := []int32{1, 2, 3}
+
+	token, err := NewDeleteConfirmation("admin", ids)
+	if err != nil {
+		t.Fatalf("new confirmation: %v", err)
+	}
+	if !ConfirmDelete(token, "admin", ids) {
+		t.Fatal("valid confirmation rejected")
+	}
+	if ConfirmDelete(token, "admin", ids) {
+		t.Fatal("confirmation accepted twice")
+	}
+
+	for name, check := range map[string]func(token string) bool{
+		"other admin": func(token string) bool { return ConfirmDelete(token, "root", ids) },
+		"other ids":   func(token string) bool { return ConfirmDelete(token, "admin", []int32{1, 2, 3, 4}) },
+		"empty token": func(string) bool { return ConfirmDelete("", "admin", ids) },
+	} {
+		token, _ := NewDeleteConfirmation("admin", ids)
+		if check(token) {
+			t.Fatalf("%s: confirmation accepted", name)
+		}
+	}
+
+	token, _ = NewDeleteConfirmation("admin", ids)
+	confirmationsLock.Lock()
+	confirmations[token].expiresAt = time.Now().Add(-time.Second)
+	confirmationsLock.Unlock()
+	if ConfirmDelete(token, "admin", ids) {
+		t.Fatal("expired confirmation accepted")
+	}
+}
+
+func TestConfirmationRequired(t *testing.T) {
+	for _, c := range []struct {
+		above, n int
+		want     bool
+	}{{0, 1000, false}, {20, 20, false}, {20, 21, true}} {
+		cp := filepath.Join(t.TempDir(), "config.json")
+		b, _ := json.Marshal(cfg.Config{StorageMedia: "lfs", Delete: cfg.DeleteConfig{ConfirmAbove: c.above}})
+		_ = os.WriteFile(cp, b, 0644)
+		if err := cfg.Load(cp); err != nil {
+			t.Fatalf("load config: %v", err)
+		}
+		if got := ConfirmationRequired(c.n); got != c.want {
+			t.Fatalf("ConfirmAbove %d, %d packets: required %v, want %v", c.above, c.n, got, c.want)
+		}
+	}
+}
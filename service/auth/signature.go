@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	cfg "packet_cloud/config"
+	"packet_cloud/util"
+)
+
+var (
+	// ErrSignature is returned for unknown users and signatures that do not match.
+	ErrSignature = errors.New("invalid signature")
+	// ErrStaleRequest is returned when the timestamp is outside the allowed skew.
+	ErrStaleRequest = errors.New("request timestamp out of range")
+	// ErrReplay is returned when the nonce was already used.
+	ErrReplay = errors.New("nonce already used")
+)
+
+// SignedRequest is the signed part of a request plus the claimed signer.
+type SignedRequest struct {
+	User      string
+	Method    string
+	URI       string
+	Body      []byte
+	Timestamp int64
+	Nonce     string
+	Signature string
+}
+
+// nonceCache remembers the nonces seen within their TTL.
+type nonceCache struct {
+	lock      sync.Mutex
+	seen      map[string]time.Time
+	lastPurge time.Time
+}
+
+var nonces = &nonceCache{seen: make(map[string]time.Time)}
+
+// use records key and reports whether it was unused. Expired entries are purged
+// at most once per ttl so the check stays cheap.
+func (n *nonceCache) use(key string, now time.Time, ttl time.Duration) bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if now.Sub(n.lastPurge) > ttl {
+		for k, exp := range n.seen {
+			if now.After(exp) {
+				delete(n.seen, k)
+			}
+		}
+		n.lastPurge = now
+	}
+
+	if exp, ok := n.seen[key]; ok && !now.After(exp) {
+		return false
+	}
+	n.seen[key] = now.Add(ttl)
+	return true
+}
+
+// signingWindow returns the allowed clock skew and the nonce TTL from the config.
+func signingWindow() (skew, ttl time.Duration) {
+	conf := cfg.Get().Signing
+	skew = time.Duration(conf.MaxSkewSec) * time.Second
+	if skew <= 0 {
+		skew = 5 * time.Minute
+	}
+	ttl = time.Duration(conf.NonceTTLSec) * time.Second
+	if ttl < 2*skew {
+		ttl = 2 * skew
+	}
+	return skew, ttl
+}
+
+// VerifyRequest checks the signature of r with the secret of r.User, rejects
+// timestamps outside the allowed skew and nonces used before.
+func VerifyRequest(r *SignedRequest) error {
+	secret := cfg.Get().Signing.Secrets[r.User]
+	if r.User == "" || secret == "" || r.Nonce == "" {
+		return ErrSignature
+	}
+
+	now := time.Now()
+	skew, ttl := signingWindow()
+	ts := time.Unix(r.Timestamp, 0)
+	if ts.Before(now.Add(-skew)) || ts.After(now.Add(skew)) {
+		return ErrStaleRequest
+	}
+
+	if !util.VerifySignature(secret, r.Method, r.URI, r.Body, r.Timestamp, r.Nonce, r.Signature) {
+		return ErrSignature
+	}
+
+	// Only remember nonces of valid requests, so forged ones cannot burn them.
+	if !nonces.use(r.User+"\n"+r.Nonce, now, ttl) {
+		return ErrReplay
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	cfg "packet_cloud/config"
+	"packet_cloud/util"
+)
+
+func TestVerifyRequest(t *testing.T) {
+	useAdminConfig(t, cfg.AdminConfig{})
+	cfg.Get().Signing = cfg.SigningConfig{MaxSkewSec: 60, Secrets: map[string]string{"alice": "s3cret"}}
+
+	signed := func(user, nonce string, ts int64) *SignedRequest {
+		r := &SignedRequest{User: user, Method: "GET", URI: "/v1/packet/list?page=1", Timestamp: ts, Nonce: nonce}
+		r.Signature = util.SignRequest("s3cret", r.Method, r.URI, r.Body, r.Timestamp, r.Nonce)
+		return r
+	}
+	now := time.Now().Unix()
+
+	if err := VerifyRequest(signed("alice", "a", now)); err != nil {
+		t.Fatalf("valid request: %v", err)
+	}
+	if err := VerifyRequest(signed("alice", "a", now)); !errors.Is(err, ErrReplay) {
+		t.Fatalf("replayed nonce: %v", err)
+	}
+	if err := VerifyRequest(signed("alice", "b", now-120)); !errors.Is(err, ErrStaleRequest) {
+		t.Fatalf("stale timestamp: %v", err)
+	}
+	if err := VerifyRequest(signed("bob", "c", now)); !errors.Is(err, ErrSignature) {
+		t.Fatalf("unknown user: %v", err)
+	}
+
+	forged := signed("alice", "d", now)
+	forged.URI = "/v1/packet/list?page=2"
+	if err := VerifyRequest(forged); !errors.Is(err, ErrSignature) {
+		t.Fatalf("tampered uri: %v", err)
+	}
+	// The forged attempt must not have consumed the nonce.
+	if err := VerifyRequest(signed("alice", "d", now)); err != nil {
+		t.Fatalf("nonce burned by forged request: %v", err)
+	}
+}
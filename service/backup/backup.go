@@ -0,0 +1,332 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+
+	cfg "packet_cloud/config"
+	"packet_cloud/service/readwriter"
+)
+
+// timeLayout is the timestamp in backup file names, in local time.
+const timeLayout = "20060102-150405"
+
+// nameRe matches packets-<time>[-<label>][.<n>].json[.gz], where n tells
+// apart backups taken in the same second.
+var nameRe = regexp.MustCompile(`^packets-(\d{8}-\d{6})(?:-([a-z0-9-]+))?(?:\.(\d+))?\.json(\.gz)?$`)
+
+// labelRe restricts labels to what nameRe reads back.
+var labelRe = regexp.MustCompile(`^[a-z0-9-]*$`)
+
+// ErrNotFound is returned for a backup name that is not in the directory.
+var ErrNotFound = errors.New("backup not found")
+
+// File is one backup in the backup directory.
+type File struct {
+	Name string    `json:"name"`
+	Time time.Time `json:"time"`
+	// Label says why the backup was made, e.g. "manual"; scheduled backups have none.
+	Label string `json:"label"`
+	Size  int64  `json:"size"`
+
+	// n numbers the backups taken in the same second, from 1.
+	n int
+}
+
+// Dir is the configured backup directory.
+func Dir() string {
+	if dir := cfg.Get().Backup.Dir; dir != "" {
+		return dir
+	}
+	return "./backups"
+}
+
+// Create backs up the configured storage into Dir and returns the new
+// backup. It does not apply the retention policy, so a backup taken by hand
+// never costs an older one; scheduled backups prune afterwards.
+func Create(ctx context.Context, label string) (*File, error) {
+	data, err := readwriter.Backup(ctx, readwriter.LFS)
+	if err != nil {
+		return nil, err
+	}
+	return Write(Dir(), data, time.Now(), label, cfg.Get().Backup.Gzip)
+}
+
+// createScheduled takes a scheduled backup and applies the retention policy.
+func createScheduled(ctx context.Context) (*File, error) {
+	f, err := Create(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	conf := cfg.Get().Backup
+	removed, err := Prune(Dir(), conf.KeepDaily, conf.KeepWeekly, conf.KeepLabelled)
+	for _, old := range removed {
+		log.Printf("[Backup] removed %s", old.Name)
+	}
+	if err != nil {
+		log.Println("[Backup] prune error:", err)
+	}
+	return f, nil
+}
+
+// Write stores data in dir as a backup taken at the given time. It never
+// replaces an existing backup: a second backup in the same second gets a
+// number in its file name, and keeps its label.
+func Write(dir string, data *readwriter.BackupData, at time.Time, label string, compress bool) (*File, error) {
+	if !labelRe.MatchString(label) {
+		return nil, errors.Errorf("invalid backup label %q", label)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(dir, "packets.tmp*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := encode(tmp, data, compress); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	// Link fails when the name is taken, unlike Rename.
+	for n := 1; n <= 100; n++ {
+		f := File{Time: at.Truncate(time.Second), Label: label, n: n}
+		f.Name = fileName(f, compress)
+		path := filepath.Join(dir, f.Name)
+		err := os.Link(tmp.Name(), path)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "create backup")
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		f.Size = info.Size()
+		return &f, nil
+	}
+	return nil, errors.New("create backup: too many backups in one second")
+}
+
+func fileName(f File, compress bool) string {
+	name := "packets-" + f.Time.Format(timeLayout)
+	if f.Label != "" {
+		name += "-" + f.Label
+	}
+	if f.n > 1 {
+		name += "." + strconv.Itoa(f.n)
+	}
+	name += ".json"
+	if compress {
+		name += ".gz"
+	}
+	return name
+}
+
+func encode(w io.Writer, data *readwriter.BackupData, compress bool) error {
+	if !compress {
+		return json.NewEncoder(w).Encode(data)
+	}
+	zw := gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// List returns the backups in dir, newest first. A missing directory has none.
+func List(dir string) ([]File, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+	for _, e := range entries {
+		f, ok := parseName(e.Name())
+		if !ok || !e.Type().IsRegular() {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			f.Size = info.Size()
+		}
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].Time.Equal(files[j].Time) {
+			return files[i].Time.After(files[j].Time)
+		}
+		if files[i].n != files[j].n {
+			return files[i].n > files[j].n
+		}
+		return files[i].Name > files[j].Name
+	})
+	return files, nil
+}
+
+func parseName(name string) (File, bool) {
+	m := nameRe.FindStringSubmatch(name)
+	if m == nil {
+		return File{}, false
+	}
+	at, err := time.ParseInLocation(timeLayout, m[1], time.Local)
+	if err != nil {
+		return File{}, false
+	}
+	n := 1
+	if m[3] != "" {
+		if n, err = strconv.Atoi(m[3]); err != nil {
+			return File{}, false
+		}
+	}
+	return File{Name: name, Time: at, Label: m[2], n: n}, true
+}
+
+// Read loads the backup with the given name from dir.
+func Read(dir, name string) (*readwriter.BackupData, error) {
+	if _, ok := parseName(name); !ok {
+		return nil, ErrNotFound
+	}
+	f, err := os.Open(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if filepath.Ext(name) == ".gz" {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read backup %s", name)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	var data readwriter.BackupData
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, errors.Wrapf(err, "read backup %s", name)
+	}
+	return &data, nil
+}
+
+// Prune removes the backups in dir that the retention policy does not keep.
+// Of the scheduled backups it keeps the newest of each of the last keepDaily
+// days that have one, and of each of the last keepWeekly ISO weeks; the
+// newest is always kept and with both limits zero all are. Labelled backups,
+// such as manual and pre-restore ones, are counted per label: the newest
+// keepLabelled of each are kept, so the undo point of the last restore
+// survives, and with keepLabelled zero all are. It returns the removed
+// backups.
+func Prune(dir string, keepDaily, keepWeekly, keepLabelled int) ([]File, error) {
+	if keepDaily <= 0 && keepWeekly <= 0 && keepLabelled <= 0 {
+		return nil, nil
+	}
+	all, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := make(map[string]bool)
+	labelled := make(map[string]int)
+	var files []File
+	for _, f := range all {
+		if f.Label == "" {
+			files = append(files, f)
+			continue
+		}
+		labelled[f.Label]++
+		if keepLabelled <= 0 || labelled[f.Label] <= keepLabelled {
+			keep[f.Name] = true
+		}
+	}
+	if len(files) > 0 {
+		keep[files[0].Name] = true
+	}
+	if keepDaily <= 0 && keepWeekly <= 0 {
+		for _, f := range files {
+			keep[f.Name] = true
+		}
+	}
+
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	for _, f := range files {
+		day := f.Time.Format("2006-01-02")
+		if !days[day] && len(days) < keepDaily {
+			days[day] = true
+			keep[f.Name] = true
+		}
+		year, week := f.Time.ISOWeek()
+		wk := fmt.Sprintf("%d-%02d", year, week)
+		if !weeks[wk] && len(weeks) < keepWeekly {
+			weeks[wk] = true
+			keep[f.Name] = true
+		}
+	}
+
+	var removed []File
+	for _, f := range all {
+		if keep[f.Name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, f.Name)); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed = append(removed, f)
+	}
+	return removed, nil
+}
+
+// Start schedules Create according to Backup.Schedule and returns a function
+// that stops the schedule, waiting for a running backup. An empty schedule
+// starts nothing.
+func Start() (stop func(), err error) {
+	spec := cfg.Get().Backup.Schedule
+	if spec == "" {
+		return func() {}, nil
+	}
+
+	c := cron.New()
+	_, err = c.AddFunc(spec, func() {
+		f, err := createScheduled(context.Background())
+		if err != nil {
+			log.Println("[Backup] !!! scheduled backup failed:", err)
+			return
+		}
+		log.Printf("[Backup] wrote %s (%d bytes)", f.Name, f.Size)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid Backup.Schedule %q", spec)
+	}
+	c.Start()
+	return func() { <-c.Stop().Done() }, nil
+}
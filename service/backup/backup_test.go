@@ -0,0 +1,187 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	packet "packet_cloud/biz/model/hertz/packet"
+	cfg "packet_cloud/config"
+	"packet_cloud/service/readwriter"
+)
+
+// useBackupConfig points the LFS storage and the backup directory into a
+// temporary directory and returns the backup directory.
+func useBackupConfig(t *testing.T, conf cfg.BackupConfig) string {
+	t.Helper()
+	dir := t.TempDir()
+	conf.Dir = filepath.Join(dir, "backups")
+	cp := filepath.Join(dir, "config.json")
+	b, _ := json.Marshal(cfg.Config{StorageMedia: "lfs", PacketsFilePath: filepath.Join(dir, "packets"), Backup: conf})
+	_ = os.WriteFile(cp, b, 0644)
+	if err := cfg.Load(cp); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	t.Cleanup(func() { _ = readwriter.Close() })
+	return conf.Dir
+}
+
+func TestWriteAndRead(t *testing.T) {
+	dir := t.TempDir()
+	data := &readwriter.BackupData{
+		Packets: []*packet.CloudPacket{{Id: 3, Name: "a", UserPackets: []*packet.UserPacket{{Id: 1, Name: "u"}}}},
+		Keys:    []*readwriter.APIKey{{ID: 1, Name: "ci", Hash: "h"}},
+	}
+	at := time.Date(2024, 5, 6, 7, 8, 9, 0, time.Local)
+
+	for _, compress := range []bool{false, true} {
+		f, err := Write(dir, data, at, map[bool]string{false: "", true: "manual"}[compress], compress)
+		if err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		got, err := Read(dir, f.Name)
+		if err != nil {
+			t.Fatalf("read %s: %v", f.Name, err)
+		}
+		if len(got.Packets) != 1 || got.Packets[0].Id != 3 || got.Packets[0].UserPackets[0].Name != "u" || len(got.Keys) != 1 {
+			t.Fatalf("%s does not round trip: %+v", f.Name, got)
+		}
+	}
+
+	files, err := List(dir)
+	if err != nil || len(files) != 2 {
+		t.Fatalf("list: %v %+v", err, files)
+	}
+	gz := files[0]
+	if gz.Label == "" {
+		gz = files[1]
+	}
+	if gz.Name != "packets-20240506-070809-manual.json.gz" || gz.Label != "manual" || !gz.Time.Equal(at) {
+		t.Fatalf("unexpected listing: %+v", files)
+	}
+
+	again, err := Write(dir, data, at, "manual", true)
+	if err != nil || again.Name != "packets-20240506-070809-manual.2.json.gz" || again.Label != "manual" {
+		t.Fatalf("second backup in the same second: %v %+v", err, again)
+	}
+	if files, _ := List(dir); len(files) != 3 || files[0].Name != again.Name || files[0].Label != "manual" {
+		t.Fatalf("unexpected listing: %+v", files)
+	}
+	if _, err := Read(dir, "../packets"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	data := &readwriter.BackupData{}
+	// Two backups a day for three weeks, ending on Sunday 2024-05-26.
+	start := time.Date(2024, 5, 6, 3, 0, 0, 0, time.Local)
+	for d := 0; d < 21; d++ {
+		for _, h := range []int{0, 12} {
+			if _, err := Write(dir, data, start.AddDate(0, 0, d).Add(time.Duration(h)*time.Hour), "", false); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+		}
+	}
+
+	// Labelled backups are kept by count per label, however old.
+	for _, l := range []struct {
+		at    time.Time
+		label string
+	}{
+		{start.Add(time.Hour), "pre-restore"},
+		{start.AddDate(0, 0, 19).Add(14 * time.Hour), "manual"},
+		{start.AddDate(0, 0, 20).Add(14 * time.Hour), "manual"},
+		{start.AddDate(0, 0, 20).Add(14 * time.Hour), "manual"},
+	} {
+		if _, err := Write(dir, data, l.at, l.label, false); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	if _, err := Prune(dir, 3, 2, 2); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	files, _ := List(dir)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	want := []string{
+		"packets-20240526-170000-manual.2.json",
+		"packets-20240526-170000-manual.json",
+		"packets-20240526-150000.json", // newest of 05-26 and of week 21
+		"packets-20240525-150000.json",
+		"packets-20240524-150000.json",
+		"packets-20240519-150000.json", // newest of week 20
+		"packets-20240506-040000-pre-restore.json",
+	}
+	if len(names) != len(want) {
+		t.Fatalf("kept %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("kept %v, want %v", names, want)
+		}
+	}
+
+	if removed, _ := Prune(dir, 0, 0, 0); len(removed) != 0 {
+		t.Fatalf("zero limits removed %d backups", len(removed))
+	}
+	// Only labelled backups are capped; scheduled ones are all kept.
+	removed, err := Prune(dir, 0, 0, 1)
+	if err != nil || len(removed) != 1 || removed[0].Name != "packets-20240526-170000-manual.json" {
+		t.Fatalf("labelled cap removed %v: %v", removed, err)
+	}
+}
+
+func TestCreateKeepsLiveData(t *testing.T) {
+	ctx := context.Background()
+	dir := useBackupConfig(t, cfg.BackupConfig{Gzip: true, KeepDaily: 1})
+
+	if err := readwriter.Insert(ctx, []*packet.CloudPacket{{Name: "a"}, {Name: "b"}}, readwriter.LFS); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	f, err := Create(ctx, "manual")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	data, err := Read(dir, f.Name)
+	if err != nil || len(data.Packets) != 2 {
+		t.Fatalf("backup content: %v %+v", err, data)
+	}
+	if live, _ := readwriter.ReadPacket(ctx, readwriter.LFS); len(live) != 2 {
+		t.Fatalf("live data changed by the backup: %+v", live)
+	}
+}
+
+func TestOnlyScheduledBackupsPrune(t *testing.T) {
+	ctx := context.Background()
+	dir := useBackupConfig(t, cfg.BackupConfig{KeepDaily: 1})
+	earlier, err := Write(dir, &readwriter.BackupData{}, time.Now().Add(-time.Minute), "", false)
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if _, err := Create(ctx, "manual"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if files, _ := List(dir); len(files) != 2 {
+		t.Fatalf("a manual backup removed an older one: %+v", files)
+	}
+
+	if _, err := createScheduled(ctx); err != nil {
+		t.Fatalf("scheduled backup: %v", err)
+	}
+	files, _ := List(dir)
+	for _, f := range files {
+		if f.Name == earlier.Name {
+			t.Fatalf("scheduled backup did not prune: %+v", files)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	packet "packet_cloud/biz/model/hertz/packet"
+	"packet_cloud/service/readwriter"
+)
+
+// PreRestoreLabel marks the backup Restore takes before changing anything.
+const PreRestoreLabel = "pre-restore"
+
+// Range selects packets by ID, inclusively. To 0 has no upper bound, so the
+// zero Range selects every packet.
+type Range struct {
+	From int32 `json:"from"`
+	To   int32 `json:"to"`
+}
+
+// Contains reports whether id is in r.
+func (r Range) Contains(id int32) bool {
+	return id >= r.From && (r.To <= 0 || id <= r.To)
+}
+
+// Diff describes what restoring a backup would change in the selected range.
+type Diff struct {
+	// Added are in the backup only; restoring brings them back.
+	Added []int32 `json:"added"`
+	// Removed are current packets the backup does not have; restoring deletes them.
+	Removed []int32 `json:"removed"`
+	// Changed differ between the backup and the current data.
+	Changed   []int32 `json:"changed"`
+	Unchanged int     `json:"unchanged"`
+}
+
+// Empty reports whether restoring would change nothing.
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Compare returns the Diff of restoring the packets of backup in r over current.
+func Compare(current, backup []*packet.CloudPacket, r Range) *Diff {
+	d := &Diff{Added: []int32{}, Removed: []int32{}, Changed: []int32{}}
+	now := make(map[int32]*packet.CloudPacket, len(current))
+	for _, p := range current {
+		if r.Contains(p.Id) {
+			now[p.Id] = p
+		}
+	}
+	for _, p := range backup {
+		if !r.Contains(p.Id) {
+			continue
+		}
+		cur, ok := now[p.Id]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, p.Id)
+		case samePacket(cur, p):
+			d.Unchanged++
+		default:
+			d.Changed = append(d.Changed, p.Id)
+		}
+		delete(now, p.Id)
+	}
+	for id := range now {
+		d.Removed = append(d.Removed, id)
+	}
+	sortIDs(d.Added)
+	sortIDs(d.Removed)
+	sortIDs(d.Changed)
+	return d
+}
+
+func samePacket(a, b *packet.CloudPacket) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+func sortIDs(ids []int32) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+}
+
+// Preview reads the backup with the given name and compares it to the
+// configured storage without changing anything.
+func Preview(ctx context.Context, name string, r Range) (*readwriter.BackupData, *Diff, error) {
+	data, err := Read(Dir(), name)
+	if err != nil {
+		return nil, nil, err
+	}
+	current, err := readwriter.ReadPacket(ctx, readwriter.LFS)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, Compare(current, data.Packets, r), nil
+}
+
+// RestoreResult reports a restore.
+type RestoreResult struct {
+	Diff
+	// PreRestore is the backup of the data before the restore; restoring it
+	// undoes the restore.
+	PreRestore string `json:"pre_restore"`
+}
+
+// Restore makes the packets in r of the configured storage equal to those of
+// the backup with the given name, leaving packets outside r alone. API keys
+// are not restored, so revoked keys stay revoked. It first backs up the
+// current data under PreRestoreLabel; nothing is changed if that fails. Writes
+// wait until the restore is done, so none of them is lost.
+func Restore(ctx context.Context, name string, r Range) (*RestoreResult, error) {
+	data, err := Read(Dir(), name)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *RestoreResult
+	err = readwriter.Replace(ctx, func(current []*packet.CloudPacket) ([]*packet.CloudPacket, error) {
+		pre, err := Create(ctx, PreRestoreLabel)
+		if err != nil {
+			return nil, errors.Wrap(err, "pre-restore backup")
+		}
+		log.Printf("[Backup] saved the current data as %s before restoring %s", pre.Name, name)
+
+		result = &RestoreResult{Diff: *Compare(current, data.Packets, r), PreRestore: pre.Name}
+		if result.Empty() {
+			return nil, nil
+		}
+
+		restored := make([]*packet.CloudPacket, 0, len(current)+len(result.Added))
+		for _, p := range current {
+			if !r.Contains(p.Id) {
+				restored = append(restored, p)
+			}
+		}
+		for _, p := range data.Packets {
+			if r.Contains(p.Id) {
+				restored = append(restored, p)
+			}
+		}
+		sort.Slice(restored, func(i, j int) bool { return restored[i].Id < restored[j].Id })
+		return restored, nil
+	}, readwriter.LFS)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
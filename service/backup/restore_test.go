@@ -0,0 +1,124 @@
+package backup
+
+import (
+	"context"
+	"testing"
+
+	packet "packet_cloud/biz/model/hertz/packet"
+	cfg "packet_cloud/config"
+	"packet_cloud/service/readwriter"
+)
+
+func TestCompare(t *testing.T) {
+	current := []*packet.CloudPacket{{Id: 1, Name: "a"}, {Id: 2, Name: "b2"}, {Id: 4, Name: "d"}, {Id: 9, Name: "z"}}
+	backup := []*packet.CloudPacket{{Id: 1, Name: "a"}, {Id: 2, Name: "b"}, {Id: 3, Name: "c"}, {Id: 8, Name: "y"}}
+
+	d := Compare(current, backup, Range{From: 1, To: 4})
+	if len(d.Added) != 1 || d.Added[0] != 3 || len(d.Removed) != 1 || d.Removed[0] != 4 ||
+		len(d.Changed) != 1 || d.Changed[0] != 2 || d.Unchanged != 1 {
+		t.Fatalf("unexpected diff: %+v", d)
+	}
+
+	all := Compare(current, backup, Range{})
+	if len(all.Added) != 2 || len(all.Removed) != 2 {
+		t.Fatalf("unexpected diff of everything: %+v", all)
+	}
+}
+
+func TestRestoreRangeAndUndo(t *testing.T) {
+	ctx := context.Background()
+	dir := useBackupConfig(t, cfg.BackupConfig{})
+
+	if err := readwriter.Insert(ctx, []*packet.CloudPacket{{Name: "a"}, {Name: "b"}, {Name: "c"}}, readwriter.LFS); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	old, err := Create(ctx, "manual")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	renamed := "changed"
+	for _, id := range []int32{1, 3} {
+		if _, err := readwriter.Patch(ctx, id, readwriter.PatchFields{Name: &renamed}, readwriter.LFS); err != nil {
+			t.Fatalf("patch: %v", err)
+		}
+	}
+	if _, err := readwriter.Trash(ctx, readwriter.Selector{IDs: []int32{2}}, "admin", readwriter.LFS); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+
+	_, diff, err := Preview(ctx, old.Name, Range{From: 1, To: 2})
+	if err != nil || len(diff.Added) != 1 || len(diff.Changed) != 1 {
+		t.Fatalf("preview: %v %+v", err, diff)
+	}
+	if files, _ := List(dir); len(files) != 1 {
+		t.Fatalf("preview wrote a backup: %+v", files)
+	}
+
+	result, err := Restore(ctx, old.Name, Range{From: 1, To: 2})
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	got, _ := readwriter.ReadPacket(ctx, readwriter.LFS)
+	if len(got) != 3 || got[0].Name != "a" || got[1].Name != "b" || got[2].Name != "changed" {
+		t.Fatalf("unexpected data after restore: %+v", got)
+	}
+
+	// Restoring the pre-restore backup undoes the restore.
+	if _, err := Restore(ctx, result.PreRestore, Range{}); err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	got, _ = readwriter.ReadPacket(ctx, readwriter.LFS)
+	if len(got) != 2 || got[0].Name != "changed" || got[1].Id != 3 {
+		t.Fatalf("unexpected data after undo: %+v", got)
+	}
+}
+
+func TestRestoreTwiceKeepsUndoPoints(t *testing.T) {
+	ctx := context.Background()
+	dir := useBackupConfig(t, cfg.BackupConfig{KeepDaily: 1, KeepWeekly: 1})
+
+	if err := readwriter.Insert(ctx, []*packet.CloudPacket{{Name: "a"}}, readwriter.LFS); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	old, err := Create(ctx, "manual")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	renamed := "changed"
+	if _, err := readwriter.Patch(ctx, 1, readwriter.PatchFields{Name: &renamed}, readwriter.LFS); err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+
+	first, err := Restore(ctx, old.Name, Range{})
+	if err != nil {
+		t.Fatalf("first restore: %v", err)
+	}
+	second, err := Restore(ctx, first.PreRestore, Range{})
+	if err != nil {
+		t.Fatalf("second restore: %v", err)
+	}
+	// A scheduled backup applies the retention policy afterwards.
+	if _, err := createScheduled(ctx); err != nil {
+		t.Fatalf("scheduled backup: %v", err)
+	}
+
+	kept := make(map[string]bool)
+	files, _ := List(dir)
+	for _, f := range files {
+		kept[f.Name] = true
+	}
+	for _, name := range []string{old.Name, first.PreRestore, second.PreRestore} {
+		if !kept[name] {
+			t.Fatalf("%s was pruned, left %+v", name, files)
+		}
+	}
+}
+
+func TestRestoreMissingBackup(t *testing.T) {
+	useBackupConfig(t, cfg.BackupConfig{})
+
+	if _, err := Restore(context.Background(), "packets-20240101-000000.json", Range{}); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
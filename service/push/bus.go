@@ -0,0 +1,119 @@
+package push
+
+import "sync"
+
+// Kind is the type of a Message.
+type Kind string
+
+const (
+	Upload Kind = "upload"
+	Update Kind = "update"
+	Delete Kind = "delete"
+	// Ready is the first message of a stream that did not resume.
+	Ready Kind = "ready"
+	// Reset tells the client its revision is unknown, e.g. after the data was
+	// replaced, and it has to sync everything with /v1/packet/changes.
+	Reset     Kind = "reset"
+	Heartbeat Kind = "heartbeat"
+)
+
+// Event announces a write the storage accepted. It only names the packets;
+// streams read them and the revision from the storage, so an event that is
+// dropped or arrives late never loses a change.
+type Event struct {
+	Kind Kind
+	IDs  []int32
+}
+
+// subscriptionBuffer is how many events a subscriber may fall behind before
+// Publish drops events for it.
+const subscriptionBuffer = 64
+
+// Bus fans events out to its subscribers. Publish never blocks: a subscriber
+// with a full buffer misses the event, but the events still buffered wake it
+// and the storage tells it what changed.
+type Bus struct {
+	lock   sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// Subscription receives the events published after Subscribe on C. C is
+// closed when the subscription or the bus is closed.
+type Subscription struct {
+	C   <-chan Event
+	c   chan Event
+	bus *Bus
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{})}
+}
+
+func (b *Bus) Subscribe() *Subscription {
+	c := make(chan Event, subscriptionBuffer)
+	s := &Subscription{C: c, c: c, bus: b}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		close(c)
+		return s
+	}
+	b.subs[s] = struct{}{}
+	return s
+}
+
+func (b *Bus) Publish(e Event) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for s := range b.subs {
+		select {
+		case s.c <- e:
+		default:
+		}
+	}
+}
+
+// Close closes every subscription; later ones are closed right away.
+func (b *Bus) Close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for s := range b.subs {
+		close(s.c)
+	}
+	b.subs = nil
+}
+
+func (s *Subscription) Close() {
+	s.bus.lock.Lock()
+	defer s.bus.lock.Unlock()
+	if _, ok := s.bus.subs[s]; ok {
+		delete(s.bus.subs, s)
+		close(s.c)
+	}
+}
+
+// bus is the bus the handlers publish their writes to.
+var bus = NewBus()
+
+// Publish announces that the packets with the given IDs were written.
+func Publish(kind Kind, ids []int32) {
+	if len(ids) == 0 {
+		return
+	}
+	bus.Publish(Event{Kind: kind, IDs: ids})
+}
+
+func Subscribe() *Subscription {
+	return bus.Subscribe()
+}
+
+// Close ends every stream, on shutdown.
+func Close() {
+	bus.Close()
+}
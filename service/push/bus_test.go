@@ -0,0 +1,49 @@
+package push
+
+import "testing"
+
+func TestBusFanOut(t *testing.T) {
+	b := NewBus()
+	s1, s2 := b.Subscribe(), b.Subscribe()
+
+	b.Publish(Event{Kind: Upload, IDs: []int32{1}})
+	for _, s := range []*Subscription{s1, s2} {
+		if e := <-s.C; e.Kind != Upload || e.IDs[0] != 1 {
+			t.Fatalf("unexpected event: %+v", e)
+		}
+	}
+
+	s1.Close()
+	s1.Close()
+	if _, ok := <-s1.C; ok {
+		t.Fatal("closed subscription still receives events")
+	}
+	b.Publish(Event{Kind: Delete, IDs: []int32{2}})
+	if e := <-s2.C; e.Kind != Delete {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+}
+
+func TestBusPublishDoesNotBlock(t *testing.T) {
+	b := NewBus()
+	s := b.Subscribe()
+	for i := 0; i < subscriptionBuffer*2; i++ {
+		b.Publish(Event{Kind: Update, IDs: []int32{int32(i)}})
+	}
+	if len(s.C) != subscriptionBuffer {
+		t.Fatalf("buffered %d events, want %d", len(s.C), subscriptionBuffer)
+	}
+}
+
+func TestBusClose(t *testing.T) {
+	b := NewBus()
+	s := b.Subscribe()
+	b.Close()
+	if _, ok := <-s.C; ok {
+		t.Fatal("subscription open after the bus closed")
+	}
+	s.Close()
+	if _, ok := <-b.Subscribe().C; ok {
+		t.Fatal("subscription to a closed bus is open")
+	}
+}
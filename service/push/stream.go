@@ -0,0 +1,180 @@
+package push
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	packet "packet_cloud/biz/model/hertz/packet"
+	cfg "packet_cloud/config"
+	"packet_cloud/service/readwriter"
+)
+
+// Message is what a stream sends to its client.
+type Message struct {
+	// Type tells uploads from updates by the events the handlers published; a
+	// packet read before its event arrived is reported as updated.
+	Type Kind `json:"type"`
+	// Revision is set on the last message of each batch of changes and on
+	// Ready, Reset and Heartbeat; it is 0 elsewhere. A client that reconnects
+	// with the last revision it received gets everything it missed.
+	Revision int64 `json:"revision"`
+	// Packet is an uploaded or updated packet without its user packets.
+	Packet *packet.CloudPacket `json:"packet,omitempty"`
+	// ID is the ID of a deleted packet.
+	ID int32 `json:"id,omitempty"`
+}
+
+// Filter selects packets by region and channel. An empty list matches
+// everything. Deletes are not filtered, since a deleted packet no longer has
+// a region; clients ignore IDs they do not know.
+type Filter struct {
+	Regions  []string
+	Channels []string
+}
+
+// ParseFilter builds a Filter from comma separated regions and channels.
+func ParseFilter(regions, channels string) Filter {
+	return Filter{Regions: splitList(regions), Channels: splitList(channels)}
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func (f Filter) Match(p *packet.CloudPacket) bool {
+	return matchList(f.Regions, p.Region) && matchList(f.Channels, p.Channel)
+}
+
+func matchList(list []string, v string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, l := range list {
+		if l == v {
+			return true
+		}
+	}
+	return false
+}
+
+// HeartbeatInterval returns how often idle streams send a heartbeat.
+func HeartbeatInterval() time.Duration {
+	if sec := cfg.Get().Push.HeartbeatSec; sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	return 25 * time.Second
+}
+
+// Follow streams the changes matching f to send until ctx is done, the bus
+// is closed or send fails. With since > 0 it first sends what changed after
+// since; otherwise it starts with a Ready message holding the current
+// revision.
+func Follow(ctx context.Context, since int64, f Filter, heartbeat time.Duration, send func(*Message) error) error {
+	// Subscribe before reading the revision so no write falls in between.
+	sub := Subscribe()
+	defer sub.Close()
+
+	last := since
+	if since > 0 {
+		rev, err := sendChanges(ctx, since, f, nil, send)
+		if err != nil {
+			return err
+		}
+		last = rev
+	} else {
+		rev, err := readwriter.Revision(ctx, readwriter.LFS)
+		if err != nil {
+			return err
+		}
+		if err := send(&Message{Type: Ready, Revision: rev}); err != nil {
+			return err
+		}
+		last = rev
+	}
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := send(&Message{Type: Heartbeat, Revision: last}); err != nil {
+				return err
+			}
+		case e, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			// Take every pending event, so a burst of writes costs one read.
+			kinds := make(map[int32]Kind)
+			for ok {
+				for _, id := range e.IDs {
+					if _, seen := kinds[id]; !seen {
+						kinds[id] = e.Kind
+					}
+				}
+				select {
+				case e, ok = <-sub.C:
+					if !ok {
+						return nil
+					}
+				default:
+					ok = false
+				}
+			}
+
+			rev, err := sendChanges(ctx, last, f, kinds, send)
+			if err != nil {
+				return err
+			}
+			last = rev
+		}
+	}
+}
+
+// sendChanges sends the changes after since and returns the revision they
+// lead to. since may be 0 when the storage had not recorded a write yet.
+// kinds holds the kinds of the published events by packet ID; a packet
+// without one is reported as updated.
+func sendChanges(ctx context.Context, since int64, f Filter, kinds map[int32]Kind, send func(*Message) error) (int64, error) {
+	cs, err := readwriter.ChangesAfter(ctx, since, readwriter.LFS)
+	if err != nil {
+		return since, err
+	}
+	if cs.Reset {
+		return cs.Revision, send(&Message{Type: Reset, Revision: cs.Revision})
+	}
+
+	var messages []*Message
+	for _, p := range cs.Packets {
+		if !f.Match(p) {
+			continue
+		}
+		kind := kinds[p.Id]
+		if kind != Upload {
+			kind = Update
+		}
+		messages = append(messages, &Message{Type: kind, Packet: p})
+	}
+	for _, id := range cs.Deleted {
+		messages = append(messages, &Message{Type: Delete, ID: id})
+	}
+
+	for i, m := range messages {
+		if i == len(messages)-1 {
+			m.Revision = cs.Revision
+		}
+		if err := send(m); err != nil {
+			return since, err
+		}
+	}
+	return cs.Revision, nil
+}
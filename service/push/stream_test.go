@@ -0,0 +1,119 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	packet "packet_cloud/biz/model/hertz/packet"
+	cfg "packet_cloud/config"
+	"packet_cloud/service/readwriter"
+)
+
+// usePushConfig points the LFS storage into a temporary directory.
+func usePushConfig(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	cp := filepath.Join(dir, "config.json")
+	b, _ := json.Marshal(cfg.Config{StorageMedia: "lfs", PacketsFilePath: filepath.Join(dir, "packets")})
+	_ = os.WriteFile(cp, b, 0644)
+	if err := cfg.Load(cp); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	t.Cleanup(func() { _ = readwriter.Close() })
+}
+
+// follow runs Follow in the background and returns its messages.
+func follow(t *testing.T, since int64, f Filter) <-chan *Message {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	messages := make(chan *Message, 16)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = Follow(ctx, since, f, time.Hour, func(m *Message) error {
+			messages <- m
+			return nil
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return messages
+}
+
+func next(t *testing.T, messages <-chan *Message) *Message {
+	t.Helper()
+	select {
+	case m := <-messages:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message")
+		return nil
+	}
+}
+
+func insert(t *testing.T, packets ...*packet.CloudPacket) {
+	t.Helper()
+	if err := readwriter.Insert(context.Background(), packets, readwriter.LFS); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	ids := make([]int32, len(packets))
+	for i, p := range packets {
+		ids[i] = p.Id
+	}
+	Publish(Upload, ids)
+}
+
+func TestFollowFiltersAndResumes(t *testing.T) {
+	usePushConfig(t)
+	ctx := context.Background()
+
+	insert(t, &packet.CloudPacket{Name: "a", Region: "r2"})
+	messages := follow(t, 0, ParseFilter("r1", ""))
+	if m := next(t, messages); m.Type != Ready || m.Revision != 1 {
+		t.Fatalf("unexpected first message: %+v", m)
+	}
+
+	insert(t, &packet.CloudPacket{Name: "b", Region: "r1"})
+	m := next(t, messages)
+	if m.Type != Upload || m.Packet.Name != "b" || m.Revision != 2 {
+		t.Fatalf("unexpected upload: %+v", m)
+	}
+
+	renamed := "b2"
+	if _, err := readwriter.Patch(ctx, m.Packet.Id, readwriter.PatchFields{Name: &renamed}, readwriter.LFS); err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	Publish(Update, []int32{m.Packet.Id})
+	if m := next(t, messages); m.Type != Update || m.Packet.Name != "b2" || m.Revision != 3 {
+		t.Fatalf("unexpected update: %+v", m)
+	}
+
+	ids, err := readwriter.Trash(ctx, readwriter.Selector{IDs: []int32{1}}, "admin", readwriter.LFS)
+	if err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+	Publish(Delete, ids)
+	if m := next(t, messages); m.Type != Delete || m.ID != 1 || m.Revision != 4 {
+		t.Fatalf("unexpected delete: %+v", m)
+	}
+
+	// A client that saw revision 2 gets the update and the delete.
+	resumed := follow(t, 2, Filter{})
+	if m := next(t, resumed); m.Type != Update || m.Packet.Id != 2 || m.Revision != 0 {
+		t.Fatalf("unexpected resumed update: %+v", m)
+	}
+	if m := next(t, resumed); m.Type != Delete || m.ID != 1 || m.Revision != 4 {
+		t.Fatalf("unexpected resumed delete: %+v", m)
+	}
+
+	// A revision from before the data was replaced asks for a full sync.
+	if m := next(t, follow(t, 99, Filter{})); m.Type != Reset || m.Revision != 4 {
+		t.Fatalf("unexpected reset: %+v", m)
+	}
+}
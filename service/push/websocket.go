@@ -0,0 +1,177 @@
+package push
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/pkg/errors"
+)
+
+// websocketGUID is appended to the client key to compute the accept key,
+// see RFC 6455 section 1.3.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xa
+)
+
+// maxFramePayload bounds the frames read from clients, which only send
+// control frames to the stream.
+const maxFramePayload = 64 << 10
+
+// writeTimeout bounds each frame write, so a client that stopped reading
+// does not hold the stream forever.
+const writeTimeout = 10 * time.Second
+
+var errFrameTooLarge = errors.New("websocket frame too large")
+
+// AcceptKey returns the Sec-WebSocket-Accept value for a Sec-WebSocket-Key.
+func AcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// writeFrame writes a single unmasked frame, as servers must.
+func writeFrame(w io.Writer, op byte, payload []byte) error {
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | op
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	if _, err := w.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readFrame reads a frame and unmasks its payload.
+func readFrame(r io.Reader) (op byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	op = header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+
+	n := uint64(header[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > maxFramePayload {
+		return 0, nil, errFrameTooLarge
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(r, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return op, payload, nil
+}
+
+// wsConn serializes the frames written by the stream and the replies to
+// the client's control frames.
+type wsConn struct {
+	conn net.Conn
+	lock sync.Mutex
+}
+
+func (c *wsConn) write(op byte, payload []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return writeFrame(c.conn, op, payload)
+}
+
+// closePayload builds the payload of a close frame.
+func closePayload(code uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, code)
+}
+
+// ServeWebSocket runs Follow over a connection that completed the WebSocket
+// handshake, sending each message as a text frame. It answers pings and
+// returns when the client closes the connection or the stream ends.
+func ServeWebSocket(conn net.Conn, since int64, f Filter, heartbeat time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ws := &wsConn{conn: conn}
+
+	go func() {
+		defer cancel()
+		for {
+			op, payload, err := readFrame(conn)
+			if err != nil {
+				if errors.Is(err, errFrameTooLarge) {
+					_ = ws.write(opClose, closePayload(1009))
+				}
+				return
+			}
+			switch op {
+			case opPing:
+				if ws.write(opPong, payload) != nil {
+					return
+				}
+			case opClose:
+				_ = ws.write(opClose, payload)
+				return
+			}
+		}
+	}()
+
+	err := Follow(ctx, since, f, heartbeat, func(m *Message) error {
+		b, err := sonic.Marshal(m)
+		if err != nil {
+			return err
+		}
+		return ws.write(opText, b)
+	})
+	switch {
+	case ctx.Err() != nil:
+		// The client closed the connection.
+	case err != nil:
+		log.Println("[Push] websocket stream error:", err)
+		_ = ws.write(opClose, closePayload(1011))
+	default:
+		// The server is shutting down.
+		_ = ws.write(opClose, closePayload(1001))
+	}
+}
@@ -0,0 +1,42 @@
+package push
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// The example of RFC 6455 section 1.3.
+	if got := AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 5, 125, 126, 70000} {
+		var buf bytes.Buffer
+		payload := bytes.Repeat([]byte{'x'}, n)
+		if err := writeFrame(&buf, opText, payload); err != nil {
+			t.Fatalf("write %d: %v", n, err)
+		}
+		op, got, err := readFrame(&buf)
+		if n > maxFramePayload {
+			if err != errFrameTooLarge {
+				t.Fatalf("expected errFrameTooLarge for %d bytes, got %v", n, err)
+			}
+			continue
+		}
+		if err != nil || op != opText || !bytes.Equal(got, payload) {
+			t.Fatalf("read %d: op=%d err=%v", n, op, err)
+		}
+	}
+}
+
+func TestReadMaskedFrame(t *testing.T) {
+	// A masked "Hello" text frame from RFC 6455 section 5.7.
+	frame := []byte{0x81, 0x85, 0x37, 0xfa, 0x21, 0x3d, 0x7f, 0x9f, 0x4d, 0x51, 0x58}
+	op, payload, err := readFrame(bytes.NewReader(frame))
+	if err != nil || op != opText || string(payload) != "Hello" {
+		t.Fatalf("op=%d payload=%q err=%v", op, payload, err)
+	}
+}
@@ -1,9 +1,11 @@
 package readwriter
 
 import (
+    "context"
     "github.com/pkg/errors"
     "packet_cloud/biz/model/hertz/packet"
     cfg "packet_cloud/config"
+    "strings"
 )
 
 type StorageMedia int
@@ -11,36 +13,177 @@ type StorageMedia int
 const (
 	LFS StorageMedia = iota
 	MySQL
+	SQLite
+	Journal
 )
 
+// ErrNotFound is returned by Get and Update when no packet has the requested ID.
+var ErrNotFound = errors.New("packet not found")
+
+// Sort keys accepted by Filter.SortBy.
+const (
+	SortByID   = "id"
+	SortByTime = "time"
+)
+
+// Filter narrows, orders and pages the packets returned by List. Empty fields
+// match everything.
+type Filter struct {
+	Region   string
+	Channel  string
+	Uploader string
+	// Name matches packets whose name contains it.
+	Name string
+	// TimeFrom and TimeTo bound the packet time inclusively; times are compared
+	// as strings, which orders the "2006-01-02" format clients send.
+	TimeFrom string
+	TimeTo   string
+
+	// SortBy is SortByID (the default) or SortByTime; ties are broken by ID.
+	SortBy string
+	Desc   bool
+	// Offset skips that many matches; Limit 0 returns all remaining ones.
+	Offset int
+	Limit  int
+
+	// Summary leaves UserPackets empty, for listings that only show metadata.
+	Summary bool
+}
+
+// Match reports whether p satisfies every non-empty condition of f.
+func (f Filter) Match(p *packet.CloudPacket) bool {
+	if f.Region != "" && p.Region != f.Region {
+		return false
+	}
+	if f.Channel != "" && p.Channel != f.Channel {
+		return false
+	}
+	if f.Uploader != "" && p.Uploader != f.Uploader {
+		return false
+	}
+	if f.Name != "" && !strings.Contains(p.Name, f.Name) {
+		return false
+	}
+	if f.TimeFrom != "" && p.Time < f.TimeFrom {
+		return false
+	}
+	if f.TimeTo != "" && p.Time > f.TimeTo {
+		return false
+	}
+	return true
+}
+
+// PatchFields lists the fields Patch changes. Nil fields are left untouched;
+// UserPackets replaces the whole list, and an empty one clears it.
+type PatchFields struct {
+	Name        *string
+	Region      *string
+	Channel     *string
+	UserPackets *[]*packet.UserPacket
+}
+
+// Apply copies the set fields of f onto p.
+func (f PatchFields) Apply(p *packet.CloudPacket) {
+	if f.Name != nil {
+		p.Name = *f.Name
+	}
+	if f.Region != nil {
+		p.Region = *f.Region
+	}
+	if f.Channel != nil {
+		p.Channel = *f.Channel
+	}
+	if f.UserPackets != nil {
+		p.UserPackets = append(make([]*packet.UserPacket, 0, len(*f.UserPackets)), *f.UserPackets...)
+	}
+}
+
+// ReadWriter is a packet storage. Packets returned by its methods belong to the
+// caller, which may modify them without affecting what is stored. Backends that
+// talk to a database stop waiting when ctx is done.
 type ReadWriter interface {
-    ReadPacket() ([]*packet.CloudPacket, error)
-    SavePacket([]*packet.CloudPacket) error
-    Backup() error
+    // ReadPacket and SavePacket load and replace the whole dataset.
+    ReadPacket(ctx context.Context) ([]*packet.CloudPacket, error)
+    SavePacket(ctx context.Context, packets []*packet.CloudPacket) error
+
+    // Get returns the packet with the given ID or ErrNotFound.
+    Get(ctx context.Context, id int32) (*packet.CloudPacket, error)
+    // Insert stores new packets, assigning each of them a fresh ID in place.
+    Insert(ctx context.Context, packets []*packet.CloudPacket) error
+    // Update replaces the stored packet with the same ID or returns ErrNotFound.
+    Update(ctx context.Context, p *packet.CloudPacket) error
+    // Patch applies fields to the stored packet and returns the result, or ErrNotFound.
+    Patch(ctx context.Context, id int32, fields PatchFields) (*packet.CloudPacket, error)
+    // List returns one page of the packets matching filter and the total number of matches.
+    List(ctx context.Context, filter Filter) ([]*packet.CloudPacket, int64, error)
+
+    // Backup returns a consistent copy of the packets and API keys.
+    Backup(ctx context.Context) (*BackupData, error)
+}
+
+// BackupData is everything a storage holds, as written to backup files.
+type BackupData struct {
+    Packets []*packet.CloudPacket `json:"packets"`
+    Keys    []*APIKey             `json:"keys"`
+}
+
+// ParseStorageMedia maps a StorageMedia config value to its StorageMedia.
+func ParseStorageMedia(name string) (StorageMedia, bool) {
+	switch name {
+	case "lfs":
+		return LFS, true
+	case "mysql", "rds":
+		return MySQL, true
+	case "sqlite":
+		return SQLite, true
+	case "journal":
+		return Journal, true
+	}
+	return LFS, false
 }
 
 func newReadWriter(media StorageMedia) ReadWriter {
-	sm := cfg.Get().StorageMedia
-	if sm == "mysql" || sm == "rds" {
-		return NewMySQLStorageFromConfig()
+	if m, ok := ParseStorageMedia(cfg.Get().StorageMedia); ok && m != LFS {
+		media = m
 	}
+	if s := indexedStorage(media, func() ReadWriter { return openBackend(media) }); s != nil {
+		return s
+	}
+	return nil
+}
+
+// openBackend connects to the storage of media, or returns nil.
+func openBackend(media StorageMedia) ReadWriter {
 	switch media {
 	case LFS:
 		return &LocalFileSystem{}
 	case MySQL:
-		return NewMySQLStorageFromConfig()
+		if s := NewMySQLStorageFromConfig(); s != nil {
+			return s
+		}
+		return nil
+	case SQLite:
+		if s := NewSQLiteStorageFromConfig(); s != nil {
+			return s
+		}
+		return nil
+	case Journal:
+		if s := NewJournalFileSystemFromConfig(); s != nil {
+			return s
+		}
+		return nil
 	default:
 		return &LocalFileSystem{}
 	}
 }
 
-func ReadPacket(media StorageMedia) ([]*packet.CloudPacket, error) {
+func ReadPacket(ctx context.Context, media StorageMedia) ([]*packet.CloudPacket, error) {
 	rw := newReadWriter(media)
 	if rw == nil {
 		return nil, errors.New("readWriter is nil")
 	}
 
-	packets, err := rw.ReadPacket()
+	packets, err := rw.ReadPacket(ctx)
 	if err != nil {
 		return nil, errors.Wrapf(err, "read packet error")
 	}
@@ -48,16 +191,117 @@ func ReadPacket(media StorageMedia) ([]*packet.CloudPacket, error) {
 	return packets, nil
 }
 
-func SavePacket(packets []*packet.CloudPacket, media StorageMedia) error {
+func SavePacket(ctx context.Context, packets []*packet.CloudPacket, media StorageMedia) error {
 	rw := newReadWriter(media)
 	if rw == nil {
 		return errors.New("readWriter is nil")
 	}
 
-	err := rw.SavePacket(packets)
+	err := rw.SavePacket(ctx, packets)
 	if err != nil {
 		return errors.Wrapf(err, "save packet error")
 	}
 
 	return nil
 }
+
+func Replace(ctx context.Context, merge func(current []*packet.CloudPacket) ([]*packet.CloudPacket, error), media StorageMedia) error {
+	rw := newReadWriter(media)
+	if rw == nil {
+		return errors.New("readWriter is nil")
+	}
+	ix, ok := rw.(*IndexedStorage)
+	if !ok {
+		return errors.New("storage cannot replace its data atomically")
+	}
+
+	if err := ix.Replace(ctx, merge); err != nil {
+		return errors.Wrapf(err, "replace packets error")
+	}
+
+	return nil
+}
+
+func Get(ctx context.Context, id int32, media StorageMedia) (*packet.CloudPacket, error) {
+	rw := newReadWriter(media)
+	if rw == nil {
+		return nil, errors.New("readWriter is nil")
+	}
+
+	p, err := rw.Get(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get packet %d error", id)
+	}
+
+	return p, nil
+}
+
+func Insert(ctx context.Context, packets []*packet.CloudPacket, media StorageMedia) error {
+	rw := newReadWriter(media)
+	if rw == nil {
+		return errors.New("readWriter is nil")
+	}
+
+	err := rw.Insert(ctx, packets)
+	if err != nil {
+		return errors.Wrapf(err, "insert packet error")
+	}
+
+	return nil
+}
+
+func Update(ctx context.Context, p *packet.CloudPacket, media StorageMedia) error {
+	rw := newReadWriter(media)
+	if rw == nil {
+		return errors.New("readWriter is nil")
+	}
+
+	err := rw.Update(ctx, p)
+	if err != nil {
+		return errors.Wrapf(err, "update packet %d error", p.Id)
+	}
+
+	return nil
+}
+
+func Patch(ctx context.Context, id int32, fields PatchFields, media StorageMedia) (*packet.CloudPacket, error) {
+	rw := newReadWriter(media)
+	if rw == nil {
+		return nil, errors.New("readWriter is nil")
+	}
+
+	p, err := rw.Patch(ctx, id, fields)
+	if err != nil {
+		return nil, errors.Wrapf(err, "patch packet %d error", id)
+	}
+
+	return p, nil
+}
+
+func List(ctx context.Context, filter Filter, media StorageMedia) ([]*packet.CloudPacket, int64, error) {
+	rw := newReadWriter(media)
+	if rw == nil {
+		return nil, 0, errors.New("readWriter is nil")
+	}
+
+	packets, total, err := rw.List(ctx, filter)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "list packet error")
+	}
+
+	return packets, total, nil
+}
+
+func Backup(ctx context.Context, media StorageMedia) (*BackupData, error) {
+	rw := newReadWriter(media)
+	if rw == nil {
+		return nil, errors.New("readWriter is nil")
+	}
+
+	data, err := rw.Backup(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "backup error")
+	}
+
+	return data, nil
+}
@@ -0,0 +1,247 @@
+package readwriter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"gorm.io/gorm"
+
+	cfg "packet_cloud/config"
+)
+
+// APIKey is a client credential. Only the SHA-256 hash of the secret is
+// stored; the secret itself is shown once when the key is issued.
+type APIKey struct {
+	ID   int32  `json:"id"`
+	Name string `json:"name"`
+	Hash string `json:"hash"`
+	// Scopes is a comma separated list such as "read,upload".
+	Scopes    string `json:"scopes"`
+	CreatedAt string `json:"created_at"`
+	// RevokedAt is empty while the key is active.
+	RevokedAt string `json:"revoked_at"`
+}
+
+// KeyStore persists API keys next to the packets of the same backend.
+type KeyStore interface {
+	ListKeys(ctx context.Context) ([]*APIKey, error)
+	// FindKey returns the key with the given hash, revoked or not, or ErrNotFound.
+	FindKey(ctx context.Context, hash string) (*APIKey, error)
+	// InsertKey stores k and assigns it a fresh ID in place.
+	InsertKey(ctx context.Context, k *APIKey) error
+	// RevokeKey marks the key as revoked or returns ErrNotFound.
+	RevokeKey(ctx context.Context, id int32) error
+}
+
+func newKeyStore(media StorageMedia) KeyStore {
+	rw := newReadWriter(media)
+	if rw == nil {
+		return nil
+	}
+	if ix, ok := rw.(*IndexedStorage); ok {
+		rw = ix.Backend()
+	}
+	ks, _ := rw.(KeyStore)
+	return ks
+}
+
+func ListKeys(ctx context.Context, media StorageMedia) ([]*APIKey, error) {
+	ks := newKeyStore(media)
+	if ks == nil {
+		return nil, errors.New("keyStore is nil")
+	}
+	return ks.ListKeys(ctx)
+}
+
+func FindKey(ctx context.Context, hash string, media StorageMedia) (*APIKey, error) {
+	ks := newKeyStore(media)
+	if ks == nil {
+		return nil, errors.New("keyStore is nil")
+	}
+	return ks.FindKey(ctx, hash)
+}
+
+func InsertKey(ctx context.Context, k *APIKey, media StorageMedia) error {
+	ks := newKeyStore(media)
+	if ks == nil {
+		return errors.New("keyStore is nil")
+	}
+	return ks.InsertKey(ctx, k)
+}
+
+func RevokeKey(ctx context.Context, id int32, media StorageMedia) error {
+	ks := newKeyStore(media)
+	if ks == nil {
+		return errors.New("keyStore is nil")
+	}
+	return ks.RevokeKey(ctx, id)
+}
+
+// keysFilePath is where LFS keeps the API keys.
+func keysFilePath() string {
+	return cfg.Get().PacketsFilePath + ".keys"
+}
+
+// readKeysFile loads the API keys. A missing file means no keys. Callers must hold syncLock.
+func readKeysFile() ([]*APIKey, error) {
+	keys := make([]*APIKey, 0)
+
+	bytes, err := os.ReadFile(keysFilePath())
+	if os.IsNotExist(err) {
+		return keys, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := sonic.Unmarshal(bytes, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// writeKeysFile overwrites the API keys file. Callers must hold syncLock for writing.
+func writeKeysFile(keys []*APIKey) error {
+	bytes, err := sonic.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(keysFilePath(), bytes, 0600)
+}
+
+func (s *LocalFileSystem) ListKeys(ctx context.Context) ([]*APIKey, error) {
+	syncLock.RLock()
+	defer syncLock.RUnlock()
+
+	return readKeysFile()
+}
+
+func (s *LocalFileSystem) FindKey(ctx context.Context, hash string) (*APIKey, error) {
+	keys, err := s.ListKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, k := range keys {
+		if k.Hash == hash {
+			return k, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *LocalFileSystem) InsertKey(ctx context.Context, k *APIKey) error {
+	syncLock.Lock()
+	defer syncLock.Unlock()
+
+	keys, err := readKeysFile()
+	if err != nil {
+		return err
+	}
+
+	k.ID = 1
+	for _, old := range keys {
+		if old.ID >= k.ID {
+			k.ID = old.ID + 1
+		}
+	}
+	return writeKeysFile(append(keys, k))
+}
+
+func (s *LocalFileSystem) RevokeKey(ctx context.Context, id int32) error {
+	syncLock.Lock()
+	defer syncLock.Unlock()
+
+	keys, err := readKeysFile()
+	if err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if k.ID == id {
+			if k.RevokedAt == "" {
+				k.RevokedAt = time.Now().Format(time.RFC3339)
+			}
+			return writeKeysFile(keys)
+		}
+	}
+	return ErrNotFound
+}
+
+type APIKeyModel struct {
+	ID        int32  `gorm:"primaryKey;autoIncrement;column:id"`
+	Name      string `gorm:"column:name;type:varchar(64)"`
+	Hash      string `gorm:"column:hash;type:char(64);uniqueIndex:uk_hash"`
+	Scopes    string `gorm:"column:scopes;type:varchar(64)"`
+	CreatedAt string `gorm:"column:created_at;type:varchar(32)"`
+	RevokedAt string `gorm:"column:revoked_at;type:varchar(32)"`
+}
+
+func (APIKeyModel) TableName() string {
+	return "api_keys"
+}
+
+func (s *MySQLStorage) ListKeys(ctx context.Context) ([]*APIKey, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var models []APIKeyModel
+	if err := s.readDB.WithContext(ctx).Order("id ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	keys := make([]*APIKey, len(models))
+	for i := range models {
+		keys[i] = (*APIKey)(&models[i])
+	}
+	return keys, nil
+}
+
+func (s *MySQLStorage) FindKey(ctx context.Context, hash string) (*APIKey, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var m APIKeyModel
+	err := s.readDB.WithContext(ctx).Where("hash = ?", hash).First(&m).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return (*APIKey)(&m), nil
+}
+
+func (s *MySQLStorage) InsertKey(ctx context.Context, k *APIKey) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	m := APIKeyModel(*k)
+	m.ID = 0
+	if err := s.writeDB.WithContext(ctx).Create(&m).Error; err != nil {
+		return err
+	}
+	k.ID = m.ID
+	return nil
+}
+
+func (s *MySQLStorage) RevokeKey(ctx context.Context, id int32) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var m APIKeyModel
+	err := s.writeDB.WithContext(ctx).Where("id = ?", id).First(&m).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if m.RevokedAt != "" {
+		return nil
+	}
+	return s.writeDB.WithContext(ctx).Model(&APIKeyModel{}).Where("id = ?", id).
+		Update("revoked_at", time.Now().Format(time.RFC3339)).Error
+}
@@ -0,0 +1,112 @@
+package readwriter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// checksumPrefix starts the first line of the packets file, followed by the
+// hex SHA-256 of the JSON after that line. Files without it predate checksums
+// and are read as plain JSON.
+const checksumPrefix = "sha256:"
+
+// errChecksum is returned for packets files whose content does not match their header.
+var errChecksum = errors.New("packets file checksum mismatch")
+
+// withChecksum prepends the checksum header to data.
+func withChecksum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	out := make([]byte, 0, len(checksumPrefix)+sha256.Size*2+1+len(data))
+	out = append(out, checksumPrefix...)
+	out = append(out, hex.EncodeToString(sum[:])...)
+	out = append(out, '\n')
+	return append(out, data...)
+}
+
+// verifyChecksum strips and checks the checksum header of a packets file.
+func verifyChecksum(raw []byte) ([]byte, error) {
+	if !bytes.HasPrefix(raw, []byte(checksumPrefix)) {
+		return raw, nil
+	}
+	header, data, ok := bytes.Cut(raw, []byte("\n"))
+	if !ok {
+		return nil, errChecksum
+	}
+	want, err := hex.DecodeString(string(header[len(checksumPrefix):]))
+	if err != nil {
+		return nil, errChecksum
+	}
+	got := sha256.Sum256(data)
+	if !bytes.Equal(got[:], want) {
+		return nil, errChecksum
+	}
+	return data, nil
+}
+
+// writeFileAtomic replaces path with data so that readers and crashes only
+// ever see the old or the new content: the data goes to a temporary file in
+// the same directory, is synced, and is then renamed over path.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	// Removing fails harmlessly once the rename succeeded.
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir makes a rename in dir durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// keepGeneration makes dst a copy of src, replacing dst atomically. It hard
+// links when the filesystem allows and copies otherwise.
+func keepGeneration(src, dst string) error {
+	tmp := dst + ".link"
+	_ = os.Remove(tmp)
+	if err := os.Link(src, tmp); err == nil {
+		return os.Rename(tmp, dst)
+	}
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	st, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(dst, data, st.Mode().Perm())
+}
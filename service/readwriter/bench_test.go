@@ -1,24 +1,37 @@
 package readwriter
 
 import (
-    "os"
-    "path/filepath"
+    "context"
     "testing"
     packet "packet_cloud/biz/model/hertz/packet"
 )
 
 func BenchmarkLFSReadPacket(b *testing.B) {
-    dir := b.TempDir()
-    fp := filepath.Join(dir, "packets.json")
-    os.Setenv("PACKETS_FILE_PATH", fp)
+    ctx := context.Background()
+    useTempPacketsFile(b)
     s := &LocalFileSystem{}
     data := make([]*packet.CloudPacket, 0, 1000)
     for i := 0; i < 1000; i++ {
         data = append(data, &packet.CloudPacket{Id: int32(i + 1), Region: "r", Name: "n", Channel: "c", Uploader: "u", Time: "t", UserPackets: []*packet.UserPacket{{Id: int32(i + 1), Name: "a", Content: "b", Size: 2, SendTiming: "s"}}})
     }
-    _ = s.SavePacket(data)
+    _ = s.SavePacket(ctx, data)
     b.ResetTimer()
     for i := 0; i < b.N; i++ {
-        _, _ = s.ReadPacket()
+        _, _ = s.ReadPacket(ctx)
     }
-}
\ No newline at end of file
+}
+
+func BenchmarkIndexedList(b *testing.B) {
+    ctx := context.Background()
+    useTempPacketsFile(b)
+    s := NewIndexedStorage(&LocalFileSystem{})
+    data := make([]*packet.CloudPacket, 0, 1000)
+    for i := 0; i < 1000; i++ {
+        data = append(data, &packet.CloudPacket{Region: "r", Name: "n", Channel: "c", Uploader: "u", Time: "t", UserPackets: []*packet.UserPacket{{Id: int32(i + 1), Name: "a", Content: "b", Size: 2, SendTiming: "s"}}})
+    }
+    _ = s.Insert(ctx, data)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _, _, _ = s.List(ctx, Filter{Region: "r", Limit: 20, Summary: true})
+    }
+}
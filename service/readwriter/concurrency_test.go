@@ -0,0 +1,161 @@
+package readwriter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	packet "packet_cloud/biz/model/hertz/packet"
+	cfg "packet_cloud/config"
+)
+
+func TestLFSConcurrentInsertUniqueIDs(t *testing.T) {
+	ctx := context.Background()
+	useTempPacketsFile(t)
+
+	const uploads = 300
+	var wg sync.WaitGroup
+	ids := make(chan int32, uploads)
+	errs := make(chan error, uploads)
+	for i := 0; i < uploads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := &packet.CloudPacket{Region: "r", Name: "n", Channel: "c", Uploader: "u", Time: "t"}
+			if err := Insert(ctx, []*packet.CloudPacket{p}, LFS); err != nil {
+				errs <- err
+				return
+			}
+			ids <- p.Id
+		}()
+	}
+	wg.Wait()
+	close(ids)
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("insert: %v", err)
+	}
+	seen := make(map[int32]bool, uploads)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("duplicate id %d", id)
+		}
+		seen[id] = true
+	}
+
+	stored, err := ReadPacket(ctx, LFS)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(stored) != uploads || len(seen) != uploads {
+		t.Fatalf("lost packets: stored=%d assigned=%d", len(stored), len(seen))
+	}
+	for _, p := range stored {
+		if !seen[p.Id] {
+			t.Fatalf("stored id %d was never returned", p.Id)
+		}
+	}
+}
+
+func TestLFSDeletedIDsAreNotReused(t *testing.T) {
+	ctx := context.Background()
+	useTempPacketsFile(t)
+	s := &LocalFileSystem{}
+
+	first := []*packet.CloudPacket{{Name: "a"}, {Name: "b"}}
+	if err := s.Insert(ctx, first); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if _, err := s.trash(ctx, []int32{2}, "admin", time.Now()); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+	if _, err := s.purge(ctx, []int32{2}); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+
+	next := &packet.CloudPacket{Name: "c"}
+	if err := s.Insert(ctx, []*packet.CloudPacket{next}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if next.Id != 3 {
+		t.Fatalf("expected id 3 after deleting 2, got %d", next.Id)
+	}
+}
+
+func TestMySQLConcurrentInsertUniqueIDs(t *testing.T) {
+	if cfg.Get().MySQL.DSN == "" {
+		t.Skip("mysql dsn missing")
+	}
+	s := NewMySQLStorageFromConfig()
+	if s == nil {
+		t.Skip("mysql not available")
+	}
+	if err := s.SavePacket(context.Background(), nil); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+
+	testConcurrentInsertUniqueIDs(t, s)
+}
+
+// testConcurrentInsertUniqueIDs inserts packets in parallel into an empty s and
+// checks every one got a distinct ID and was stored.
+func testConcurrentInsertUniqueIDs(t *testing.T, s ReadWriter) {
+	ctx := context.Background()
+	const uploads = 200
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	seen := make(map[int32]bool, uploads)
+	for i := 0; i < uploads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := &packet.CloudPacket{Region: "r", Name: "n", Channel: "c", Uploader: "u", Time: "t", UserPackets: []*packet.UserPacket{{Name: "x", Content: "y"}}}
+			if err := s.Insert(ctx, []*packet.CloudPacket{p}); err != nil {
+				t.Errorf("insert: %v", err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if seen[p.Id] {
+				t.Errorf("duplicate id %d", p.Id)
+			}
+			seen[p.Id] = true
+		}()
+	}
+	wg.Wait()
+
+	stored, _, err := s.List(ctx, Filter{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(stored) != uploads || len(seen) != uploads {
+		t.Fatalf("lost packets: stored=%d assigned=%d", len(stored), len(seen))
+	}
+}
+
+// testDeletedIDsAreNotReused checks that s does not hand out the ID of a
+// purged packet again, even when it was the highest one.
+func testDeletedIDsAreNotReused(t *testing.T, s ReadWriter) {
+	ctx := context.Background()
+	first := []*packet.CloudPacket{{Name: "a"}, {Name: "b"}}
+	if err := s.Insert(ctx, first); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	rb := s.(recycleBin)
+	if _, err := rb.trash(ctx, []int32{first[1].Id}, "admin", time.Now()); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+	if _, err := rb.purge(ctx, []int32{first[1].Id}); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+
+	next := &packet.CloudPacket{Name: "c"}
+	if err := s.Insert(ctx, []*packet.CloudPacket{next}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if next.Id <= first[1].Id {
+		t.Fatalf("id %d reused after deleting %d", next.Id, first[1].Id)
+	}
+}
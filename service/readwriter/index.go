@@ -0,0 +1,181 @@
+package readwriter
+
+import (
+	"sort"
+
+	"packet_cloud/biz/model/hertz/packet"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// packetIndex is an in-memory view of a packet set with secondary indexes on
+// the columns List filters by equality. It is not synchronised: either share
+// it read-only or guard put and remove with a lock.
+type packetIndex struct {
+	byID       map[int32]*packet.CloudPacket
+	ids        []int32 // ascending
+	byRegion   map[string][]int32
+	byChannel  map[string][]int32
+	byUploader map[string][]int32
+}
+
+func newPacketIndex(packets []*packet.CloudPacket) *packetIndex {
+	ix := &packetIndex{
+		byID:       make(map[int32]*packet.CloudPacket, len(packets)),
+		ids:        make([]int32, 0, len(packets)),
+		byRegion:   make(map[string][]int32),
+		byChannel:  make(map[string][]int32),
+		byUploader: make(map[string][]int32),
+	}
+	for _, p := range packets {
+		ix.byID[p.Id] = p
+		ix.ids = append(ix.ids, p.Id)
+	}
+	sort.Slice(ix.ids, func(i, j int) bool { return ix.ids[i] < ix.ids[j] })
+	for _, id := range ix.ids {
+		p := ix.byID[id]
+		ix.byRegion[p.Region] = append(ix.byRegion[p.Region], id)
+		ix.byChannel[p.Channel] = append(ix.byChannel[p.Channel], id)
+		ix.byUploader[p.Uploader] = append(ix.byUploader[p.Uploader], id)
+	}
+	return ix
+}
+
+// put adds p or replaces the packet with the same ID. The index keeps p, so
+// it must not be modified afterwards.
+func (ix *packetIndex) put(p *packet.CloudPacket) {
+	if old, ok := ix.byID[p.Id]; ok {
+		ix.unlink(old)
+	} else {
+		ix.ids = insertID(ix.ids, p.Id)
+	}
+	ix.byID[p.Id] = p
+	ix.byRegion[p.Region] = insertID(ix.byRegion[p.Region], p.Id)
+	ix.byChannel[p.Channel] = insertID(ix.byChannel[p.Channel], p.Id)
+	ix.byUploader[p.Uploader] = insertID(ix.byUploader[p.Uploader], p.Id)
+}
+
+// remove drops the packet with the given ID, if any.
+func (ix *packetIndex) remove(id int32) {
+	old, ok := ix.byID[id]
+	if !ok {
+		return
+	}
+	ix.unlink(old)
+	delete(ix.byID, id)
+	ix.ids = removeID(ix.ids, id)
+}
+
+// unlink takes p out of the secondary indexes.
+func (ix *packetIndex) unlink(p *packet.CloudPacket) {
+	unlinkKey(ix.byRegion, p.Region, p.Id)
+	unlinkKey(ix.byChannel, p.Channel, p.Id)
+	unlinkKey(ix.byUploader, p.Uploader, p.Id)
+}
+
+func unlinkKey(m map[string][]int32, key string, id int32) {
+	if ids := removeID(m[key], id); len(ids) > 0 {
+		m[key] = ids
+	} else {
+		delete(m, key)
+	}
+}
+
+// insertID adds id to the ascending list ids unless it is already there.
+func insertID(ids []int32, id int32) []int32 {
+	i := sort.Search(len(ids), func(i int) bool { return ids[i] >= id })
+	if i < len(ids) && ids[i] == id {
+		return ids
+	}
+	ids = append(ids, 0)
+	copy(ids[i+1:], ids[i:])
+	ids[i] = id
+	return ids
+}
+
+// removeID takes id out of the ascending list ids.
+func removeID(ids []int32, id int32) []int32 {
+	i := sort.Search(len(ids), func(i int) bool { return ids[i] >= id })
+	if i == len(ids) || ids[i] != id {
+		return ids
+	}
+	return append(ids[:i], ids[i+1:]...)
+}
+
+// candidates returns the smallest ID list that can satisfy the equality
+// conditions of f, in ascending order.
+func (ix *packetIndex) candidates(f Filter) []int32 {
+	best := ix.ids
+	pick := func(m map[string][]int32, key string) {
+		if ids := m[key]; key != "" && len(ids) < len(best) {
+			best = ids
+		}
+	}
+	pick(ix.byRegion, f.Region)
+	pick(ix.byChannel, f.Channel)
+	pick(ix.byUploader, f.Uploader)
+	return best
+}
+
+// query applies f to the index and returns copies of the selected page, so
+// callers may modify the result freely, along with the total match count.
+func (ix *packetIndex) query(f Filter) ([]*packet.CloudPacket, int64) {
+	candidates := ix.candidates(f)
+	matched := make([]*packet.CloudPacket, 0, len(candidates))
+	for _, id := range candidates {
+		if p := ix.byID[id]; f.Match(p) {
+			matched = append(matched, p)
+		}
+	}
+
+	switch {
+	case f.SortBy == SortByTime:
+		sort.SliceStable(matched, func(i, j int) bool {
+			if matched[i].Time != matched[j].Time {
+				return (matched[i].Time < matched[j].Time) != f.Desc
+			}
+			return (matched[i].Id < matched[j].Id) != f.Desc
+		})
+	case f.Desc:
+		for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+			matched[i], matched[j] = matched[j], matched[i]
+		}
+	}
+
+	total := int64(len(matched))
+	page := paginate(matched, f.Offset, f.Limit)
+	out := make([]*packet.CloudPacket, len(page))
+	for i, p := range page {
+		out[i] = copyPacket(p, f.Summary)
+	}
+	return out, total
+}
+
+func paginate(packets []*packet.CloudPacket, offset, limit int) []*packet.CloudPacket {
+	if offset >= len(packets) {
+		return nil
+	}
+	if offset > 0 {
+		packets = packets[offset:]
+	}
+	if limit > 0 && limit < len(packets) {
+		packets = packets[:limit]
+	}
+	return packets
+}
+
+// copyPacket returns a deep copy of p, leaving out its user packets when summary is set.
+func copyPacket(p *packet.CloudPacket, summary bool) *packet.CloudPacket {
+	if summary {
+		return &packet.CloudPacket{
+			Id:          p.Id,
+			Region:      p.Region,
+			Name:        p.Name,
+			Channel:     p.Channel,
+			Uploader:    p.Uploader,
+			Time:        p.Time,
+			UserPackets: make([]*packet.UserPacket, 0),
+		}
+	}
+	return proto.Clone(p).(*packet.CloudPacket)
+}
@@ -0,0 +1,112 @@
+package readwriter
+
+import (
+	"testing"
+
+	packet "packet_cloud/biz/model/hertz/packet"
+)
+
+func indexFixture() *packetIndex {
+	return newPacketIndex([]*packet.CloudPacket{
+		{Id: 3, Region: "r1", Name: "alpha buff", Channel: "c1", Uploader: "u1", Time: "2024-01-03", UserPackets: []*packet.UserPacket{{Content: "a"}}},
+		{Id: 1, Region: "r1", Name: "beta", Channel: "c2", Uploader: "u2", Time: "2024-01-05"},
+		{Id: 2, Region: "r2", Name: "gamma buff", Channel: "c1", Uploader: "u1", Time: "2024-01-01"},
+		{Id: 4, Region: "r1", Name: "delta", Channel: "c1", Uploader: "u1", Time: "2024-01-04"},
+	})
+}
+
+func ids(packets []*packet.CloudPacket) []int32 {
+	out := make([]int32, len(packets))
+	for i, p := range packets {
+		out[i] = p.Id
+	}
+	return out
+}
+
+func TestPacketIndexQuery(t *testing.T) {
+	ix := indexFixture()
+	cases := []struct {
+		name   string
+		filter Filter
+		want   []int32
+		total  int64
+	}{
+		{"all", Filter{}, []int32{1, 2, 3, 4}, 4},
+		{"region", Filter{Region: "r1"}, []int32{1, 3, 4}, 3},
+		{"region and channel", Filter{Region: "r1", Channel: "c1"}, []int32{3, 4}, 2},
+		{"unknown uploader", Filter{Region: "r1", Uploader: "nobody"}, []int32{}, 0},
+		{"name substring", Filter{Name: "buff"}, []int32{2, 3}, 2},
+		{"time range", Filter{TimeFrom: "2024-01-03", TimeTo: "2024-01-04"}, []int32{3, 4}, 2},
+		{"desc", Filter{Desc: true}, []int32{4, 3, 2, 1}, 4},
+		{"by time", Filter{SortBy: SortByTime}, []int32{2, 3, 4, 1}, 4},
+		{"by time desc", Filter{SortBy: SortByTime, Desc: true}, []int32{1, 4, 3, 2}, 4},
+		{"page", Filter{Offset: 1, Limit: 2}, []int32{2, 3}, 4},
+		{"past the end", Filter{Offset: 10, Limit: 2}, []int32{}, 4},
+	}
+	for _, c := range cases {
+		got, total := ix.query(c.filter)
+		if total != c.total || len(got) != len(c.want) {
+			t.Fatalf("%s: got %v total %d, want %v total %d", c.name, ids(got), total, c.want, c.total)
+		}
+		for i := range got {
+			if got[i].Id != c.want[i] {
+				t.Fatalf("%s: got %v, want %v", c.name, ids(got), c.want)
+			}
+		}
+	}
+}
+
+func TestPacketIndexQueryReturnsCopies(t *testing.T) {
+	ix := indexFixture()
+
+	got, _ := ix.query(Filter{Region: "r1", Channel: "c1", Uploader: "u1", Limit: 1})
+	got[0].Name = "changed"
+	got[0].UserPackets[0].Content = "changed"
+
+	again, _ := ix.query(Filter{Region: "r1", Channel: "c1", Uploader: "u1", Limit: 1})
+	if again[0].Name != "alpha buff" || again[0].UserPackets[0].Content != "a" {
+		t.Fatalf("index was modified through a query result: %+v", again[0])
+	}
+
+	summary, _ := ix.query(Filter{Limit: 1, Offset: 2, Summary: true})
+	if summary[0].Id != 3 || len(summary[0].UserPackets) != 0 {
+		t.Fatalf("summary should leave out user packets: %+v", summary[0])
+	}
+}
+
+func TestPacketIndexPutAndRemove(t *testing.T) {
+	ix := indexFixture()
+
+	// Moving packet 3 to another region and channel updates the secondary indexes.
+	ix.put(&packet.CloudPacket{Id: 3, Region: "r2", Name: "alpha", Channel: "c9", Uploader: "u1"})
+	ix.put(&packet.CloudPacket{Id: 7, Region: "r1", Name: "new", Channel: "c1", Uploader: "u3"})
+	ix.remove(1)
+	ix.remove(42)
+
+	cases := []struct {
+		name   string
+		filter Filter
+		want   []int32
+	}{
+		{"all", Filter{}, []int32{2, 3, 4, 7}},
+		{"region", Filter{Region: "r1"}, []int32{4, 7}},
+		{"moved", Filter{Region: "r2"}, []int32{2, 3}},
+		{"new channel", Filter{Channel: "c9"}, []int32{3}},
+		{"old channel", Filter{Channel: "c1"}, []int32{2, 4, 7}},
+		{"removed uploader", Filter{Uploader: "u2"}, []int32{}},
+	}
+	for _, c := range cases {
+		got, total := ix.query(c.filter)
+		if int(total) != len(c.want) || len(got) != len(c.want) {
+			t.Fatalf("%s: got %v, want %v", c.name, ids(got), c.want)
+		}
+		for i := range got {
+			if got[i].Id != c.want[i] {
+				t.Fatalf("%s: got %v, want %v", c.name, ids(got), c.want)
+			}
+		}
+	}
+	if _, ok := ix.byUploader["u2"]; ok {
+		t.Fatal("empty secondary index entry was kept")
+	}
+}
@@ -0,0 +1,293 @@
+package readwriter
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"packet_cloud/biz/model/hertz/packet"
+	cfg "packet_cloud/config"
+)
+
+// IndexedStorage serves ReadPacket, Get and List from an in-memory index of
+// the packets of its backend, loaded on first use. Every write made through it
+// goes to the backend first and is then applied to the index, so reads never
+// touch the disk or the database. Changes made behind its back, such as a hand
+// edited packets file, are only picked up after a restart.
+type IndexedStorage struct {
+	backend ReadWriter
+
+	// writeLock orders writes, so the index sees them in the order the backend did.
+	writeLock sync.Mutex
+	lock      sync.RWMutex
+	index     *packetIndex
+	// revs caches the revision log for conditional reads; nil until loaded.
+	revs *revisionCache
+}
+
+var (
+	// indexedStorages holds the shared index of every backend in use, keyed by
+	// storageKey.
+	indexedStorages     = make(map[string]*IndexedStorage)
+	indexedStoragesLock sync.Mutex
+)
+
+// storageKey identifies the data set media refers to with the current config.
+func storageKey(media StorageMedia) string {
+	switch media {
+	case MySQL:
+		return "mysql:" + mysqlDSN()
+	case SQLite:
+		return "sqlite:" + sqlitePath()
+	case Journal:
+		return "journal:" + cfg.Get().PacketsFilePath
+	default:
+		return "lfs:" + cfg.Get().PacketsFilePath
+	}
+}
+
+// indexedStorage returns the shared IndexedStorage of media, calling open to
+// create its backend the first time. It returns nil when open does.
+func indexedStorage(media StorageMedia, open func() ReadWriter) *IndexedStorage {
+	key := storageKey(media)
+
+	indexedStoragesLock.Lock()
+	defer indexedStoragesLock.Unlock()
+
+	if s, ok := indexedStorages[key]; ok {
+		return s
+	}
+	backend := open()
+	if backend == nil {
+		return nil
+	}
+	s := NewIndexedStorage(backend)
+	indexedStorages[key] = s
+	return s
+}
+
+// Open opens the configured storage and loads its index. The server calls it
+// at startup, so a misconfigured or unreachable backend stops it right away
+// and requests reuse the one connection instead of dialling their own.
+func Open(ctx context.Context) error {
+	rw := newReadWriter(LFS)
+	if rw == nil {
+		return errors.Errorf("open %s storage failed", cfg.Get().StorageMedia)
+	}
+	_, _, err := rw.List(ctx, Filter{Limit: 1})
+	return errors.Wrap(err, "load packets")
+}
+
+// Close closes every storage opened so far. Later calls open them again.
+func Close() error {
+	indexedStoragesLock.Lock()
+	defer indexedStoragesLock.Unlock()
+
+	var err error
+	for key, s := range indexedStorages {
+		if c, ok := s.backend.(io.Closer); ok {
+			if cerr := c.Close(); cerr != nil && err == nil {
+				err = errors.Wrapf(cerr, "close %s", key)
+			}
+		}
+		delete(indexedStorages, key)
+	}
+	return err
+}
+
+// NewIndexedStorage puts an index in front of backend. Use it for one
+// backend only; the storages handed out by this package are already shared.
+func NewIndexedStorage(backend ReadWriter) *IndexedStorage {
+	return &IndexedStorage{backend: backend}
+}
+
+// Backend returns the wrapped storage.
+func (s *IndexedStorage) Backend() ReadWriter {
+	return s.backend
+}
+
+// load returns the index, reading the backend if it is not loaded yet.
+// Callers must not hold s.lock.
+func (s *IndexedStorage) load(ctx context.Context) (*packetIndex, error) {
+	s.lock.RLock()
+	ix := s.index
+	s.lock.RUnlock()
+	if ix != nil {
+		return ix, nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.index != nil {
+		return s.index, nil
+	}
+	packets, err := s.backend.ReadPacket(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stored := make([]*packet.CloudPacket, len(packets))
+	for i, p := range packets {
+		stored[i] = copyPacket(p, false)
+	}
+	s.index = newPacketIndex(stored)
+	return s.index, nil
+}
+
+// Invalidate drops the index, so the next read loads the backend again.
+func (s *IndexedStorage) Invalidate() {
+	s.lock.Lock()
+	s.index = nil
+	s.revs = nil
+	s.lock.Unlock()
+}
+
+// apply runs change on the loaded index and records the revision the write
+// made for ids, the packets it touched. When the backend write failed the
+// index is dropped instead, since the backend may have been changed partly.
+// Callers must hold s.writeLock.
+func (s *IndexedStorage) apply(ctx context.Context, err error, ids []int32, change func(ix *packetIndex)) error {
+	var head *revisionCache
+	if err == nil && len(ids) > 0 {
+		// Read outside s.lock, so reads do not wait for the backend.
+		head, _ = s.readRevisionHead(ctx)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err != nil {
+		s.index = nil
+		s.revs = nil
+		return err
+	}
+	if s.index != nil {
+		change(s.index)
+	}
+	if len(ids) > 0 {
+		s.revs = s.revs.advance(head, ids)
+	}
+	return nil
+}
+
+func (s *IndexedStorage) ReadPacket(ctx context.Context) ([]*packet.CloudPacket, error) {
+	ix, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	packets := make([]*packet.CloudPacket, len(ix.ids))
+	for i, id := range ix.ids {
+		packets[i] = copyPacket(ix.byID[id], false)
+	}
+	return packets, nil
+}
+
+func (s *IndexedStorage) Get(ctx context.Context, id int32) (*packet.CloudPacket, error) {
+	ix, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	p, ok := ix.byID[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyPacket(p, false), nil
+}
+
+func (s *IndexedStorage) List(ctx context.Context, filter Filter) ([]*packet.CloudPacket, int64, error) {
+	ix, err := s.load(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	packets, total := ix.query(filter)
+	return packets, total, nil
+}
+
+func (s *IndexedStorage) SavePacket(ctx context.Context, packets []*packet.CloudPacket) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	err := s.backend.SavePacket(ctx, packets)
+	// Reload on the next read, in the form the backend stored the packets.
+	s.Invalidate()
+	return err
+}
+
+// Replace replaces the whole dataset with what merge makes of the current
+// packets. It holds the write lock from the read to the write, so no write in
+// between is lost. A nil result from merge leaves the data unchanged.
+func (s *IndexedStorage) Replace(ctx context.Context, merge func(current []*packet.CloudPacket) ([]*packet.CloudPacket, error)) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	current, err := s.ReadPacket(ctx)
+	if err != nil {
+		return err
+	}
+	packets, err := merge(current)
+	if err != nil || packets == nil {
+		return err
+	}
+
+	err = s.backend.SavePacket(ctx, packets)
+	s.Invalidate()
+	return err
+}
+
+func (s *IndexedStorage) Insert(ctx context.Context, packets []*packet.CloudPacket) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	err := s.backend.Insert(ctx, packets)
+	return s.apply(ctx, err, packetIDs(packets), func(ix *packetIndex) {
+		for _, p := range packets {
+			ix.put(copyPacket(p, false))
+		}
+	})
+}
+
+func (s *IndexedStorage) Update(ctx context.Context, p *packet.CloudPacket) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	err := s.backend.Update(ctx, p)
+	if errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return s.apply(ctx, err, []int32{p.Id}, func(ix *packetIndex) {
+		ix.put(copyPacket(p, false))
+	})
+}
+
+func (s *IndexedStorage) Patch(ctx context.Context, id int32, fields PatchFields) (*packet.CloudPacket, error) {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	patched, err := s.backend.Patch(ctx, id, fields)
+	if errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if err := s.apply(ctx, err, []int32{id}, func(ix *packetIndex) {
+		ix.put(copyPacket(patched, false))
+	}); err != nil {
+		return nil, err
+	}
+	return patched, nil
+}
+
+func (s *IndexedStorage) Backup(ctx context.Context) (*BackupData, error) {
+	return s.backend.Backup(ctx)
+}
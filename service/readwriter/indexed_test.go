@@ -0,0 +1,245 @@
+package readwriter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	packet "packet_cloud/biz/model/hertz/packet"
+	cfg "packet_cloud/config"
+)
+
+// countingStorage counts the reads that reach the backend and can be told to
+// fail writes. Its recycle bin, if set, is passed through uncounted.
+type countingStorage struct {
+	ReadWriter
+	recycleBin
+	reads    int
+	failNext bool
+}
+
+func (s *countingStorage) ReadPacket(ctx context.Context) ([]*packet.CloudPacket, error) {
+	s.reads++
+	return s.ReadWriter.ReadPacket(ctx)
+}
+
+func (s *countingStorage) Get(ctx context.Context, id int32) (*packet.CloudPacket, error) {
+	s.reads++
+	return s.ReadWriter.Get(ctx, id)
+}
+
+func (s *countingStorage) List(ctx context.Context, filter Filter) ([]*packet.CloudPacket, int64, error) {
+	s.reads++
+	return s.ReadWriter.List(ctx, filter)
+}
+
+func (s *countingStorage) Insert(ctx context.Context, packets []*packet.CloudPacket) error {
+	if s.failNext {
+		s.failNext = false
+		return errors.New("disk full")
+	}
+	return s.ReadWriter.Insert(ctx, packets)
+}
+
+func TestIndexedRecordCRUD(t *testing.T) {
+	useTempPacketsFile(t)
+	testRecordCRUD(t, NewIndexedStorage(&LocalFileSystem{}))
+}
+
+func TestIndexedSQLiteRecordCRUD(t *testing.T) {
+	testRecordCRUD(t, NewIndexedStorage(useTempSQLite(t)))
+}
+
+func TestIndexedReadsStayInMemory(t *testing.T) {
+	ctx := context.Background()
+	useTempPacketsFile(t)
+	lfs := &LocalFileSystem{}
+	backend := &countingStorage{ReadWriter: lfs, recycleBin: lfs}
+	s := NewIndexedStorage(backend)
+
+	in := []*packet.CloudPacket{
+		{Region: "r1", Name: "a", Channel: "c1", Uploader: "u1"},
+		{Region: "r2", Name: "b", Channel: "c1", Uploader: "u2"},
+	}
+	if err := s.Insert(ctx, in); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if _, _, err := s.List(ctx, Filter{}); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	loads := backend.reads
+
+	region := "r3"
+	if _, err := s.Patch(ctx, in[0].Id, PatchFields{Region: &region}); err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	if err := s.Update(ctx, &packet.CloudPacket{Id: in[1].Id, Region: "r2", Name: "b2", Channel: "c2", Uploader: "u2"}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if _, err := s.Trash(ctx, Selector{IDs: []int32{in[0].Id}}, "admin"); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+
+	if got, total, _ := s.List(ctx, Filter{Region: "r3"}); total != 0 {
+		t.Fatalf("deleted packet still listed: %+v", got)
+	}
+	if got, total, _ := s.List(ctx, Filter{Channel: "c2"}); total != 1 || got[0].Name != "b2" {
+		t.Fatalf("updated packet not indexed: %+v", got)
+	}
+	if _, err := s.Get(ctx, in[0].Id); err != ErrNotFound {
+		t.Fatalf("get deleted: %v", err)
+	}
+	if _, err := s.ReadPacket(ctx); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if backend.reads != loads {
+		t.Fatalf("reads reached the backend %d times after loading", backend.reads-loads)
+	}
+
+	// The backend holds the same state the index serves.
+	stored, _ := backend.ReadWriter.ReadPacket(ctx)
+	if len(stored) != 1 || stored[0].Name != "b2" {
+		t.Fatalf("backend out of sync: %+v", stored)
+	}
+}
+
+func TestIndexedFailedWriteReloads(t *testing.T) {
+	ctx := context.Background()
+	useTempPacketsFile(t)
+	backend := &countingStorage{ReadWriter: &LocalFileSystem{}}
+	s := NewIndexedStorage(backend)
+
+	if err := s.Insert(ctx, []*packet.CloudPacket{{Name: "a"}}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if _, _, err := s.List(ctx, Filter{}); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+
+	backend.failNext = true
+	if err := s.Insert(ctx, []*packet.CloudPacket{{Name: "b"}}); err == nil {
+		t.Fatal("expected the backend error")
+	}
+	loads := backend.reads
+	if got, total, _ := s.List(ctx, Filter{}); total != 1 || got[0].Name != "a" {
+		t.Fatalf("unexpected state after failed write: %+v", got)
+	}
+	if backend.reads != loads+1 {
+		t.Fatal("index was not reloaded after a failed write")
+	}
+}
+
+func TestIndexedSQLiteMatchesBackend(t *testing.T) {
+	ctx := context.Background()
+	backend := useTempSQLite(t)
+	s := NewIndexedStorage(backend)
+	if _, _, err := s.List(ctx, Filter{}); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+
+	p := &packet.CloudPacket{Name: "a", UserPackets: []*packet.UserPacket{{Id: 7, Name: "x"}, {Id: 9, Name: "y"}}}
+	if err := s.Insert(ctx, []*packet.CloudPacket{p}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	want, err := backend.Get(ctx, p.Id)
+	if err != nil {
+		t.Fatalf("backend get: %v", err)
+	}
+	got, err := s.Get(ctx, p.Id)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	for i := range want.UserPackets {
+		if got.UserPackets[i].Id != want.UserPackets[i].Id {
+			t.Fatalf("index holds user packet ids %v, backend %v", got.UserPackets, want.UserPackets)
+		}
+	}
+}
+
+func TestSharedIndexPerPacketsFile(t *testing.T) {
+	useTempPacketsFile(t)
+	if newReadWriter(LFS) != newReadWriter(LFS) {
+		t.Fatal("expected one shared storage per packets file")
+	}
+	first := newReadWriter(LFS)
+	useTempPacketsFile(t)
+	if newReadWriter(LFS) == first {
+		t.Fatal("storage shared across packets files")
+	}
+}
+
+func TestOpenAndClose(t *testing.T) {
+	useTempPacketsFile(t)
+	ctx := context.Background()
+
+	if err := Open(ctx); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	opened := newReadWriter(LFS)
+	if err := opened.Insert(ctx, []*packet.CloudPacket{{Name: "a"}}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if err := Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	reopened := newReadWriter(LFS)
+	if reopened == opened {
+		t.Fatal("storage survived Close")
+	}
+	if got, _ := reopened.ReadPacket(ctx); len(got) != 1 {
+		t.Fatalf("data lost across Close: %+v", got)
+	}
+}
+
+func TestOpenJournalClosesOnShutdown(t *testing.T) {
+	useTempPacketsFile(t)
+	cfg.Get().StorageMedia = "journal"
+	ctx := context.Background()
+
+	if err := Open(ctx); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := Insert(ctx, []*packet.CloudPacket{{Name: "a"}}, LFS); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if err := Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	// Closing compacted the journal into the packets file.
+	if snapshot, err := loadPacketsFile(cfg.Get().PacketsFilePath); err != nil || len(snapshot) != 1 {
+		t.Fatalf("snapshot after close: %v %+v", err, snapshot)
+	}
+}
+
+func TestReplaceKeepsConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	useTempPacketsFile(t)
+	s := NewIndexedStorage(&LocalFileSystem{})
+	if err := s.Insert(ctx, []*packet.CloudPacket{{Name: "a"}}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	inserted := make(chan error)
+	err := s.Replace(ctx, func(current []*packet.CloudPacket) ([]*packet.CloudPacket, error) {
+		go func() { inserted <- s.Insert(ctx, []*packet.CloudPacket{{Name: "b"}}) }()
+		// Give the insert the chance to run, as it would without the lock.
+		time.Sleep(50 * time.Millisecond)
+		current[0].Name = "a2"
+		return current, nil
+	})
+	if err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	if err := <-inserted; err != nil {
+		t.Fatalf("insert during replace: %v", err)
+	}
+
+	packets, _ := s.ReadPacket(ctx)
+	if len(packets) != 2 || packets[0].Name != "a2" || packets[1].Name != "b" {
+		t.Fatalf("packets after replace: %+v", packets)
+	}
+}
@@ -0,0 +1,457 @@
+package readwriter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/pkg/errors"
+
+	"packet_cloud/biz/model/hertz/packet"
+	cfg "packet_cloud/config"
+)
+
+// Journal operations. Replaying a record twice has the same effect as once, so
+// a crash between writing a snapshot and truncating the journal is harmless.
+const (
+	journalInsert = "insert" // add or replace Packets
+	journalUpdate = "update" // replace Packets that exist
+	journalDelete = "delete" // remove IDs
+	journalSave   = "save"   // replace the whole dataset with Packets
+)
+
+// journalRecord is one line of the journal file.
+type journalRecord struct {
+	Op      string                `json:"op"`
+	Packets []*packet.CloudPacket `json:"packets,omitempty"`
+	IDs     []int32               `json:"ids,omitempty"`
+	// Seq is the last allocated packet ID after the operation.
+	Seq int32 `json:"seq"`
+}
+
+// JournalFileSystem keeps the packets in memory and persists each change by
+// appending a record to a journal file next to the packets file, which serves
+// as the snapshot. The journal is folded into a new snapshot once it holds
+// CompactRecords records, and every CompactIntervalMin minutes.
+type JournalFileSystem struct {
+	LocalFileSystem
+
+	lock    sync.RWMutex
+	path    string
+	packets []*packet.CloudPacket // ascending by ID
+	seq     int32
+
+	journal        *os.File
+	records        int
+	compactRecords int
+	stop           chan struct{}
+}
+
+var errJournalClosed = errors.New("journal is closed")
+
+var (
+	// journalStorages holds one storage per packets file, since every instance
+	// owns the in-memory state and the journal file handle.
+	journalStorages     = make(map[string]*JournalFileSystem)
+	journalStoragesLock sync.Mutex
+)
+
+// NewJournalFileSystemFromConfig returns the journaled storage of the
+// configured packets file, loading it on first use.
+func NewJournalFileSystemFromConfig() *JournalFileSystem {
+	path := cfg.Get().PacketsFilePath
+
+	journalStoragesLock.Lock()
+	defer journalStoragesLock.Unlock()
+
+	if s, ok := journalStorages[path]; ok {
+		return s
+	}
+	s, err := OpenJournalFileSystem(cfg.Get().Journal.CompactRecords, time.Duration(cfg.Get().Journal.CompactIntervalMin)*time.Minute)
+	if err != nil {
+		log.Printf("open journal %s error: %v", path, err)
+		return nil
+	}
+	journalStorages[path] = s
+	return s
+}
+
+// OpenJournalFileSystem rebuilds the state of the configured packets file from
+// its snapshot and journal. compactRecords and compactInterval default to
+// 1000 records and one hour when zero.
+func OpenJournalFileSystem(compactRecords int, compactInterval time.Duration) (*JournalFileSystem, error) {
+	s := &JournalFileSystem{
+		path:           cfg.Get().PacketsFilePath,
+		compactRecords: intOr(compactRecords, 1000),
+		stop:           make(chan struct{}),
+	}
+	if compactInterval <= 0 {
+		compactInterval = time.Hour
+	}
+
+	syncLock.RLock()
+	packets, err := readPacketsFile()
+	if err == nil {
+		s.seq, err = readSequenceFile()
+	}
+	syncLock.RUnlock()
+	if err != nil {
+		return nil, errors.Wrap(err, "read snapshot")
+	}
+	s.packets = packets
+	sortPackets(s.packets)
+	for _, p := range s.packets {
+		if p.Id > s.seq {
+			s.seq = p.Id
+		}
+	}
+
+	if err := s.replay(); err != nil {
+		return nil, errors.Wrap(err, "replay journal")
+	}
+
+	go s.compactLoop(compactInterval)
+	return s, nil
+}
+
+func (s *JournalFileSystem) journalPath() string {
+	return s.path + ".journal"
+}
+
+// replay applies the journal to the snapshot and opens it for appending. A
+// torn record at the end, left by a crash mid-append, is cut off.
+func (s *JournalFileSystem) replay() error {
+	f, err := os.OpenFile(s.journalPath(), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	var good int64
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				log.Printf("[Journal] dropping incomplete last record of %s (%d bytes)", s.journalPath(), len(line))
+			}
+			break
+		}
+		if err != nil {
+			f.Close()
+			return err
+		}
+
+		var rec journalRecord
+		if err := sonic.Unmarshal(bytes.TrimSpace(line), &rec); err != nil {
+			log.Printf("[Journal] !!! corrupt record at offset %d of %s, ignoring it and everything after: %v", good, s.journalPath(), err)
+			break
+		}
+		s.apply(&rec)
+		s.records++
+		good += int64(len(line))
+	}
+
+	if err := f.Truncate(good); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(good, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	s.journal = f
+	return nil
+}
+
+// apply changes the in-memory state. Callers must hold s.lock for writing.
+func (s *JournalFileSystem) apply(rec *journalRecord) {
+	switch rec.Op {
+	case journalSave:
+		s.packets = append([]*packet.CloudPacket(nil), rec.Packets...)
+		sortPackets(s.packets)
+	case journalInsert:
+		for _, p := range rec.Packets {
+			if i, ok := s.find(p.Id); ok {
+				s.packets[i] = p
+			} else {
+				s.packets = append(s.packets, p)
+			}
+		}
+		sortPackets(s.packets)
+	case journalUpdate:
+		for _, p := range rec.Packets {
+			if i, ok := s.find(p.Id); ok {
+				s.packets[i] = p
+			}
+		}
+	case journalDelete:
+		deleted := make(map[int32]bool, len(rec.IDs))
+		for _, id := range rec.IDs {
+			deleted[id] = true
+		}
+		remaining := s.packets[:0]
+		for _, p := range s.packets {
+			if !deleted[p.Id] {
+				remaining = append(remaining, p)
+			}
+		}
+		s.packets = remaining
+	}
+	if rec.Seq > s.seq {
+		s.seq = rec.Seq
+	}
+}
+
+// commit appends rec to the journal, syncs it and applies it. Callers must
+// hold s.lock for writing.
+func (s *JournalFileSystem) commit(rec *journalRecord) error {
+	if s.journal == nil {
+		return errJournalClosed
+	}
+	if err := s.touch(rec); err != nil {
+		return err
+	}
+	rec.Seq = s.seq
+	line, err := sonic.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	off, err := s.journal.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := s.journal.Write(append(line, '\n')); err != nil {
+		s.rollback(off)
+		return err
+	}
+	if err := s.journal.Sync(); err != nil {
+		s.rollback(off)
+		return err
+	}
+
+	s.apply(rec)
+	s.records++
+	if s.records >= s.compactRecords {
+		if err := s.compact(); err != nil {
+			// The journal still holds everything, so this only costs replay time.
+			log.Println("[Journal] compact error", err)
+		}
+	}
+	return nil
+}
+
+// touch records the revision of the packets rec changes in the revisions
+// file LFS uses. Callers must hold s.lock for writing.
+func (s *JournalFileSystem) touch(rec *journalRecord) error {
+	ids := rec.IDs
+	if rec.Op != journalDelete {
+		ids = packetIDs(rec.Packets)
+	}
+	if rec.Op == journalSave {
+		ids = append(ids, packetIDs(s.packets)...)
+	}
+
+	syncLock.Lock()
+	defer syncLock.Unlock()
+	return touchRevisionsFile(ids)
+}
+
+// rollback cuts a partially written record off the journal, so later records
+// are not appended after garbage.
+func (s *JournalFileSystem) rollback(off int64) {
+	if err := s.journal.Truncate(off); err != nil {
+		log.Println("[Journal] rollback error", err)
+	}
+	if _, err := s.journal.Seek(off, io.SeekStart); err != nil {
+		log.Println("[Journal] rollback error", err)
+	}
+}
+
+// compact writes the state as a new snapshot and empties the journal. Callers
+// must hold s.lock for writing.
+func (s *JournalFileSystem) compact() error {
+	if s.records == 0 {
+		return nil
+	}
+
+	syncLock.Lock()
+	err := writePacketsFile(s.packets)
+	if err == nil {
+		err = writeSequenceFile(s.seq)
+	}
+	syncLock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := s.journal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := s.journal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s.records = 0
+	return s.journal.Sync()
+}
+
+// Compact folds the journal into a new snapshot now.
+func (s *JournalFileSystem) Compact() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.compact()
+}
+
+func (s *JournalFileSystem) compactLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Compact(); err != nil {
+				log.Println("[Journal] compact error", err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Close compacts the journal and releases the file. Closing twice is a no-op.
+func (s *JournalFileSystem) Close() error {
+	journalStoragesLock.Lock()
+	if journalStorages[s.path] == s {
+		delete(journalStorages, s.path)
+	}
+	journalStoragesLock.Unlock()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.journal == nil {
+		return nil
+	}
+	close(s.stop)
+	err := s.compact()
+	if cerr := s.journal.Close(); err == nil {
+		err = cerr
+	}
+	s.journal = nil
+	return err
+}
+
+// find returns the position of the packet with the given ID. Callers must hold s.lock.
+func (s *JournalFileSystem) find(id int32) (int, bool) {
+	i := sort.Search(len(s.packets), func(i int) bool { return s.packets[i].Id >= id })
+	return i, i < len(s.packets) && s.packets[i].Id == id
+}
+
+func sortPackets(packets []*packet.CloudPacket) {
+	sort.SliceStable(packets, func(i, j int) bool { return packets[i].Id < packets[j].Id })
+}
+
+func (s *JournalFileSystem) ReadPacket(ctx context.Context) ([]*packet.CloudPacket, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	packets := make([]*packet.CloudPacket, len(s.packets))
+	for i, p := range s.packets {
+		packets[i] = copyPacket(p, false)
+	}
+	return packets, nil
+}
+
+func (s *JournalFileSystem) SavePacket(ctx context.Context, packets []*packet.CloudPacket) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	saved := make([]*packet.CloudPacket, len(packets))
+	for i, p := range packets {
+		saved[i] = copyPacket(p, false)
+		if p.Id > s.seq {
+			s.seq = p.Id
+		}
+	}
+	return s.commit(&journalRecord{Op: journalSave, Packets: saved})
+}
+
+func (s *JournalFileSystem) Get(ctx context.Context, id int32) (*packet.CloudPacket, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	i, ok := s.find(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyPacket(s.packets[i], false), nil
+}
+
+func (s *JournalFileSystem) Insert(ctx context.Context, inserted []*packet.CloudPacket) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stored := make([]*packet.CloudPacket, len(inserted))
+	for i, p := range inserted {
+		s.seq++
+		p.Id = s.seq
+		stored[i] = copyPacket(p, false)
+	}
+	return s.commit(&journalRecord{Op: journalInsert, Packets: stored})
+}
+
+func (s *JournalFileSystem) Update(ctx context.Context, updated *packet.CloudPacket) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.find(updated.Id); !ok {
+		return ErrNotFound
+	}
+	return s.commit(&journalRecord{Op: journalUpdate, Packets: []*packet.CloudPacket{copyPacket(updated, false)}})
+}
+
+func (s *JournalFileSystem) Patch(ctx context.Context, id int32, fields PatchFields) (*packet.CloudPacket, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	i, ok := s.find(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	patched := copyPacket(s.packets[i], false)
+	fields.Apply(patched)
+	if err := s.commit(&journalRecord{Op: journalUpdate, Packets: []*packet.CloudPacket{patched}}); err != nil {
+		return nil, err
+	}
+	return copyPacket(patched, false), nil
+}
+
+// List scans the packets in memory; IndexedStorage keeps the index.
+func (s *JournalFileSystem) List(ctx context.Context, filter Filter) ([]*packet.CloudPacket, int64, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	packets, total := newPacketIndex(s.packets).query(filter)
+	return packets, total, nil
+}
+
+// Backup copies the packets from memory, since the packets file lags behind
+// the journal.
+func (s *JournalFileSystem) Backup(ctx context.Context) (*BackupData, error) {
+	packets, err := s.ReadPacket(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := s.ListKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &BackupData{Packets: packets, Keys: keys}, nil
+}
@@ -0,0 +1,191 @@
+package readwriter
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	packet "packet_cloud/biz/model/hertz/packet"
+)
+
+// useTempJournal opens a journaled storage over an empty packets file in a
+// temporary directory and closes it when the test ends.
+func useTempJournal(t *testing.T, compactRecords int) *JournalFileSystem {
+	t.Helper()
+	useTempPacketsFile(t)
+	s, err := OpenJournalFileSystem(compactRecords, 0)
+	if err != nil {
+		t.Fatalf("open journal: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+// reopenJournal drops s without compacting, as a crash would, and loads the
+// packets file again.
+func reopenJournal(t *testing.T, s *JournalFileSystem) *JournalFileSystem {
+	t.Helper()
+	s.lock.Lock()
+	close(s.stop)
+	_ = s.journal.Close()
+	s.journal = nil
+	s.lock.Unlock()
+
+	r, err := OpenJournalFileSystem(s.compactRecords, 0)
+	if err != nil {
+		t.Fatalf("reopen journal: %v", err)
+	}
+	t.Cleanup(func() { _ = r.Close() })
+	return r
+}
+
+func TestJournalRecordCRUD(t *testing.T) {
+	testRecordCRUD(t, useTempJournal(t, 0))
+}
+
+func TestJournalConcurrentInsertUniqueIDs(t *testing.T) {
+	testConcurrentInsertUniqueIDs(t, useTempJournal(t, 0))
+}
+
+func TestJournalDeletedIDsAreNotReused(t *testing.T) {
+	testDeletedIDsAreNotReused(t, useTempJournal(t, 0))
+}
+
+func TestJournalReplayAfterCrash(t *testing.T) {
+	ctx := context.Background()
+	s := useTempJournal(t, 0)
+
+	in := []*packet.CloudPacket{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	if err := s.Insert(ctx, in); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	renamed := "a2"
+	if _, err := s.Patch(ctx, in[0].Id, PatchFields{Name: &renamed}); err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	if _, err := s.trash(ctx, []int32{in[2].Id}, "admin", time.Now()); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+
+	r := reopenJournal(t, s)
+	got, err := r.ReadPacket(ctx)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "a2" || got[1].Name != "b" {
+		t.Fatalf("unexpected state after replay: %+v", got)
+	}
+
+	next := &packet.CloudPacket{Name: "d"}
+	if err := r.Insert(ctx, []*packet.CloudPacket{next}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if next.Id != 4 {
+		t.Fatalf("expected id 4 after replay, got %d", next.Id)
+	}
+}
+
+func TestJournalCompaction(t *testing.T) {
+	ctx := context.Background()
+	s := useTempJournal(t, 3)
+
+	for i := 0; i < 4; i++ {
+		if err := s.Insert(ctx, []*packet.CloudPacket{{Name: "p"}}); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	if s.records != 1 {
+		t.Fatalf("expected 1 record after compaction, got %d", s.records)
+	}
+
+	snapshot, err := loadPacketsFile(s.path)
+	if err != nil {
+		t.Fatalf("load snapshot: %v", err)
+	}
+	if len(snapshot) != 3 {
+		t.Fatalf("expected 3 packets in snapshot, got %d", len(snapshot))
+	}
+
+	r := reopenJournal(t, s)
+	got, _ := r.ReadPacket(ctx)
+	if len(got) != 4 {
+		t.Fatalf("expected 4 packets after replay, got %d", len(got))
+	}
+}
+
+func TestJournalTornTail(t *testing.T) {
+	ctx := context.Background()
+	s := useTempJournal(t, 0)
+
+	if err := s.Insert(ctx, []*packet.CloudPacket{{Name: "a"}}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if _, err := s.journal.WriteString(`{"op":"insert","packets":[{"id":2,`); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	r := reopenJournal(t, s)
+	got, _ := r.ReadPacket(ctx)
+	if len(got) != 1 || got[0].Name != "a" {
+		t.Fatalf("unexpected state after torn tail: %+v", got)
+	}
+
+	if err := r.Insert(ctx, []*packet.CloudPacket{{Name: "b"}}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	r = reopenJournal(t, r)
+	if got, _ := r.ReadPacket(ctx); len(got) != 2 {
+		t.Fatalf("record after torn tail was lost: %+v", got)
+	}
+}
+
+func TestJournalReadReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	s := useTempJournal(t, 0)
+
+	if err := s.Insert(ctx, []*packet.CloudPacket{{Name: "a"}}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	got, _ := s.ReadPacket(ctx)
+	got[0].Name = "changed"
+	listed, _, _ := s.List(ctx, Filter{})
+	listed[0].Region = "changed"
+
+	if p, _ := s.Get(ctx, got[0].Id); p.Name != "a" || p.Region != "" {
+		t.Fatalf("stored packet was modified through a returned one: %+v", p)
+	}
+}
+
+func TestJournalCloseCompacts(t *testing.T) {
+	s := useTempJournal(t, 0)
+
+	if err := s.Insert(context.Background(), []*packet.CloudPacket{{Name: "a"}}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	path := s.path
+	if err := s.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	info, err := os.Stat(path + ".journal")
+	if err != nil || info.Size() != 0 {
+		t.Fatalf("journal not emptied on close: %v %v", err, info)
+	}
+	if snapshot, err := loadPacketsFile(path); err != nil || len(snapshot) != 1 {
+		t.Fatalf("snapshot after close: %v %+v", err, snapshot)
+	}
+}
+
+func TestJournalBackupIncludesUncompacted(t *testing.T) {
+	ctx := context.Background()
+	s := useTempJournal(t, 0)
+
+	if err := s.Insert(ctx, []*packet.CloudPacket{{Name: "a"}}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	data, err := s.Backup(ctx)
+	if err != nil || len(data.Packets) != 1 || data.Packets[0].Name != "a" {
+		t.Fatalf("backup: %v %+v", err, data)
+	}
+}
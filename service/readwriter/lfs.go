@@ -1,19 +1,22 @@
 package readwriter
 
 import (
-    "github.com/bytedance/sonic"
-    "github.com/pkg/errors"
-    "github.com/robfig/cron/v3"
-    "log"
-    "os"
-    "packet_cloud/biz/model/hertz/packet"
-    cfg "packet_cloud/config"
-    "sync"
-    "time"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/bytedance/sonic"
+	"github.com/pkg/errors"
+	"log"
+	"os"
+	"packet_cloud/biz/model/hertz/packet"
+	cfg "packet_cloud/config"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 var (
-    fileRelativePath = "./packets"
+	fileRelativePath = "./packets"
 )
 
 var (
@@ -23,83 +26,249 @@ var (
 type LocalFileSystem struct {
 }
 
-func (s *LocalFileSystem) ReadPacket() ([]*packet.CloudPacket, error) {
-    packets := make([]*packet.CloudPacket, 0)
-
+func (s *LocalFileSystem) ReadPacket(ctx context.Context) ([]*packet.CloudPacket, error) {
 	syncLock.RLock()
 	defer syncLock.RUnlock()
 
-    fileRelativePath = cfg.Get().PacketsFilePath
-    bytes, err := os.ReadFile(fileRelativePath)
+	return readPacketsFile()
+}
+
+func (s *LocalFileSystem) SavePacket(ctx context.Context, packets []*packet.CloudPacket) error {
+	syncLock.Lock()
+	defer syncLock.Unlock()
+
+	old, err := readPacketsFile()
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if err := touchRevisionsFile(append(packetIDs(old), packetIDs(packets)...)); err != nil {
+		return err
 	}
-	err = sonic.Unmarshal(bytes, &packets)
+	return writePacketsFile(packets)
+}
+
+func (s *LocalFileSystem) Get(ctx context.Context, id int32) (*packet.CloudPacket, error) {
+	packets, err := s.ReadPacket(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return packets, nil
-}
 
-func (s *LocalFileSystem) SavePacket(packets []*packet.CloudPacket) error {
-    bytes, err := sonic.Marshal(packets)
-    if err != nil {
-        return err
-    }
+	for _, p := range packets {
+		if p.Id == id {
+			return p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
 
+func (s *LocalFileSystem) Insert(ctx context.Context, inserted []*packet.CloudPacket) error {
 	syncLock.Lock()
 	defer syncLock.Unlock()
 
-    fileRelativePath = cfg.Get().PacketsFilePath
-    err = os.WriteFile(fileRelativePath, bytes, 0644)
+	packets, err := readPacketsFile()
+	if err != nil {
+		return err
+	}
+
+	last, err := readSequenceFile()
 	if err != nil {
 		return err
 	}
-	return nil
+	for _, p := range packets {
+		if p.Id > last {
+			last = p.Id
+		}
+	}
+	for _, p := range inserted {
+		last++
+		p.Id = last
+	}
+
+	if err := touchRevisionsFile(packetIDs(inserted)); err != nil {
+		return err
+	}
+	if err := writePacketsFile(append(packets, inserted...)); err != nil {
+		return err
+	}
+	return writeSequenceFile(last)
 }
 
-func (s *LocalFileSystem) Backup() error {
+func (s *LocalFileSystem) Update(ctx context.Context, updated *packet.CloudPacket) error {
+	syncLock.Lock()
+	defer syncLock.Unlock()
 
-	var (
-		c      = cron.New()
-		backup = time.Now().Format("20060102-15-04")
-	)
+	packets, err := readPacketsFile()
+	if err != nil {
+		return err
+	}
 
-	_, err := c.AddFunc("6 6 * * 4", func() {
-		packets, err := ReadPacket(LFS)
-		if err != nil {
-			log.Println("[Backup] read packets error:", err)
-			return
+	for i, p := range packets {
+		if p.Id == updated.Id {
+			if err := touchRevisionsFile([]int32{p.Id}); err != nil {
+				return err
+			}
+			packets[i] = updated
+			return writePacketsFile(packets)
 		}
+	}
+	return ErrNotFound
+}
 
-		bs, err := sonic.Marshal(packets)
-		if err != nil {
-			log.Println("[Backup] marshal packets error:", err)
-			return
-		}
+func (s *LocalFileSystem) Patch(ctx context.Context, id int32, fields PatchFields) (*packet.CloudPacket, error) {
+	syncLock.Lock()
+	defer syncLock.Unlock()
+
+	packets, err := readPacketsFile()
+	if err != nil {
+		return nil, err
+	}
 
-		// 备份文件
-		err = os.WriteFile(backup, bs, 0644)
-		if err != nil {
-			log.Println(err)
-			return
+	for _, p := range packets {
+		if p.Id == id {
+			if err := touchRevisionsFile([]int32{id}); err != nil {
+				return nil, err
+			}
+			fields.Apply(p)
+			return p, writePacketsFile(packets)
 		}
+	}
+	return nil, ErrNotFound
+}
+
+// List scans the packets file. The storage is always used through
+// IndexedStorage, which answers lists from its own index, so this only runs
+// when the backend is used directly.
+func (s *LocalFileSystem) List(ctx context.Context, filter Filter) ([]*packet.CloudPacket, int64, error) {
+	syncLock.RLock()
+	defer syncLock.RUnlock()
 
-		// 重置当前文件
-		err = os.WriteFile(fileRelativePath, []byte("[]"), 0644)
-		if err != nil {
-			log.Println(err)
-			return
+	packets, err := readPacketsFile()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	list, total := newPacketIndex(packets).query(filter)
+	return list, total, nil
+}
+
+// readPacketsFile loads the packets file. A missing file is an empty dataset.
+// When the file is corrupt, the previous generation is used instead.
+// Callers must hold syncLock.
+func readPacketsFile() ([]*packet.CloudPacket, error) {
+	path := cfg.Get().PacketsFilePath
+	packets, err := loadPacketsFile(path)
+	if os.IsNotExist(errors.Cause(err)) {
+		return make([]*packet.CloudPacket, 0), nil
+	}
+	if err == nil {
+		return packets, nil
+	}
+
+	prev := previousGenerationPath()
+	log.Printf("[LFS] !!! packets file %s is corrupt (%v), serving the previous generation %s; the next write replaces the corrupt file", path, err, prev)
+	packets, prevErr := loadPacketsFile(prev)
+	if prevErr != nil {
+		log.Printf("[LFS] !!! previous generation %s is unusable too: %v", prev, prevErr)
+		return nil, err
+	}
+	return packets, nil
+}
+
+// loadPacketsFile reads and verifies one generation of the packets file.
+func loadPacketsFile(path string) ([]*packet.CloudPacket, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	data, err := verifyChecksum(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	packets := make([]*packet.CloudPacket, 0)
+	if err := sonic.Unmarshal(data, &packets); err != nil {
+		return nil, errors.Wrap(err, "parse packets file")
+	}
+	return packets, nil
+}
+
+// packetsFileIntact reports whether path holds a complete packets file. It
+// checks the checksum, and only parses files written before checksums.
+func packetsFileIntact(path string) bool {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	if !bytes.HasPrefix(raw, []byte(checksumPrefix)) {
+		return json.Valid(raw)
+	}
+	_, err = verifyChecksum(raw)
+	return err == nil
+}
+
+// previousGenerationPath keeps the last intact packets file before the current
+// one, to fall back to when the current one is found corrupt.
+func previousGenerationPath() string {
+	return cfg.Get().PacketsFilePath + ".prev"
+}
+
+// writePacketsFile replaces the packets file atomically, keeping the current
+// one as the previous generation. Callers must hold syncLock for writing.
+func writePacketsFile(packets []*packet.CloudPacket) error {
+	bytes, err := sonic.Marshal(packets)
+	if err != nil {
+		return err
+	}
+
+	fileRelativePath = cfg.Get().PacketsFilePath
+	// A corrupt current file must not replace the good previous generation.
+	if packetsFileIntact(fileRelativePath) {
+		if err := keepGeneration(fileRelativePath, previousGenerationPath()); err != nil {
+			log.Println("[LFS] keep previous generation error", err)
 		}
+	}
+	return writeFileAtomic(fileRelativePath, withChecksum(bytes), 0644)
+}
 
-		log.Println("备份数据文件成功")
-	})
+// sequenceFilePath is where LFS persists the last allocated packet ID, so IDs
+// of deleted packets are never reused.
+func sequenceFilePath() string {
+	return cfg.Get().PacketsFilePath + ".seq"
+}
 
+// readSequenceFile returns the last allocated packet ID, or 0 when none was
+// recorded yet. Callers must hold syncLock.
+func readSequenceFile() (int32, error) {
+	bytes, err := os.ReadFile(sequenceFilePath())
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	last, err := strconv.ParseInt(strings.TrimSpace(string(bytes)), 10, 32)
 	if err != nil {
-		return errors.Wrap(err, "add cron error")
+		return 0, errors.Wrap(err, "parse sequence file")
 	}
+	return int32(last), nil
+}
+
+// writeSequenceFile records last as the last allocated packet ID. Callers must hold syncLock for writing.
+func writeSequenceFile(last int32) error {
+	return writeFileAtomic(sequenceFilePath(), []byte(strconv.FormatInt(int64(last), 10)), 0644)
+}
 
-	c.Start()
+func (s *LocalFileSystem) Backup(ctx context.Context) (*BackupData, error) {
+	syncLock.RLock()
+	defer syncLock.RUnlock()
 
-	return nil
+	packets, err := readPacketsFile()
+	if err != nil {
+		return nil, err
+	}
+	keys, err := readKeysFile()
+	if err != nil {
+		return nil, err
+	}
+	return &BackupData{Packets: packets, Keys: keys}, nil
 }
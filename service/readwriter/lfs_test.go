@@ -1,15 +1,18 @@
 package readwriter
 
 import (
+    "context"
     "encoding/json"
     "os"
     "path/filepath"
     "testing"
+    "time"
     cfg "packet_cloud/config"
     packet "packet_cloud/biz/model/hertz/packet"
 )
 
 func TestLFSReadWrite(t *testing.T) {
+    ctx := context.Background()
     dir := t.TempDir()
     fp := filepath.Join(dir, "packets.json")
     cp := filepath.Join(dir, "config.json")
@@ -18,10 +21,10 @@ func TestLFSReadWrite(t *testing.T) {
     _ = cfg.Load(cp)
     s := &LocalFileSystem{}
     data := []*packet.CloudPacket{{Id: 1, Region: "r1", Name: "n1", Channel: "c1", Uploader: "u1", Time: "t1", UserPackets: []*packet.UserPacket{{Id: 1, Name: "x", Content: "y", Size: 1, SendTiming: "z"}}}}
-    if err := s.SavePacket(data); err != nil {
+    if err := s.SavePacket(ctx, data); err != nil {
         t.Fatalf("save: %v", err)
     }
-    out, err := s.ReadPacket()
+    out, err := s.ReadPacket(ctx)
     if err != nil {
         t.Fatalf("read: %v", err)
     }
@@ -29,3 +32,105 @@ func TestLFSReadWrite(t *testing.T) {
         t.Fatalf("mismatch: %+v", out)
     }
 }
+
+func useTempPacketsFile(t testing.TB) string {
+    t.Helper()
+    dir := t.TempDir()
+    fp := filepath.Join(dir, "packets.json")
+    cp := filepath.Join(dir, "config.json")
+    b, _ := json.Marshal(cfg.Config{StorageMedia: "lfs", PacketsFilePath: fp})
+    _ = os.WriteFile(cp, b, 0644)
+    if err := cfg.Load(cp); err != nil {
+        t.Fatalf("load config: %v", err)
+    }
+    return fp
+}
+
+func TestLFSRecordCRUD(t *testing.T) {
+    ctx := context.Background()
+    useTempPacketsFile(t)
+    s := &LocalFileSystem{}
+
+    in := []*packet.CloudPacket{
+        {Region: "r1", Name: "a", Channel: "c1", Uploader: "u1", Time: "t1", UserPackets: []*packet.UserPacket{{Name: "x", Content: "y"}}},
+        {Region: "r2", Name: "b", Channel: "c2", Uploader: "u2", Time: "t2"},
+        {Region: "r1", Name: "c", Channel: "c3", Uploader: "u1", Time: "t3"},
+    }
+    if err := s.Insert(ctx, in); err != nil {
+        t.Fatalf("insert: %v", err)
+    }
+    if in[0].Id != 1 || in[1].Id != 2 || in[2].Id != 3 {
+        t.Fatalf("ids not assigned: %d %d %d", in[0].Id, in[1].Id, in[2].Id)
+    }
+
+    got, err := s.Get(ctx, 2)
+    if err != nil || got.Name != "b" {
+        t.Fatalf("get: %v %+v", err, got)
+    }
+    if _, err := s.Get(ctx, 42); err != ErrNotFound {
+        t.Fatalf("get missing: %v", err)
+    }
+
+    got.Name = "b2"
+    if err := s.Update(ctx, got); err != nil {
+        t.Fatalf("update: %v", err)
+    }
+    if got, _ := s.Get(ctx, 2); got.Name != "b2" {
+        t.Fatalf("update not persisted: %+v", got)
+    }
+    if err := s.Update(ctx, &packet.CloudPacket{Id: 42}); err != ErrNotFound {
+        t.Fatalf("update missing: %v", err)
+    }
+
+    listed, _, err := s.List(ctx, Filter{Region: "r1"})
+    if err != nil || len(listed) != 2 || listed[0].Id != 1 || listed[1].Id != 3 {
+        t.Fatalf("list: %v %+v", err, listed)
+    }
+
+    deleted, err := s.trash(ctx, []int32{1, 2}, "admin", time.Now())
+    if err != nil || len(deleted) != 2 {
+        t.Fatalf("delete: %v %v", err, deleted)
+    }
+    rest, _ := s.ReadPacket(ctx)
+    if len(rest) != 1 || rest[0].Id != 3 {
+        t.Fatalf("remaining: %+v", rest)
+    }
+}
+
+func TestLFSPatch(t *testing.T) {
+    ctx := context.Background()
+    useTempPacketsFile(t)
+    s := &LocalFileSystem{}
+
+    p := &packet.CloudPacket{Region: "r", Name: "n", Channel: "c", Uploader: "u", Time: "t", UserPackets: []*packet.UserPacket{{Name: "x", Content: "y"}}}
+    if err := s.Insert(ctx, []*packet.CloudPacket{p}); err != nil {
+        t.Fatalf("insert: %v", err)
+    }
+
+    name := "n2"
+    patched, err := s.Patch(ctx, p.Id, PatchFields{Name: &name})
+    if err != nil {
+        t.Fatalf("patch: %v", err)
+    }
+    if patched.Name != "n2" || patched.Region != "r" || len(patched.UserPackets) != 1 {
+        t.Fatalf("patch result: %+v", patched)
+    }
+
+    ups := []*packet.UserPacket{{Name: "a"}, {Name: "b"}}
+    patched, err = s.Patch(ctx, p.Id, PatchFields{UserPackets: &ups})
+    if err != nil || patched.Name != "n2" || len(patched.UserPackets) != 2 {
+        t.Fatalf("patch user packets: %v %+v", err, patched)
+    }
+
+    patched, err = s.Patch(ctx, p.Id, PatchFields{UserPackets: &[]*packet.UserPacket{}})
+    if err != nil || patched.Name != "n2" || len(patched.UserPackets) != 0 {
+        t.Fatalf("clear user packets: %v %+v", err, patched)
+    }
+    if got, _ := s.Get(ctx, p.Id); len(got.UserPackets) != 0 {
+        t.Fatalf("user packets left after clearing: %+v", got)
+    }
+
+    if _, err := s.Patch(ctx, 42, PatchFields{Name: &name}); err != ErrNotFound {
+        t.Fatalf("patch missing: %v", err)
+    }
+}
@@ -0,0 +1,387 @@
+package readwriter
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	cfg "packet_cloud/config"
+	"packet_cloud/db"
+)
+
+// Errors returned by Migrator.Check.
+var (
+	ErrSchemaAhead  = errors.New("database schema is newer than this binary")
+	ErrSchemaBehind = errors.New("database schema has pending migrations")
+	ErrSchemaDirty  = errors.New("a migration failed halfway")
+)
+
+// autoMigrateBaseline is the version a database set up by AutoMigrate, before
+// migrations were tracked, is assumed to be at. Such a database has the
+// tables of 001 but, depending on the binary that created it, not the keys of
+// 002 and 003 or the table of 004, since AutoMigrate never changes a primary
+// key; those migrations therefore also work on a schema they already
+// describe.
+const autoMigrateBaseline = 1
+
+// Migration is one versioned schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	// Down undoes Up; it is empty when the migration cannot be undone.
+	Down string
+}
+
+func (mg Migration) String() string {
+	return fmt.Sprintf("%03d_%s", mg.Version, mg.Name)
+}
+
+// SchemaMigration records an applied migration. Dirty is set while it runs, so
+// a migration that failed halfway is noticed.
+type SchemaMigration struct {
+	Version   int    `gorm:"primaryKey;autoIncrement:false;column:version"`
+	Name      string `gorm:"column:name;type:varchar(255)"`
+	Dirty     bool   `gorm:"column:dirty"`
+	AppliedAt string `gorm:"column:applied_at;type:varchar(32)"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// LoadMigrations reads NNN_name.sql and NNN_name.down.sql files from fsys,
+// ordered by version.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, file := range files {
+		base := strings.TrimSuffix(file, ".sql")
+		down := strings.HasSuffix(base, ".down")
+		base = strings.TrimSuffix(base, ".down")
+
+		num, name, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if err != nil || version <= 0 {
+			return nil, errors.Errorf("migration %s: name must start with a version number", file)
+		}
+		body, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, errors.Errorf("migration %d has two names: %s and %s", version, m.Name, name)
+		}
+		if down {
+			m.Down = string(body)
+		} else {
+			m.Up = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, errors.Errorf("migration %s has no up file", m)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// splitStatements splits a migration into statements on semicolons that end a
+// line, dropping "--" comment lines.
+func splitStatements(sql string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+	)
+	for _, line := range strings.Split(sql, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+// Migrator applies migrations to a database and records them in the
+// schema_migrations table.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// OpenMigrator connects to the configured MySQL database, for the migrate
+// command. Call Close when done.
+func OpenMigrator() (*Migrator, error) {
+	dsn := mysqlDSN()
+	if dsn == "" {
+		return nil, errors.New("MySQL.DSN is not configured")
+	}
+	gdb := mustOpenWithRetry(dsn, 1, 1, intOr(cfg.Get().MySQL.ConnMaxLifetimeMin, 30))
+	if gdb == nil {
+		return nil, errors.New("connect to mysql failed")
+	}
+	return NewMigrator(gdb)
+}
+
+// migrateOnOpen brings the schema up to date when a MySQL storage is opened,
+// or with MySQL.SkipMigrations only checks that it is.
+func migrateOnOpen(gdb *gorm.DB) error {
+	m, err := NewMigrator(gdb)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if cfg.Get().MySQL.SkipMigrations {
+		return m.Check(ctx)
+	}
+	done, err := m.Up(ctx)
+	for _, mg := range done {
+		log.Printf("[Migrate] applied %s", mg)
+	}
+	return err
+}
+
+// NewMigrator returns a Migrator for the migrations embedded in the binary.
+func NewMigrator(gdb *gorm.DB) (*Migrator, error) {
+	sub, err := fs.Sub(db.Migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := LoadMigrations(sub)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: gdb, migrations: migrations}, nil
+}
+
+// Close closes the database connection.
+func (m *Migrator) Close() error {
+	return closeDB(m.db)
+}
+
+// Migrations returns the migrations known to the binary in version order.
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Latest is the version of the newest migration known to the binary.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Applied returns the recorded migrations in version order, creating the
+// table on first use. A database that already holds the packet tables but
+// no record was set up by AutoMigrate and is recorded at autoMigrateBaseline.
+func (m *Migrator) Applied(ctx context.Context) ([]SchemaMigration, error) {
+	tx := m.db.WithContext(ctx)
+	if !tx.Migrator().HasTable(&SchemaMigration{}) {
+		legacy := tx.Migrator().HasTable(&CloudPacketModel{})
+		if err := tx.Migrator().CreateTable(&SchemaMigration{}); err != nil {
+			return nil, errors.Wrap(err, "create schema_migrations")
+		}
+		if legacy {
+			log.Printf("[Migrate] !!! found packet tables without schema_migrations, assuming the schema of migration %d", autoMigrateBaseline)
+			if err := m.recordThrough(ctx, autoMigrateBaseline); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var applied []SchemaMigration
+	if err := tx.Order("version ASC").Find(&applied).Error; err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// Version returns the newest applied version and whether it failed halfway.
+func (m *Migrator) Version(ctx context.Context) (int, bool, error) {
+	applied, err := m.Applied(ctx)
+	if err != nil || len(applied) == 0 {
+		return 0, false, err
+	}
+	last := applied[len(applied)-1]
+	return last.Version, last.Dirty, nil
+}
+
+// Check returns ErrSchemaDirty, ErrSchemaAhead or ErrSchemaBehind unless the
+// database is exactly at Latest.
+func (m *Migrator) Check(ctx context.Context) error {
+	version, dirty, err := m.Version(ctx)
+	switch {
+	case err != nil:
+		return err
+	case dirty:
+		return errors.Wrapf(ErrSchemaDirty, "version %d", version)
+	case version > m.Latest():
+		return errors.Wrapf(ErrSchemaAhead, "database at %d, binary knows %d", version, m.Latest())
+	case version < m.Latest():
+		return errors.Wrapf(ErrSchemaBehind, "database at %d, binary knows %d", version, m.Latest())
+	}
+	return nil
+}
+
+// Up applies every pending migration and returns them. It refuses to run on a
+// database that is dirty or ahead of the binary.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	if err := m.Check(ctx); !errors.Is(err, ErrSchemaBehind) {
+		return nil, err
+	}
+	version, _, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, mg := range m.migrations {
+		if mg.Version <= version {
+			continue
+		}
+		if err := m.run(ctx, mg, false); err != nil {
+			return done, errors.Wrapf(err, "migration %s", mg)
+		}
+		done = append(done, mg)
+	}
+	return done, nil
+}
+
+// Down undoes the newest steps migrations and returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	for i := 0; i < steps; i++ {
+		version, dirty, err := m.Version(ctx)
+		if err != nil {
+			return done, err
+		}
+		if dirty {
+			return done, errors.Wrapf(ErrSchemaDirty, "version %d", version)
+		}
+		if version == 0 {
+			return done, nil
+		}
+
+		mg, ok := m.find(version)
+		if !ok {
+			return done, errors.Wrapf(ErrSchemaAhead, "no migration %d in this binary", version)
+		}
+		if mg.Down == "" {
+			return done, errors.Errorf("migration %s cannot be undone", mg)
+		}
+		if err := m.run(ctx, mg, true); err != nil {
+			return done, errors.Wrapf(err, "undo migration %s", mg)
+		}
+		done = append(done, mg)
+	}
+	return done, nil
+}
+
+// Force records version as the current, clean state without running anything,
+// for repairing a dirty database by hand or adopting an existing one.
+func (m *Migrator) Force(ctx context.Context, version int) error {
+	if _, ok := m.find(version); !ok && version != 0 {
+		return errors.Errorf("no migration %d in this binary", version)
+	}
+	if _, err := m.Applied(ctx); err != nil {
+		return err
+	}
+	return m.recordThrough(ctx, version)
+}
+
+// recordThrough makes the records say exactly the migrations up to version
+// were applied.
+func (m *Migrator) recordThrough(ctx context.Context, version int) error {
+	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("version > ?", version).Delete(&SchemaMigration{}).Error; err != nil {
+			return err
+		}
+		for _, mg := range m.migrations {
+			if mg.Version > version {
+				break
+			}
+			row := SchemaMigration{Version: mg.Version, Name: mg.Name, AppliedAt: time.Now().Format(time.RFC3339)}
+			if err := tx.Save(&row).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// run executes the up or, with undo, the down statements of mg. MySQL commits
+// every DDL statement on its own, so the record is marked dirty first and
+// cleaned once all statements succeeded.
+func (m *Migrator) run(ctx context.Context, mg Migration, undo bool) error {
+	if err := m.record(ctx, mg, true); err != nil {
+		return err
+	}
+
+	sql := mg.Up
+	if undo {
+		sql = mg.Down
+	}
+	// One connection, so session settings such as FOREIGN_KEY_CHECKS hold
+	// for the statements after them.
+	err := m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		for i, stmt := range splitStatements(sql) {
+			if err := conn.Exec(stmt).Error; err != nil {
+				return errors.Wrapf(err, "statement %d", i+1)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if undo {
+		return m.db.WithContext(ctx).Delete(&SchemaMigration{}, mg.Version).Error
+	}
+	return m.record(ctx, mg, false)
+}
+
+func (m *Migrator) record(ctx context.Context, mg Migration, dirty bool) error {
+	row := SchemaMigration{Version: mg.Version, Name: mg.Name, Dirty: dirty, AppliedAt: time.Now().Format(time.RFC3339)}
+	return m.db.WithContext(ctx).Save(&row).Error
+}
+
+func (m *Migrator) find(version int) (Migration, bool) {
+	for _, mg := range m.migrations {
+		if mg.Version == version {
+			return mg, true
+		}
+	}
+	return Migration{}, false
+}
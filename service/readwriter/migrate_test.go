@@ -0,0 +1,269 @@
+package readwriter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/glebarez/sqlite"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	cfg "packet_cloud/config"
+)
+
+var testMigrations = fstest.MapFS{
+	"001_init.sql":      {Data: []byte("-- first\nCREATE TABLE a (id INT);\nCREATE TABLE b (\n  id INT\n);")},
+	"001_init.down.sql": {Data: []byte("DROP TABLE b;\nDROP TABLE a;")},
+	"002_c.sql":         {Data: []byte("CREATE TABLE c (id INT);")},
+	"002_c.down.sql":    {Data: []byte("DROP TABLE c;")},
+	"003_broken.sql":    {Data: []byte("CREATE TABLE d (id INT);\nNOT SQL;")},
+}
+
+// useTestMigrator returns a Migrator for fsys over an empty SQLite database.
+func useTestMigrator(t *testing.T, fsys fstest.MapFS) *Migrator {
+	t.Helper()
+	gdb, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "m.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	m := &Migrator{db: gdb, migrations: migrations}
+	t.Cleanup(func() { _ = m.Close() })
+	return m
+}
+
+func withoutBroken() fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for k, v := range testMigrations {
+		if !strings.HasPrefix(k, "003") {
+			fsys[k] = v
+		}
+	}
+	return fsys
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	m := useTestMigrator(t, withoutBroken())
+
+	if err := m.Check(ctx); !errors.Is(err, ErrSchemaBehind) {
+		t.Fatalf("fresh database: %v", err)
+	}
+	done, err := m.Up(ctx)
+	if err != nil || len(done) != 2 {
+		t.Fatalf("up: %v %v", err, done)
+	}
+	if err := m.Check(ctx); err != nil {
+		t.Fatalf("check after up: %v", err)
+	}
+	if !m.db.Migrator().HasTable("c") {
+		t.Fatal("table c missing")
+	}
+	if done, err := m.Up(ctx); err != nil || len(done) != 0 {
+		t.Fatalf("second up: %v %v", err, done)
+	}
+
+	done, err = m.Down(ctx, 1)
+	if err != nil || len(done) != 1 || done[0].Version != 2 {
+		t.Fatalf("down: %v %v", err, done)
+	}
+	if m.db.Migrator().HasTable("c") {
+		t.Fatal("table c still there")
+	}
+	if version, _, _ := m.Version(ctx); version != 1 {
+		t.Fatalf("expected version 1, got %d", version)
+	}
+	if _, err := m.Down(ctx, 5); err != nil {
+		t.Fatalf("down to zero: %v", err)
+	}
+	if m.db.Migrator().HasTable("a") {
+		t.Fatal("table a still there")
+	}
+}
+
+func TestMigrateFailureLeavesDirty(t *testing.T) {
+	ctx := context.Background()
+	m := useTestMigrator(t, testMigrations)
+
+	done, err := m.Up(ctx)
+	if err == nil || len(done) != 2 {
+		t.Fatalf("expected the broken migration to fail after two: %v %v", err, done)
+	}
+	if err := m.Check(ctx); !errors.Is(err, ErrSchemaDirty) {
+		t.Fatalf("check: %v", err)
+	}
+	if _, err := m.Up(ctx); !errors.Is(err, ErrSchemaDirty) {
+		t.Fatalf("up on a dirty database: %v", err)
+	}
+
+	// After fixing the database by hand the operator records where it is.
+	if err := m.Force(ctx, 2); err != nil {
+		t.Fatalf("force: %v", err)
+	}
+	if version, dirty, _ := m.Version(ctx); version != 2 || dirty {
+		t.Fatalf("after force: %d %v", version, dirty)
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	ctx := context.Background()
+	m := useTestMigrator(t, withoutBroken())
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+
+	first, err := LoadMigrations(fstest.MapFS{"001_init.sql": testMigrations["001_init.sql"]})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	older := &Migrator{db: m.db, migrations: first}
+	if err := older.Check(ctx); !errors.Is(err, ErrSchemaAhead) {
+		t.Fatalf("check: %v", err)
+	}
+	if _, err := older.Up(ctx); !errors.Is(err, ErrSchemaAhead) {
+		t.Fatalf("up: %v", err)
+	}
+}
+
+// baselineCloudPacketModel and baselineUserPacketModel are the models the
+// first release migrated with AutoMigrate: user_packets was keyed by an
+// AUTO_INCREMENT id alone.
+type baselineCloudPacketModel struct {
+	ID          int32                     `gorm:"primaryKey;column:id"`
+	Region      string                    `gorm:"column:region;type:varchar(32);index:idx_region"`
+	Name        string                    `gorm:"column:name;type:varchar(64)"`
+	Channel     string                    `gorm:"column:channel;type:varchar(32);index:idx_channel"`
+	Uploader    string                    `gorm:"column:uploader;type:varchar(64);index:idx_uploader;index:idx_uploader_time"`
+	Time        string                    `gorm:"column:time;type:varchar(32);index:idx_time;index:idx_uploader_time"`
+	UserPackets []baselineUserPacketModel `gorm:"foreignKey:CloudPacketID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+func (baselineCloudPacketModel) TableName() string {
+	return "cloud_packets"
+}
+
+type baselineUserPacketModel struct {
+	ID            int32  `gorm:"primaryKey;column:id"`
+	CloudPacketID int32  `gorm:"column:cloud_packet_id;index:idx_cloud_packet_id"`
+	Name          string `gorm:"column:name;type:varchar(64)"`
+	Content       string `gorm:"column:content;type:longtext"`
+	Size          int32  `gorm:"column:size"`
+	SendTiming    string `gorm:"column:send_timing;type:varchar(32)"`
+}
+
+func (baselineUserPacketModel) TableName() string {
+	return "user_packets"
+}
+
+func TestMigrateAdoptsAutoMigrateDatabase(t *testing.T) {
+	ctx := context.Background()
+	m := useTestMigrator(t, fstest.MapFS{})
+	m.migrations = embeddedMigrations(t)
+	if err := m.db.AutoMigrate(&baselineCloudPacketModel{}, &baselineUserPacketModel{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+
+	// Only the tables of 001 can be assumed; the keys of 002 and 003 are
+	// missing from such a database.
+	if version, _, err := m.Version(ctx); err != nil || version != 1 {
+		t.Fatalf("expected version 1, got %d %v", version, err)
+	}
+	applied, _ := m.Applied(ctx)
+	if len(applied) != 1 || applied[0].Version != 1 {
+		t.Fatalf("expected only 001 recorded: %+v", applied)
+	}
+}
+
+// TestMySQLMigrateAutoMigrateDatabase brings a database set up by the first
+// release up to date. It recreates the tables of the configured database.
+func TestMySQLMigrateAutoMigrateDatabase(t *testing.T) {
+	if cfg.Get().MySQL.DSN == "" {
+		t.Skip("mysql dsn missing")
+	}
+	ctx := context.Background()
+	m, err := OpenMigrator()
+	if err != nil {
+		t.Skipf("mysql not available: %v", err)
+	}
+	t.Cleanup(func() { _ = m.Close() })
+
+	for _, table := range []string{"schema_migrations", "user_packets", "cloud_packets", "api_keys", "packet_revisions", "revision_counter", "deleted_packets"} {
+		if err := m.db.Exec("DROP TABLE IF EXISTS `" + table + "`").Error; err != nil {
+			t.Fatalf("drop %s: %v", table, err)
+		}
+	}
+	if err := m.db.AutoMigrate(&baselineCloudPacketModel{}, &baselineUserPacketModel{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	if err := m.db.Create(&baselineCloudPacketModel{ID: 1, UserPackets: []baselineUserPacketModel{{Name: "a"}, {Name: "b"}}}).Error; err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if err := m.Check(ctx); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	var models []CloudPacketModel
+	if err := m.db.Preload("UserPackets", orderByPosition).Find(&models).Error; err != nil || len(models) != 1 || len(models[0].UserPackets) != 2 {
+		t.Fatalf("packets after migrating: %v %+v", err, models)
+	}
+}
+
+// embeddedMigrations loads the migrations embedded in the binary.
+func embeddedMigrations(t *testing.T) []Migration {
+	t.Helper()
+	m, err := NewMigrator(nil)
+	if err != nil {
+		t.Fatalf("load embedded migrations: %v", err)
+	}
+	return m.migrations
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations := embeddedMigrations(t)
+	for i, mg := range migrations {
+		if mg.Version != i+1 {
+			t.Fatalf("migration %s out of sequence", mg)
+		}
+		if mg.Down == "" {
+			t.Fatalf("migration %s has no down file", mg)
+		}
+		for _, stmt := range splitStatements(mg.Up) {
+			if strings.HasPrefix(strings.ToUpper(stmt), "USE ") {
+				t.Fatalf("migration %s selects a database; the DSN does that", mg)
+			}
+		}
+	}
+
+	latest := migrations[len(migrations)-1]
+	schema, err := os.ReadFile("../../db/schema.sql")
+	if err != nil {
+		t.Fatalf("read schema: %v", err)
+	}
+	if !strings.Contains(string(schema), "after migration "+latest.String()) {
+		t.Fatalf("db/schema.sql is not updated to %s", latest)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	got := splitStatements("-- comment\nCREATE TABLE a (\n  id INT\n);\n\nDROP TABLE b;\nSELECT 1")
+	want := []string{"CREATE TABLE a (\n  id INT\n);", "DROP TABLE b;", "SELECT 1"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
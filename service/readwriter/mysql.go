@@ -2,19 +2,23 @@ package readwriter
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
 	"packet_cloud/biz/model/hertz/packet"
 	cfg "packet_cloud/config"
+	"strings"
+	"sync"
 	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
 type CloudPacketModel struct {
-	ID          int32             `gorm:"primaryKey;column:id"`
+	ID          int32             `gorm:"primaryKey;autoIncrement;column:id"`
 	Region      string            `gorm:"column:region;type:varchar(32);index:idx_region"`
 	Name        string            `gorm:"column:name;type:varchar(64)"`
 	Channel     string            `gorm:"column:channel;type:varchar(32);index:idx_channel"`
@@ -27,9 +31,13 @@ func (CloudPacketModel) TableName() string {
 	return "cloud_packets"
 }
 
+// UserPacketModel is keyed by (cloud_packet_id, position), the entry's
+// position inside its cloud packet; id is the ID the client sent, which need
+// not be unique.
 type UserPacketModel struct {
-	ID            int32  `gorm:"primaryKey;column:id"`
-	CloudPacketID int32  `gorm:"column:cloud_packet_id;index:idx_cloud_packet_id"`
+	ID            int32  `gorm:"column:id;not null"`
+	CloudPacketID int32  `gorm:"primaryKey;autoIncrement:false;column:cloud_packet_id;index:idx_cloud_packet_id"`
+	Position      int32  `gorm:"primaryKey;autoIncrement:false;column:position"`
 	Name          string `gorm:"column:name;type:varchar(64)"`
 	Content       string `gorm:"column:content;type:longtext"`
 	Size          int32  `gorm:"column:size"`
@@ -43,18 +51,41 @@ func (UserPacketModel) TableName() string {
 type MySQLStorage struct {
 	writeDB       *gorm.DB
 	readDB        *gorm.DB
-	cache         []*packet.CloudPacket
-	cacheAt       time.Time
-	cacheTTL      time.Duration
 	slowThreshold time.Duration
 	queryTimeout  time.Duration
+	// connLock queues the operations of a storage with a single connection,
+	// SQLite, so the time spent waiting for it does not count against
+	// queryTimeout. It is nil for MySQL.
+	connLock *sync.Mutex
 }
 
-func NewMySQLStorageFromConfig() *MySQLStorage {
-	writeDSN := cfg.Get().MySQL.DSN
-	if writeDSN == "" {
-		writeDSN = os.Getenv("MYSQL_DSN")
+// withTimeout bounds an operation by queryTimeout, after waiting for the
+// connection when the storage has a single one.
+func (s *MySQLStorage) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.connLock == nil {
+		return context.WithTimeout(ctx, s.queryTimeout)
+	}
+	s.connLock.Lock()
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	return ctx, func() {
+		cancel()
+		s.connLock.Unlock()
+	}
+}
+
+// mysqlDSN returns the configured write DSN, falling back to MYSQL_DSN.
+func mysqlDSN() string {
+	if dsn := cfg.Get().MySQL.DSN; dsn != "" {
+		return dsn
 	}
+	return os.Getenv("MYSQL_DSN")
+}
+
+// NewMySQLStorageFromConfig opens new connection pools to the configured
+// database, retrying for a few seconds while it is unreachable. The server
+// does this once at startup; see Open.
+func NewMySQLStorageFromConfig() *MySQLStorage {
+	writeDSN := mysqlDSN()
 	if writeDSN == "" {
 		return nil
 	}
@@ -66,7 +97,6 @@ func NewMySQLStorageFromConfig() *MySQLStorage {
 	maxOpen := intOr(cfg.Get().MySQL.MaxOpen, 20)
 	maxIdle := intOr(cfg.Get().MySQL.MaxIdle, 10)
 	lifeMin := intOr(cfg.Get().MySQL.ConnMaxLifetimeMin, 30)
-	cacheTTLms := intOr(cfg.Get().MySQL.QueryCacheTTLms, 500)
 	slowMS := intOr(cfg.Get().MySQL.SlowQueryMs, 200)
 	qTimeoutMS := intOr(cfg.Get().MySQL.QueryTimeoutMs, 3000)
 
@@ -75,9 +105,10 @@ func NewMySQLStorageFromConfig() *MySQLStorage {
 		return nil
 	}
 
-	// Auto Migrate
-	if err := wdb.AutoMigrate(&CloudPacketModel{}, &UserPacketModel{}); err != nil {
-		log.Printf("AutoMigrate error: %v", err)
+	if err := migrateOnOpen(wdb); err != nil {
+		log.Printf("[Migrate] %v", err)
+		_ = closeDB(wdb)
+		return nil
 	}
 
 	var rdb *gorm.DB
@@ -93,24 +124,19 @@ func NewMySQLStorageFromConfig() *MySQLStorage {
 	return &MySQLStorage{
 		writeDB:       wdb,
 		readDB:        rdb,
-		cacheTTL:      time.Duration(cacheTTLms) * time.Millisecond,
 		slowThreshold: time.Duration(slowMS) * time.Millisecond,
 		queryTimeout:  time.Duration(qTimeoutMS) * time.Millisecond,
 	}
 }
 
-func (s *MySQLStorage) ReadPacket() ([]*packet.CloudPacket, error) {
-	if s.cache != nil && time.Since(s.cacheAt) < s.cacheTTL {
-		return s.cache, nil
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+func (s *MySQLStorage) ReadPacket(ctx context.Context) ([]*packet.CloudPacket, error) {
+	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
 	start := time.Now()
 	var models []CloudPacketModel
 	// Preload UserPackets to avoid N+1 query
-	err := s.readDB.WithContext(ctx).Preload("UserPackets").Order("id ASC").Find(&models).Error
+	err := s.readDB.WithContext(ctx).Preload("UserPackets", orderByPosition).Order("id ASC").Find(&models).Error
 	if err != nil {
 		return nil, err
 	}
@@ -120,38 +146,25 @@ func (s *MySQLStorage) ReadPacket() ([]*packet.CloudPacket, error) {
 	}
 
 	packets := make([]*packet.CloudPacket, len(models))
-	for i, m := range models {
-		ups := make([]*packet.UserPacket, len(m.UserPackets))
-		for j, um := range m.UserPackets {
-			ups[j] = &packet.UserPacket{
-				Id:         um.ID,
-				Name:       um.Name,
-				Content:    um.Content,
-				Size:       um.Size,
-				SendTiming: um.SendTiming,
-			}
-		}
-		packets[i] = &packet.CloudPacket{
-			Id:          m.ID,
-			Region:      m.Region,
-			Name:        m.Name,
-			Channel:     m.Channel,
-			Uploader:    m.Uploader,
-			Time:        m.Time,
-			UserPackets: ups,
-		}
+	for i := range models {
+		packets[i] = fromModel(&models[i])
 	}
-
-	s.cache = packets
-	s.cacheAt = time.Now()
 	return packets, nil
 }
 
-func (s *MySQLStorage) SavePacket(packets []*packet.CloudPacket) error {
-	ctx, cancel := context.WithTimeout(context.Background(), s.queryTimeout)
+func (s *MySQLStorage) SavePacket(ctx context.Context, packets []*packet.CloudPacket) error {
+	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
 	return s.writeDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var oldIDs []int32
+		if err := tx.Model(&CloudPacketModel{}).Pluck("id", &oldIDs).Error; err != nil {
+			return err
+		}
+		if err := touchRevisions(tx, append(oldIDs, packetIDs(packets)...)); err != nil {
+			return err
+		}
+
 		// Clear existing data to match LFS overwrite behavior
 		if err := tx.Exec("DELETE FROM user_packets").Error; err != nil {
 			return err
@@ -167,26 +180,7 @@ func (s *MySQLStorage) SavePacket(packets []*packet.CloudPacket) error {
 		// Convert to models
 		models := make([]CloudPacketModel, len(packets))
 		for i, p := range packets {
-			ums := make([]UserPacketModel, len(p.UserPackets))
-			for j, up := range p.UserPackets {
-				ums[j] = UserPacketModel{
-					ID:            up.Id,
-					CloudPacketID: p.Id,
-					Name:          up.Name,
-					Content:       up.Content,
-					Size:          up.Size,
-					SendTiming:    up.SendTiming,
-				}
-			}
-			models[i] = CloudPacketModel{
-				ID:          p.Id,
-				Region:      p.Region,
-				Name:        p.Name,
-				Channel:     p.Channel,
-				Uploader:    p.Uploader,
-				Time:        p.Time,
-				UserPackets: ums,
-			}
+			models[i] = toModel(p)
 		}
 
 		// Batch insert
@@ -201,10 +195,264 @@ func (s *MySQLStorage) SavePacket(packets []*packet.CloudPacket) error {
 	})
 }
 
-func (s *MySQLStorage) Backup() error {
+func (s *MySQLStorage) Get(ctx context.Context, id int32) (*packet.CloudPacket, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var m CloudPacketModel
+	err := s.readDB.WithContext(ctx).Preload("UserPackets", orderByPosition).Where("id = ?", id).First(&m).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fromModel(&m), nil
+}
+
+func (s *MySQLStorage) Insert(ctx context.Context, packets []*packet.CloudPacket) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// IDs come from the cloud_packets AUTO_INCREMENT column, so concurrent
+	// inserts never collide and deleted IDs are never handed out again.
+	models := make([]CloudPacketModel, len(packets))
+	for i, p := range packets {
+		models[i] = toModel(p)
+		models[i].ID = 0
+	}
+	err := s.writeDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models).Error; err != nil {
+			return err
+		}
+		ids := make([]int32, len(models))
+		for i := range models {
+			ids[i] = models[i].ID
+		}
+		return touchRevisions(tx, ids)
+	})
+	if err != nil {
+		return err
+	}
+	for i := range models {
+		packets[i].Id = models[i].ID
+	}
+
+	return nil
+}
+
+func (s *MySQLStorage) Update(ctx context.Context, p *packet.CloudPacket) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	err := s.writeDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&CloudPacketModel{}).Where("id = ?", p.Id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+		if err := touchRevisions(tx, []int32{p.Id}); err != nil {
+			return err
+		}
+
+		m := toModel(p)
+		err := tx.Model(&CloudPacketModel{}).Where("id = ?", p.Id).Updates(map[string]interface{}{
+			"region":   m.Region,
+			"name":     m.Name,
+			"channel":  m.Channel,
+			"uploader": m.Uploader,
+			"time":     m.Time,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("cloud_packet_id = ?", p.Id).Delete(&UserPacketModel{}).Error; err != nil {
+			return err
+		}
+		if len(m.UserPackets) == 0 {
+			return nil
+		}
+		return tx.Create(&m.UserPackets).Error
+	})
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+func (s *MySQLStorage) Patch(ctx context.Context, id int32, fields PatchFields) (*packet.CloudPacket, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var patched *packet.CloudPacket
+	err := s.writeDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var m CloudPacketModel
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("UserPackets", orderByPosition).Where("id = ?", id).First(&m).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := touchRevisions(tx, []int32{id}); err != nil {
+			return err
+		}
+
+		patched = fromModel(&m)
+		fields.Apply(patched)
+		updated := toModel(patched)
+
+		err = tx.Model(&CloudPacketModel{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"region":  updated.Region,
+			"name":    updated.Name,
+			"channel": updated.Channel,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		if fields.UserPackets == nil {
+			return nil
+		}
+		if err := tx.Where("cloud_packet_id = ?", id).Delete(&UserPacketModel{}).Error; err != nil {
+			return err
+		}
+		if len(updated.UserPackets) == 0 {
+			return nil
+		}
+		return tx.Create(&updated.UserPackets).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return patched, nil
+}
+
+func (s *MySQLStorage) List(ctx context.Context, filter Filter) ([]*packet.CloudPacket, int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+	q := s.readDB.WithContext(ctx).Model(&CloudPacketModel{})
+	if filter.Region != "" {
+		q = q.Where("region = ?", filter.Region)
+	}
+	if filter.Channel != "" {
+		q = q.Where("channel = ?", filter.Channel)
+	}
+	if filter.Uploader != "" {
+		q = q.Where("uploader = ?", filter.Uploader)
+	}
+	if filter.Name != "" {
+		q = q.Where("name LIKE ? ESCAPE '!'", "%"+likeEscaper.Replace(filter.Name)+"%")
+	}
+	if filter.TimeFrom != "" {
+		q = q.Where("time >= ?", filter.TimeFrom)
+	}
+	if filter.TimeTo != "" {
+		q = q.Where("time <= ?", filter.TimeTo)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order := "id ASC"
+	switch {
+	case filter.SortBy == SortByTime && filter.Desc:
+		order = "time DESC, id DESC"
+	case filter.SortBy == SortByTime:
+		order = "time ASC, id ASC"
+	case filter.Desc:
+		order = "id DESC"
+	}
+	q = q.Order(order)
+	if filter.Offset > 0 {
+		q = q.Offset(filter.Offset)
+	}
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+	if !filter.Summary {
+		q = q.Preload("UserPackets", orderByPosition)
+	}
+
+	var models []CloudPacketModel
+	if err := q.Find(&models).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if dur := time.Since(start); dur > s.slowThreshold {
+		log.Printf("slow query List dur=%s filter=%+v", dur, filter)
+	}
+
+	packets := make([]*packet.CloudPacket, len(models))
+	for i := range models {
+		packets[i] = fromModel(&models[i])
+	}
+	return packets, total, nil
+}
+
+// Backup reads the packets and API keys in one transaction from the primary,
+// so a replica that lags behind does not leave recent writes out.
+func (s *MySQLStorage) Backup(ctx context.Context) (*BackupData, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var (
+		models []CloudPacketModel
+		keys   []APIKeyModel
+	)
+	err := s.writeDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("UserPackets", orderByPosition).Order("id ASC").Find(&models).Error; err != nil {
+			return err
+		}
+		return tx.Order("id ASC").Find(&keys).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := &BackupData{
+		Packets: make([]*packet.CloudPacket, len(models)),
+		Keys:    make([]*APIKey, len(keys)),
+	}
+	for i := range models {
+		data.Packets[i] = fromModel(&models[i])
+	}
+	for i := range keys {
+		data.Keys[i] = (*APIKey)(&keys[i])
+	}
+	return data, nil
+}
+
+// Close closes the connection pools.
+func (s *MySQLStorage) Close() error {
+	var err error
+	if s.readDB != s.writeDB {
+		err = closeDB(s.readDB)
+	}
+	if werr := closeDB(s.writeDB); err == nil {
+		err = werr
+	}
+	return err
+}
+
+func closeDB(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
 func mustOpenWithRetry(dsn string, maxOpen, maxIdle, lifeMin int) *gorm.DB {
 	var db *gorm.DB
 	var err error
@@ -236,6 +484,60 @@ func mustOpenWithRetry(dsn string, maxOpen, maxIdle, lifeMin int) *gorm.DB {
 	return nil
 }
 
+// likeEscaper escapes the LIKE wildcards in user supplied substrings. The
+// escape character is given explicitly since MySQL and SQLite default differently.
+var likeEscaper = strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`)
+
+func orderByPosition(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
+
+func fromModel(m *CloudPacketModel) *packet.CloudPacket {
+	ups := make([]*packet.UserPacket, len(m.UserPackets))
+	for j, um := range m.UserPackets {
+		ups[j] = &packet.UserPacket{
+			Id:         um.ID,
+			Name:       um.Name,
+			Content:    um.Content,
+			Size:       um.Size,
+			SendTiming: um.SendTiming,
+		}
+	}
+	return &packet.CloudPacket{
+		Id:          m.ID,
+		Region:      m.Region,
+		Name:        m.Name,
+		Channel:     m.Channel,
+		Uploader:    m.Uploader,
+		Time:        m.Time,
+		UserPackets: ups,
+	}
+}
+
+func toModel(p *packet.CloudPacket) CloudPacketModel {
+	ums := make([]UserPacketModel, len(p.UserPackets))
+	for j, up := range p.UserPackets {
+		ums[j] = UserPacketModel{
+			ID:            up.Id,
+			CloudPacketID: p.Id,
+			Position:      int32(j),
+			Name:          up.Name,
+			Content:       up.Content,
+			Size:          up.Size,
+			SendTiming:    up.SendTiming,
+		}
+	}
+	return CloudPacketModel{
+		ID:          p.Id,
+		Region:      p.Region,
+		Name:        p.Name,
+		Channel:     p.Channel,
+		Uploader:    p.Uploader,
+		Time:        p.Time,
+		UserPackets: ums,
+	}
+}
+
 func intOr(x, def int) int {
 	if x == 0 {
 		return def
@@ -1,6 +1,7 @@
 package readwriter
 
 import (
+	"context"
 	"testing"
 	packet "packet_cloud/biz/model/hertz/packet"
 	cfg "packet_cloud/config"
@@ -8,6 +9,7 @@ import (
 )
 
 func TestMySQLReadWrite(t *testing.T) {
+	ctx := context.Background()
 	x := cfg.Get()
 	if x.MySQL.DSN == "" {
 		t.Skip("mysql dsn missing")
@@ -30,10 +32,10 @@ func TestMySQLReadWrite(t *testing.T) {
     }
 
 	in := []*packet.CloudPacket{{Id: 1, Region: "r", Name: "n", Channel: "c", Uploader: "u", Time: "t", UserPackets: []*packet.UserPacket{{Id: 10, Name: "a", Content: "b", Size: 2, SendTiming: "s"}}}}
-	if err := s.SavePacket(in); err != nil {
+	if err := s.SavePacket(ctx, in); err != nil {
 		t.Fatalf("save: %v", err)
 	}
-	out, err := s.ReadPacket()
+	out, err := s.ReadPacket(ctx)
 	if err != nil {
 		t.Fatalf("read: %v", err)
 	}
@@ -41,3 +43,61 @@ func TestMySQLReadWrite(t *testing.T) {
 		t.Fatalf("mismatch: %+v", out)
 	}
 }
+
+func TestMySQLRecordCRUD(t *testing.T) {
+	if cfg.Get().MySQL.DSN == "" {
+		t.Skip("mysql dsn missing")
+	}
+	s := NewMySQLStorageFromConfig()
+	if s == nil {
+		t.Skip("mysql not available")
+	}
+	if err := s.SavePacket(context.Background(), nil); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+
+	testRecordCRUD(t, s)
+}
+
+// testRecordCRUD runs the record level operations against an empty s.
+func testRecordCRUD(t *testing.T, s ReadWriter) {
+	ctx := context.Background()
+	in := []*packet.CloudPacket{
+		{Region: "r1", Name: "a", Channel: "c1", Uploader: "u1", Time: "t1", UserPackets: []*packet.UserPacket{{Name: "x", Content: "y"}, {Id: 1, Name: "z", Content: "w"}}},
+		{Region: "r2", Name: "b", Channel: "c2", Uploader: "u2", Time: "t2", UserPackets: []*packet.UserPacket{{Name: "x", Content: "y"}}},
+	}
+	if err := s.Insert(ctx, in); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	got, err := s.Get(ctx, in[0].Id)
+	if err != nil || len(got.UserPackets) != 2 || got.UserPackets[1].Content != "w" {
+		t.Fatalf("get: %v %+v", err, got)
+	}
+
+	got.Name = "a2"
+	got.UserPackets = got.UserPackets[:1]
+	if err := s.Update(ctx, got); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got, _ := s.Get(ctx, in[0].Id); got.Name != "a2" || len(got.UserPackets) != 1 {
+		t.Fatalf("update not persisted: %+v", got)
+	}
+
+	listed, _, err := s.List(ctx, Filter{Region: "r2"})
+	if err != nil || len(listed) != 1 || listed[0].Id != in[1].Id {
+		t.Fatalf("list: %v %+v", err, listed)
+	}
+
+	ix, ok := s.(*IndexedStorage)
+	if !ok {
+		ix = NewIndexedStorage(s)
+	}
+	deleted, err := ix.Trash(ctx, Selector{IDs: []int32{in[0].Id}}, "admin")
+	if err != nil || len(deleted) != 1 {
+		t.Fatalf("delete: %v %v", err, deleted)
+	}
+	if _, err := s.Get(ctx, in[0].Id); err != ErrNotFound {
+		t.Fatalf("get deleted: %v", err)
+	}
+}
@@ -0,0 +1,675 @@
+package readwriter
+
+import (
+	"context"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"packet_cloud/biz/model/hertz/packet"
+	cfg "packet_cloud/config"
+)
+
+// DeletedPacket is a packet in the recycle bin.
+type DeletedPacket struct {
+	Packet    *packet.CloudPacket `json:"packet"`
+	DeletedBy string              `json:"deleted_by"`
+	DeletedAt time.Time           `json:"deleted_at"`
+}
+
+// recycleBin is implemented by backends that keep deleted packets until they
+// are purged. Packets in the bin are no longer part of the dataset, so reads,
+// backups and revisions treat them as deleted; restoring brings them back
+// under their old IDs.
+type recycleBin interface {
+	// trash moves the packets with the given IDs into the bin and returns the
+	// IDs it found.
+	trash(ctx context.Context, ids []int32, by string, at time.Time) ([]int32, error)
+	// trashed returns the packets in the bin, ascending by ID.
+	trashed(ctx context.Context) ([]*DeletedPacket, error)
+	// restore moves the packets with the given IDs back, except those whose
+	// ID is in use again, e.g. after a backup was restored, and returns them.
+	restore(ctx context.Context, ids []int32) ([]*packet.CloudPacket, error)
+	// purge removes the packets with the given IDs from the bin for good.
+	purge(ctx context.Context, ids []int32) ([]int32, error)
+	// purgeBefore removes the packets deleted before t for good.
+	purgeBefore(ctx context.Context, t time.Time) ([]int32, error)
+}
+
+var errNoRecycleBin = errors.New("storage has no recycle bin")
+
+// Select returns the IDs of the packets sel selects, ascending.
+func (s *IndexedStorage) Select(ctx context.Context, sel Selector) ([]int32, error) {
+	if err := sel.Validate(); err != nil {
+		return nil, err
+	}
+	ix, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.selectIDs(ix, sel.matcher()), nil
+}
+
+// selectIDs returns the IDs of the packets in ix match selects, ascending.
+func (s *IndexedStorage) selectIDs(ix *packetIndex, match func(p *packet.CloudPacket) bool) []int32 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	ids := make([]int32, 0)
+	for _, id := range ix.ids {
+		if match(ix.byID[id]) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Trash moves the packets sel selects into the recycle bin, recording who
+// deleted them, and returns their IDs.
+func (s *IndexedStorage) Trash(ctx context.Context, sel Selector, by string) ([]int32, error) {
+	if err := sel.Validate(); err != nil {
+		return nil, err
+	}
+	rb, ok := s.backend.(recycleBin)
+	if !ok {
+		return nil, errNoRecycleBin
+	}
+
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	ix, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := s.selectIDs(ix, sel.matcher())
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	trashed, err := rb.trash(ctx, ids, by, time.Now())
+	if err := s.apply(ctx, err, trashed, func(ix *packetIndex) {
+		for _, id := range trashed {
+			ix.remove(id)
+		}
+	}); err != nil {
+		return nil, err
+	}
+	return trashed, nil
+}
+
+func (s *IndexedStorage) ListTrash(ctx context.Context) ([]*DeletedPacket, error) {
+	rb, ok := s.backend.(recycleBin)
+	if !ok {
+		return nil, errNoRecycleBin
+	}
+	return rb.trashed(ctx)
+}
+
+// RestoreTrash moves the packets with the given IDs out of the recycle bin
+// and returns the IDs it restored.
+func (s *IndexedStorage) RestoreTrash(ctx context.Context, ids []int32) ([]int32, error) {
+	rb, ok := s.backend.(recycleBin)
+	if !ok {
+		return nil, errNoRecycleBin
+	}
+
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	restored, err := rb.restore(ctx, ids)
+	if err := s.apply(ctx, err, packetIDs(restored), func(ix *packetIndex) {
+		for _, p := range restored {
+			ix.put(copyPacket(p, false))
+		}
+	}); err != nil {
+		return nil, err
+	}
+	return packetIDs(restored), nil
+}
+
+func (s *IndexedStorage) PurgeTrash(ctx context.Context, ids []int32) ([]int32, error) {
+	rb, ok := s.backend.(recycleBin)
+	if !ok {
+		return nil, errNoRecycleBin
+	}
+
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	return rb.purge(ctx, ids)
+}
+
+func (s *IndexedStorage) PurgeTrashBefore(ctx context.Context, t time.Time) ([]int32, error) {
+	rb, ok := s.backend.(recycleBin)
+	if !ok {
+		return nil, errNoRecycleBin
+	}
+
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	return rb.purgeBefore(ctx, t)
+}
+
+// indexedStorageOf returns the IndexedStorage of media.
+func indexedStorageOf(media StorageMedia) (*IndexedStorage, error) {
+	rw := newReadWriter(media)
+	if rw == nil {
+		return nil, errors.New("readWriter is nil")
+	}
+	ix, ok := rw.(*IndexedStorage)
+	if !ok {
+		return nil, errNoRecycleBin
+	}
+	return ix, nil
+}
+
+func Select(ctx context.Context, sel Selector, media StorageMedia) ([]int32, error) {
+	ix, err := indexedStorageOf(media)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := ix.Select(ctx, sel)
+	if err != nil {
+		return nil, errors.Wrapf(err, "select packet error")
+	}
+
+	return ids, nil
+}
+
+func Trash(ctx context.Context, sel Selector, by string, media StorageMedia) ([]int32, error) {
+	ix, err := indexedStorageOf(media)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := ix.Trash(ctx, sel, by)
+	if err != nil {
+		return nil, errors.Wrapf(err, "trash packet error")
+	}
+
+	return ids, nil
+}
+
+func ListTrash(ctx context.Context, media StorageMedia) ([]*DeletedPacket, error) {
+	ix, err := indexedStorageOf(media)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, err := ix.ListTrash(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "list recycle bin error")
+	}
+
+	return deleted, nil
+}
+
+func RestoreTrash(ctx context.Context, ids []int32, media StorageMedia) ([]int32, error) {
+	ix, err := indexedStorageOf(media)
+	if err != nil {
+		return nil, err
+	}
+
+	restored, err := ix.RestoreTrash(ctx, ids)
+	if err != nil {
+		return nil, errors.Wrapf(err, "restore packet error")
+	}
+
+	return restored, nil
+}
+
+func PurgeTrash(ctx context.Context, ids []int32, media StorageMedia) ([]int32, error) {
+	ix, err := indexedStorageOf(media)
+	if err != nil {
+		return nil, err
+	}
+
+	purged, err := ix.PurgeTrash(ctx, ids)
+	if err != nil {
+		return nil, errors.Wrapf(err, "purge packet error")
+	}
+
+	return purged, nil
+}
+
+func PurgeTrashBefore(ctx context.Context, t time.Time, media StorageMedia) ([]int32, error) {
+	ix, err := indexedStorageOf(media)
+	if err != nil {
+		return nil, err
+	}
+
+	purged, err := ix.PurgeTrashBefore(ctx, t)
+	if err != nil {
+		return nil, errors.Wrapf(err, "purge recycle bin error")
+	}
+
+	return purged, nil
+}
+
+// idSet returns ids as a set.
+func idSet(ids []int32) map[int32]bool {
+	set := make(map[int32]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// trashFilePath is where LFS and the journal keep the recycle bin.
+func trashFilePath() string {
+	return cfg.Get().PacketsFilePath + ".trash"
+}
+
+// readTrashFile loads the recycle bin. A missing file is an empty bin.
+// Callers must hold syncLock.
+func readTrashFile() ([]*DeletedPacket, error) {
+	deleted := make([]*DeletedPacket, 0)
+	bytes, err := os.ReadFile(trashFilePath())
+	if os.IsNotExist(err) {
+		return deleted, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := sonic.Unmarshal(bytes, &deleted); err != nil {
+		return nil, errors.Wrap(err, "parse recycle bin file")
+	}
+	return deleted, nil
+}
+
+// writeTrashFile replaces the recycle bin. Callers must hold syncLock for writing.
+func writeTrashFile(deleted []*DeletedPacket) error {
+	sort.Slice(deleted, func(i, j int) bool { return deleted[i].Packet.Id < deleted[j].Packet.Id })
+	bytes, err := sonic.Marshal(deleted)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(trashFilePath(), bytes, 0644)
+}
+
+// addToTrash puts packets into the recycle bin file, replacing entries with
+// the same ID. Callers must hold syncLock for writing.
+func addToTrash(packets []*packet.CloudPacket, by string, at time.Time) error {
+	deleted, err := readTrashFile()
+	if err != nil {
+		return err
+	}
+	replaced := idSet(packetIDs(packets))
+	kept := deleted[:0]
+	for _, d := range deleted {
+		if !replaced[d.Packet.Id] {
+			kept = append(kept, d)
+		}
+	}
+	for _, p := range packets {
+		kept = append(kept, &DeletedPacket{Packet: p, DeletedBy: by, DeletedAt: at})
+	}
+	return writeTrashFile(kept)
+}
+
+// takeFromTrash splits the recycle bin into the packets with the given IDs
+// that live does not hold and everything else. Callers must hold syncLock.
+func takeFromTrash(ids []int32, live func(id int32) bool) (taken []*packet.CloudPacket, kept []*DeletedPacket, err error) {
+	deleted, err := readTrashFile()
+	if err != nil {
+		return nil, nil, err
+	}
+	wanted := idSet(ids)
+	kept = deleted[:0]
+	for _, d := range deleted {
+		if wanted[d.Packet.Id] && !live(d.Packet.Id) {
+			taken = append(taken, d.Packet)
+		} else {
+			kept = append(kept, d)
+		}
+	}
+	return taken, kept, nil
+}
+
+// purgeTrashFile removes the entries match selects from the recycle bin file.
+func purgeTrashFile(match func(d *DeletedPacket) bool) ([]int32, error) {
+	syncLock.Lock()
+	defer syncLock.Unlock()
+
+	deleted, err := readTrashFile()
+	if err != nil {
+		return nil, err
+	}
+	purged := make([]int32, 0)
+	kept := deleted[:0]
+	for _, d := range deleted {
+		if match(d) {
+			purged = append(purged, d.Packet.Id)
+		} else {
+			kept = append(kept, d)
+		}
+	}
+	if len(purged) == 0 {
+		return purged, nil
+	}
+	return purged, writeTrashFile(kept)
+}
+
+func (s *LocalFileSystem) trash(ctx context.Context, ids []int32, by string, at time.Time) ([]int32, error) {
+	syncLock.Lock()
+	defer syncLock.Unlock()
+
+	packets, err := readPacketsFile()
+	if err != nil {
+		return nil, err
+	}
+
+	selected := idSet(ids)
+	moved := make([]*packet.CloudPacket, 0, len(ids))
+	remaining := make([]*packet.CloudPacket, 0, len(packets))
+	for _, p := range packets {
+		if selected[p.Id] {
+			moved = append(moved, p)
+		} else {
+			remaining = append(remaining, p)
+		}
+	}
+	trashed := packetIDs(moved)
+	if len(trashed) == 0 {
+		return trashed, nil
+	}
+
+	if err := touchRevisionsFile(trashed); err != nil {
+		return nil, err
+	}
+	// The bin is written first: a crash in between leaves the packets in both
+	// places rather than in neither.
+	if err := addToTrash(moved, by, at); err != nil {
+		return nil, err
+	}
+	return trashed, writePacketsFile(remaining)
+}
+
+func (s *LocalFileSystem) trashed(ctx context.Context) ([]*DeletedPacket, error) {
+	syncLock.RLock()
+	defer syncLock.RUnlock()
+
+	return readTrashFile()
+}
+
+func (s *LocalFileSystem) restore(ctx context.Context, ids []int32) ([]*packet.CloudPacket, error) {
+	syncLock.Lock()
+	defer syncLock.Unlock()
+
+	packets, err := readPacketsFile()
+	if err != nil {
+		return nil, err
+	}
+	live := idSet(packetIDs(packets))
+	restored, kept, err := takeFromTrash(ids, func(id int32) bool { return live[id] })
+	if err != nil || len(restored) == 0 {
+		return nil, err
+	}
+
+	if err := touchRevisionsFile(packetIDs(restored)); err != nil {
+		return nil, err
+	}
+	packets = append(packets, restored...)
+	sortPackets(packets)
+	if err := writePacketsFile(packets); err != nil {
+		return nil, err
+	}
+	return restored, writeTrashFile(kept)
+}
+
+func (s *LocalFileSystem) purge(ctx context.Context, ids []int32) ([]int32, error) {
+	selected := idSet(ids)
+	return purgeTrashFile(func(d *DeletedPacket) bool { return selected[d.Packet.Id] })
+}
+
+func (s *LocalFileSystem) purgeBefore(ctx context.Context, t time.Time) ([]int32, error) {
+	return purgeTrashFile(func(d *DeletedPacket) bool { return d.DeletedAt.Before(t) })
+}
+
+// trash records the packets in the bin, then journals their removal.
+func (s *JournalFileSystem) trash(ctx context.Context, ids []int32, by string, at time.Time) ([]int32, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	moved := make([]*packet.CloudPacket, 0, len(ids))
+	for _, id := range ids {
+		if i, ok := s.find(id); ok {
+			moved = append(moved, copyPacket(s.packets[i], false))
+		}
+	}
+	trashed := packetIDs(moved)
+	if len(trashed) == 0 {
+		return trashed, nil
+	}
+
+	syncLock.Lock()
+	err := addToTrash(moved, by, at)
+	syncLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return trashed, s.commit(&journalRecord{Op: journalDelete, IDs: trashed})
+}
+
+// restore journals the packets back in, then takes them out of the bin.
+func (s *JournalFileSystem) restore(ctx context.Context, ids []int32) ([]*packet.CloudPacket, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	syncLock.RLock()
+	restored, kept, err := takeFromTrash(ids, func(id int32) bool {
+		_, ok := s.find(id)
+		return ok
+	})
+	syncLock.RUnlock()
+	if err != nil || len(restored) == 0 {
+		return nil, err
+	}
+
+	if err := s.commit(&journalRecord{Op: journalInsert, Packets: restored}); err != nil {
+		return nil, err
+	}
+
+	syncLock.Lock()
+	defer syncLock.Unlock()
+	out := make([]*packet.CloudPacket, len(restored))
+	for i, p := range restored {
+		out[i] = copyPacket(p, false)
+	}
+	return out, writeTrashFile(kept)
+}
+
+// DeletedPacketModel is a packet in the recycle bin. Its user packets are
+// kept as JSON, since the bin is only read whole.
+type DeletedPacketModel struct {
+	ID          int32  `gorm:"primaryKey;autoIncrement:false;column:id"`
+	Region      string `gorm:"column:region;type:varchar(32)"`
+	Name        string `gorm:"column:name;type:varchar(64)"`
+	Channel     string `gorm:"column:channel;type:varchar(32)"`
+	Uploader    string `gorm:"column:uploader;type:varchar(64)"`
+	Time        string `gorm:"column:time;type:varchar(32)"`
+	UserPackets string `gorm:"column:user_packets;type:longtext"`
+	DeletedBy   string `gorm:"column:deleted_by;type:varchar(64)"`
+	DeletedAt   int64  `gorm:"column:deleted_at;index:idx_deleted_at"`
+}
+
+func (DeletedPacketModel) TableName() string {
+	return "deleted_packets"
+}
+
+func toDeletedModel(p *packet.CloudPacket, by string, at time.Time) (DeletedPacketModel, error) {
+	ups, err := sonic.Marshal(p.UserPackets)
+	if err != nil {
+		return DeletedPacketModel{}, err
+	}
+	return DeletedPacketModel{
+		ID:          p.Id,
+		Region:      p.Region,
+		Name:        p.Name,
+		Channel:     p.Channel,
+		Uploader:    p.Uploader,
+		Time:        p.Time,
+		UserPackets: string(ups),
+		DeletedBy:   by,
+		DeletedAt:   at.Unix(),
+	}, nil
+}
+
+func fromDeletedModel(m *DeletedPacketModel) (*DeletedPacket, error) {
+	ups := make([]*packet.UserPacket, 0)
+	if err := sonic.UnmarshalString(m.UserPackets, &ups); err != nil {
+		return nil, errors.Wrapf(err, "parse user packets of deleted packet %d", m.ID)
+	}
+	return &DeletedPacket{
+		Packet: &packet.CloudPacket{
+			Id:          m.ID,
+			Region:      m.Region,
+			Name:        m.Name,
+			Channel:     m.Channel,
+			Uploader:    m.Uploader,
+			Time:        m.Time,
+			UserPackets: ups,
+		},
+		DeletedBy: m.DeletedBy,
+		DeletedAt: time.Unix(m.DeletedAt, 0),
+	}, nil
+}
+
+func (s *MySQLStorage) trash(ctx context.Context, ids []int32, by string, at time.Time) ([]int32, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	trashed := make([]int32, 0)
+	err := s.writeDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var models []CloudPacketModel
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("UserPackets", orderByPosition).
+			Where("id IN ?", ids).Order("id ASC").Find(&models).Error
+		if err != nil || len(models) == 0 {
+			return err
+		}
+
+		rows := make([]DeletedPacketModel, len(models))
+		for i := range models {
+			if rows[i], err = toDeletedModel(fromModel(&models[i]), by, at); err != nil {
+				return err
+			}
+			trashed = append(trashed, models[i].ID)
+		}
+		if err := touchRevisions(tx, trashed); err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&rows).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("cloud_packet_id IN ?", trashed).Delete(&UserPacketModel{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", trashed).Delete(&CloudPacketModel{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return trashed, nil
+}
+
+func (s *MySQLStorage) trashed(ctx context.Context) ([]*DeletedPacket, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var models []DeletedPacketModel
+	if err := s.readDB.WithContext(ctx).Order("id ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	deleted := make([]*DeletedPacket, len(models))
+	for i := range models {
+		d, err := fromDeletedModel(&models[i])
+		if err != nil {
+			return nil, err
+		}
+		deleted[i] = d
+	}
+	return deleted, nil
+}
+
+func (s *MySQLStorage) restore(ctx context.Context, ids []int32) ([]*packet.CloudPacket, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var restored []*packet.CloudPacket
+	err := s.writeDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rows []DeletedPacketModel
+		if err := tx.Where("id IN ?", ids).Order("id ASC").Find(&rows).Error; err != nil || len(rows) == 0 {
+			return err
+		}
+		var liveIDs []int32
+		if err := tx.Model(&CloudPacketModel{}).Where("id IN ?", ids).Pluck("id", &liveIDs).Error; err != nil {
+			return err
+		}
+		live := idSet(liveIDs)
+
+		models := make([]CloudPacketModel, 0, len(rows))
+		for i := range rows {
+			if live[rows[i].ID] {
+				continue
+			}
+			d, err := fromDeletedModel(&rows[i])
+			if err != nil {
+				return err
+			}
+			restored = append(restored, d.Packet)
+			models = append(models, toModel(d.Packet))
+		}
+		if len(models) == 0 {
+			return nil
+		}
+
+		restoredIDs := packetIDs(restored)
+		if err := touchRevisions(tx, restoredIDs); err != nil {
+			return err
+		}
+		if err := tx.Create(&models).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", restoredIDs).Delete(&DeletedPacketModel{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+func (s *MySQLStorage) purge(ctx context.Context, ids []int32) ([]int32, error) {
+	return s.purgeWhere(ctx, "id IN ?", ids)
+}
+
+func (s *MySQLStorage) purgeBefore(ctx context.Context, t time.Time) ([]int32, error) {
+	return s.purgeWhere(ctx, "deleted_at < ?", t.Unix())
+}
+
+// purgeWhere removes the entries of the recycle bin matching the condition.
+func (s *MySQLStorage) purgeWhere(ctx context.Context, query string, arg interface{}) ([]int32, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	purged := make([]int32, 0)
+	err := s.writeDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&DeletedPacketModel{}).Where(query, arg).Order("id ASC").Pluck("id", &purged).Error
+		if err != nil || len(purged) == 0 {
+			return err
+		}
+		return tx.Where("id IN ?", purged).Delete(&DeletedPacketModel{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,133 @@
+package readwriter
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	packet "packet_cloud/biz/model/hertz/packet"
+)
+
+func TestLFSRecycleBin(t *testing.T) {
+	useTempPacketsFile(t)
+	testRecycleBin(t, &LocalFileSystem{})
+}
+
+func TestJournalRecycleBin(t *testing.T) {
+	testRecycleBin(t, useTempJournal(t, 0))
+}
+
+func TestSQLiteRecycleBin(t *testing.T) {
+	testRecycleBin(t, useTempSQLite(t))
+}
+
+func testRecycleBin(t *testing.T, backend ReadWriter) {
+	ctx := context.Background()
+	s := NewIndexedStorage(backend)
+
+	in := []*packet.CloudPacket{
+		{Name: "a", Region: "cn", UserPackets: []*packet.UserPacket{{Name: "u", Content: "c", Size: 1}}},
+		{Name: "b"},
+		{Name: "c"},
+	}
+	if err := s.Insert(ctx, in); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	a, b := in[0].Id, in[1].Id
+	rev, _ := s.Revision(ctx)
+
+	trashed, err := s.Trash(ctx, Selector{From: a, To: b}, "alice")
+	if err != nil || !reflect.DeepEqual(trashed, []int32{a, b}) {
+		t.Fatalf("trash: %v %v", trashed, err)
+	}
+	if _, err := s.Get(ctx, a); err != ErrNotFound {
+		t.Fatalf("trashed packet still readable: %v", err)
+	}
+	if packets, _ := s.ReadPacket(ctx); len(packets) != 1 {
+		t.Fatalf("trashed packets still listed: %d", len(packets))
+	}
+	cs, err := s.Changes(ctx, rev)
+	if err != nil || !reflect.DeepEqual(cs.Deleted, []int32{a, b}) {
+		t.Fatalf("changes after trash: %+v %v", cs, err)
+	}
+
+	deleted, err := s.ListTrash(ctx)
+	if err != nil || len(deleted) != 2 {
+		t.Fatalf("list trash: %+v %v", deleted, err)
+	}
+	d := deleted[0]
+	if d.Packet.Id != a || d.Packet.Region != "cn" || d.DeletedBy != "alice" || time.Since(d.DeletedAt) > time.Minute {
+		t.Fatalf("deleted packet: %+v", d)
+	}
+	if len(d.Packet.UserPackets) != 1 || d.Packet.UserPackets[0].Content != "c" {
+		t.Fatalf("user packets of the deleted packet: %+v", d.Packet.UserPackets)
+	}
+
+	restored, err := s.RestoreTrash(ctx, []int32{a, 999})
+	if err != nil || !reflect.DeepEqual(restored, []int32{a}) {
+		t.Fatalf("restore: %v %v", restored, err)
+	}
+	got, err := s.Get(ctx, a)
+	if err != nil || got.Name != "a" || len(got.UserPackets) != 1 || got.UserPackets[0].Content != "c" {
+		t.Fatalf("restored packet: %+v %v", got, err)
+	}
+	if list, _, _ := s.List(ctx, Filter{Region: "cn"}); len(list) != 1 || list[0].Id != a {
+		t.Fatalf("restored packet not indexed: %+v", list)
+	}
+
+	// A packet trashed again replaces its old entry.
+	if _, err := s.Trash(ctx, Selector{IDs: []int32{a}}, "bob"); err != nil {
+		t.Fatalf("trash again: %v", err)
+	}
+	if deleted, _ = s.ListTrash(ctx); len(deleted) != 2 || deleted[0].DeletedBy != "bob" {
+		t.Fatalf("trash after trashing again: %+v", deleted)
+	}
+
+	purged, err := s.PurgeTrash(ctx, []int32{b})
+	if err != nil || !reflect.DeepEqual(purged, []int32{b}) {
+		t.Fatalf("purge: %v %v", purged, err)
+	}
+	if restored, _ := s.RestoreTrash(ctx, []int32{b}); len(restored) != 0 {
+		t.Fatalf("purged packet restored: %v", restored)
+	}
+
+	if purged, _ := s.PurgeTrashBefore(ctx, time.Now().Add(-time.Hour)); len(purged) != 0 {
+		t.Fatalf("purged recent packets: %v", purged)
+	}
+	purged, err = s.PurgeTrashBefore(ctx, time.Now().Add(time.Hour))
+	if err != nil || !reflect.DeepEqual(purged, []int32{a}) {
+		t.Fatalf("purge before: %v %v", purged, err)
+	}
+	if deleted, _ = s.ListTrash(ctx); len(deleted) != 0 {
+		t.Fatalf("recycle bin not empty: %+v", deleted)
+	}
+}
+
+func TestRestoreSkipsReusedIDs(t *testing.T) {
+	ctx := context.Background()
+	useTempPacketsFile(t)
+	s := NewIndexedStorage(&LocalFileSystem{})
+
+	if err := s.Insert(ctx, []*packet.CloudPacket{{Name: "old"}}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if _, err := s.Trash(ctx, Selector{IDs: []int32{1}}, "alice"); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+	// Restoring a backup may bring the ID back.
+	if err := s.SavePacket(ctx, []*packet.CloudPacket{{Id: 1, Name: "new"}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	restored, err := s.RestoreTrash(ctx, []int32{1})
+	if err != nil || len(restored) != 0 {
+		t.Fatalf("restore over a live packet: %v %v", restored, err)
+	}
+	if got, _ := s.Get(ctx, 1); got.Name != "new" {
+		t.Fatalf("live packet replaced: %+v", got)
+	}
+	if deleted, _ := s.ListTrash(ctx); len(deleted) != 1 {
+		t.Fatalf("skipped packet left the recycle bin: %+v", deleted)
+	}
+}
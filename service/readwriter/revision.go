@@ -0,0 +1,505 @@
+package readwriter
+
+import (
+	"context"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"packet_cloud/biz/model/hertz/packet"
+	cfg "packet_cloud/config"
+)
+
+// ChangeSet is what changed after a revision, as returned by Changes.
+type ChangeSet struct {
+	// Revision is the current revision; clients pass it as since next time.
+	Revision int64
+	// Reset is set when since was 0 or newer than Revision, e.g. after the
+	// data was replaced; Packets then holds every packet and the client
+	// should drop its copy.
+	Reset bool
+	// Packets were inserted or updated, without their user packets.
+	Packets []*packet.CloudPacket
+	// Deleted are the IDs of deleted packets.
+	Deleted []int32
+}
+
+// revisionLog is implemented by backends that record the revision at which
+// each packet last changed. Every write bumps the revision once and records it
+// for the IDs it touched, before or together with the change itself, so a
+// failed write at worst reports an unchanged packet as changed.
+type revisionLog interface {
+	// revisions returns the IDs touched after since and the current revision.
+	revisions(ctx context.Context, since int64) ([]int32, int64, error)
+	// modified returns when the packet with the given ID last changed, or
+	// with id 0 when the current revision was made. It is zero if unknown.
+	modified(ctx context.Context, id int32) (time.Time, error)
+}
+
+// Changes returns the packets changed after revision since. It holds the write
+// lock while reading the revisions and the index, so both describe the same
+// writes.
+func (s *IndexedStorage) Changes(ctx context.Context, since int64) (*ChangeSet, error) {
+	return s.changes(ctx, since, since <= 0)
+}
+
+// ChangesAfter is like Changes, but since 0 is the revision before the first
+// recorded write rather than a request for everything. It is for callers that
+// got since from Revision.
+func (s *IndexedStorage) ChangesAfter(ctx context.Context, since int64) (*ChangeSet, error) {
+	return s.changes(ctx, since, since < 0)
+}
+
+func (s *IndexedStorage) changes(ctx context.Context, since int64, reset bool) (*ChangeSet, error) {
+	rl, ok := s.backend.(revisionLog)
+	if !ok {
+		return nil, errors.New("storage does not record revisions")
+	}
+
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	ids, rev, err := rl.revisions(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	ix, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	cs := &ChangeSet{Revision: rev, Packets: make([]*packet.CloudPacket, 0), Deleted: make([]int32, 0)}
+	if reset || since > rev {
+		cs.Reset = true
+		for _, id := range ix.ids {
+			cs.Packets = append(cs.Packets, copyPacket(ix.byID[id], true))
+		}
+		return cs, nil
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		if p, ok := ix.byID[id]; ok {
+			cs.Packets = append(cs.Packets, copyPacket(p, true))
+		} else {
+			cs.Deleted = append(cs.Deleted, id)
+		}
+	}
+	return cs, nil
+}
+
+func Changes(ctx context.Context, since int64, media StorageMedia) (*ChangeSet, error) {
+	rw := newReadWriter(media)
+	if rw == nil {
+		return nil, errors.New("readWriter is nil")
+	}
+	ix, ok := rw.(*IndexedStorage)
+	if !ok {
+		return nil, errors.New("storage does not record revisions")
+	}
+
+	cs, err := ix.Changes(ctx, since)
+	if err != nil {
+		return nil, errors.Wrapf(err, "list changes error")
+	}
+
+	return cs, nil
+}
+
+func ChangesAfter(ctx context.Context, since int64, media StorageMedia) (*ChangeSet, error) {
+	rw := newReadWriter(media)
+	if rw == nil {
+		return nil, errors.New("readWriter is nil")
+	}
+	ix, ok := rw.(*IndexedStorage)
+	if !ok {
+		return nil, errors.New("storage does not record revisions")
+	}
+
+	cs, err := ix.ChangesAfter(ctx, since)
+	if err != nil {
+		return nil, errors.Wrapf(err, "list changes error")
+	}
+
+	return cs, nil
+}
+
+// revisionCache holds what conditional reads need from the revision log, so
+// they do not read the backend. IndexedStorage keeps it current in apply.
+type revisionCache struct {
+	revision int64
+	modified time.Time
+	// packets holds the modification times looked up so far, by packet ID.
+	packets map[int32]time.Time
+}
+
+// advance returns the cache after a write that touched ids and left the log
+// at head. Without head, as after a failed read, the cache is dropped.
+func (rc *revisionCache) advance(head *revisionCache, ids []int32) *revisionCache {
+	if head == nil {
+		return nil
+	}
+	if rc == nil {
+		return head
+	}
+	rc.revision, rc.modified = head.revision, head.modified
+	for _, id := range ids {
+		rc.packets[id] = head.modified
+	}
+	return rc
+}
+
+// readRevisionHead reads the current revision and when it was made from the
+// backend.
+func (s *IndexedStorage) readRevisionHead(ctx context.Context) (*revisionCache, error) {
+	rl, ok := s.backend.(revisionLog)
+	if !ok {
+		return nil, errors.New("storage does not record revisions")
+	}
+	_, rev, err := rl.revisions(ctx, math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+	modified, err := rl.modified(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &revisionCache{revision: rev, modified: modified, packets: make(map[int32]time.Time)}, nil
+}
+
+// revisionHead returns the current revision and when it was made, reading
+// the backend only the first time.
+func (s *IndexedStorage) revisionHead(ctx context.Context) (int64, time.Time, error) {
+	s.lock.RLock()
+	if rc := s.revs; rc != nil {
+		defer s.lock.RUnlock()
+		return rc.revision, rc.modified, nil
+	}
+	s.lock.RUnlock()
+
+	head, err := s.readRevisionHead(ctx)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.revs == nil {
+		s.revs = head
+	}
+	return s.revs.revision, s.revs.modified, nil
+}
+
+// Revision returns the current revision without reading any packets.
+func (s *IndexedStorage) Revision(ctx context.Context) (int64, error) {
+	rev, _, err := s.revisionHead(ctx)
+	return rev, err
+}
+
+func Revision(ctx context.Context, media StorageMedia) (int64, error) {
+	rw := newReadWriter(media)
+	if rw == nil {
+		return 0, errors.New("readWriter is nil")
+	}
+	ix, ok := rw.(*IndexedStorage)
+	if !ok {
+		return 0, errors.New("storage does not record revisions")
+	}
+
+	rev, err := ix.Revision(ctx)
+	if err != nil {
+		return 0, errors.Wrapf(err, "read revision error")
+	}
+
+	return rev, nil
+}
+
+// Modified returns when the packet with the given ID last changed, or with
+// id 0 when anything last changed. It is zero for changes made before the
+// times were recorded. A packet's time is read from the backend only the
+// first time it is asked for.
+func (s *IndexedStorage) Modified(ctx context.Context, id int32) (time.Time, error) {
+	_, modified, err := s.revisionHead(ctx)
+	if err != nil || id == 0 {
+		return modified, err
+	}
+
+	s.lock.RLock()
+	if rc := s.revs; rc != nil {
+		if t, ok := rc.packets[id]; ok {
+			s.lock.RUnlock()
+			return t, nil
+		}
+	}
+	s.lock.RUnlock()
+
+	t, err := s.backend.(revisionLog).modified(ctx, id)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	// A write that got in first recorded a newer time.
+	if rc := s.revs; rc != nil {
+		if _, ok := rc.packets[id]; !ok {
+			rc.packets[id] = t
+		}
+	}
+	return t, nil
+}
+
+func Modified(ctx context.Context, id int32, media StorageMedia) (time.Time, error) {
+	rw := newReadWriter(media)
+	if rw == nil {
+		return time.Time{}, errors.New("readWriter is nil")
+	}
+	ix, ok := rw.(*IndexedStorage)
+	if !ok {
+		return time.Time{}, errors.New("storage does not record revisions")
+	}
+
+	t, err := ix.Modified(ctx, id)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "read modification time error")
+	}
+
+	return t, nil
+}
+
+// unixTime converts a recorded time, where 0 means unknown.
+func unixTime(sec int64) time.Time {
+	if sec <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// revisionsFile is the format of the LFS revisions file.
+type revisionsFile struct {
+	Revision int64 `json:"revision"`
+	// Packets maps packet IDs to the revision they last changed at.
+	Packets map[int32]int64 `json:"packets"`
+	// Modified is when Revision was made and PacketsModified when each packet
+	// last changed, in Unix seconds. Files written before they were recorded
+	// lack them.
+	Modified        int64           `json:"modified,omitempty"`
+	PacketsModified map[int32]int64 `json:"packets_modified,omitempty"`
+}
+
+// revisionsFilePath is where LFS and the journal keep the revisions.
+func revisionsFilePath() string {
+	return cfg.Get().PacketsFilePath + ".revs"
+}
+
+// readRevisionsFile loads the revisions. A missing file means revision 0. Callers must hold syncLock.
+func readRevisionsFile() (*revisionsFile, error) {
+	revs := &revisionsFile{Packets: make(map[int32]int64), PacketsModified: make(map[int32]int64)}
+
+	bytes, err := os.ReadFile(revisionsFilePath())
+	if os.IsNotExist(err) {
+		return revs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := sonic.Unmarshal(bytes, revs); err != nil {
+		return nil, errors.Wrap(err, "parse revisions file")
+	}
+	if revs.Packets == nil {
+		revs.Packets = make(map[int32]int64)
+	}
+	if revs.PacketsModified == nil {
+		revs.PacketsModified = make(map[int32]int64)
+	}
+	return revs, nil
+}
+
+// touchRevisionsFile bumps the revision and records it for ids. Callers must hold syncLock for writing.
+func touchRevisionsFile(ids []int32) error {
+	return touchRevisionsFileAfter(0, ids)
+}
+
+// touchRevisionsFileAfter is touchRevisionsFile with a new revision above
+// floor as well. Callers must hold syncLock for writing.
+func touchRevisionsFileAfter(floor int64, ids []int32) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	revs, err := readRevisionsFile()
+	if err != nil {
+		return err
+	}
+	if revs.Revision < floor {
+		revs.Revision = floor
+	}
+	revs.Revision++
+	revs.Modified = time.Now().Unix()
+	for _, id := range ids {
+		revs.Packets[id] = revs.Revision
+		revs.PacketsModified[id] = revs.Modified
+	}
+
+	bytes, err := sonic.Marshal(revs)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(revisionsFilePath(), bytes, 0644)
+}
+
+func (s *LocalFileSystem) revisions(ctx context.Context, since int64) ([]int32, int64, error) {
+	syncLock.RLock()
+	defer syncLock.RUnlock()
+
+	revs, err := readRevisionsFile()
+	if err != nil {
+		return nil, 0, err
+	}
+	ids := make([]int32, 0)
+	for id, rev := range revs.Packets {
+		if rev > since {
+			ids = append(ids, id)
+		}
+	}
+	return ids, revs.Revision, nil
+}
+
+func (s *LocalFileSystem) modified(ctx context.Context, id int32) (time.Time, error) {
+	syncLock.RLock()
+	defer syncLock.RUnlock()
+
+	revs, err := readRevisionsFile()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if id == 0 {
+		return unixTime(revs.Modified), nil
+	}
+	return unixTime(revs.PacketsModified[id]), nil
+}
+
+// packetIDs returns the IDs of packets.
+func packetIDs(packets []*packet.CloudPacket) []int32 {
+	ids := make([]int32, len(packets))
+	for i, p := range packets {
+		ids[i] = p.Id
+	}
+	return ids
+}
+
+// PacketRevisionModel records the revision at which a packet last changed.
+type PacketRevisionModel struct {
+	PacketID   int32 `gorm:"primaryKey;autoIncrement:false;column:packet_id"`
+	Revision   int64 `gorm:"column:revision;index:idx_revision"`
+	ModifiedAt int64 `gorm:"column:modified_at;not null;default:0"`
+}
+
+func (PacketRevisionModel) TableName() string {
+	return "packet_revisions"
+}
+
+// RevisionCounterModel holds the current revision in its single row.
+type RevisionCounterModel struct {
+	ID         int32 `gorm:"primaryKey;autoIncrement:false;column:id"`
+	Revision   int64 `gorm:"column:revision"`
+	ModifiedAt int64 `gorm:"column:modified_at;not null;default:0"`
+}
+
+func (RevisionCounterModel) TableName() string {
+	return "revision_counter"
+}
+
+// touchRevisions bumps the revision and records it for ids inside tx. The
+// counter row stays locked until tx ends, so revisions are committed in order
+// and a client never sees a revision before the smaller ones.
+func touchRevisions(tx *gorm.DB, ids []int32) error {
+	return touchRevisionsAfter(tx, 0, ids)
+}
+
+// touchRevisionsAfter is touchRevisions with a new revision above floor as
+// well.
+func touchRevisionsAfter(tx *gorm.DB, floor int64, ids []int32) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	now := time.Now().Unix()
+	// One upsert, so concurrent first writes do not both create the row.
+	err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"revision":    gorm.Expr("CASE WHEN revision < ? THEN ? ELSE revision END + 1", floor, floor),
+			"modified_at": now,
+		}),
+	}).Create(&RevisionCounterModel{ID: 1, Revision: floor + 1, ModifiedAt: now}).Error
+	if err != nil {
+		return err
+	}
+	var counter RevisionCounterModel
+	if err := tx.First(&counter, 1).Error; err != nil {
+		return err
+	}
+
+	rows := make([]PacketRevisionModel, 0, len(ids))
+	seen := make(map[int32]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			rows = append(rows, PacketRevisionModel{PacketID: id, Revision: counter.Revision, ModifiedAt: now})
+		}
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "packet_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revision", "modified_at"}),
+	}).CreateInBatches(rows, 500).Error
+}
+
+func (s *MySQLStorage) revisions(ctx context.Context, since int64) ([]int32, int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var (
+		ids     = make([]int32, 0)
+		counter RevisionCounterModel
+	)
+	// From the primary: a lagging replica could report a revision older than
+	// one a client has already seen, and the index is loaded from the primary.
+	err := s.writeDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Limit(1).Find(&counter, 1).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&PacketRevisionModel{}).Where("revision > ?", since).Pluck("packet_id", &ids).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return ids, counter.Revision, nil
+}
+
+func (s *MySQLStorage) modified(ctx context.Context, id int32) (time.Time, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var (
+		modifiedAt []int64
+		err        error
+	)
+	if id == 0 {
+		err = s.writeDB.WithContext(ctx).Model(&RevisionCounterModel{}).Where("id = ?", 1).Pluck("modified_at", &modifiedAt).Error
+	} else {
+		err = s.writeDB.WithContext(ctx).Model(&PacketRevisionModel{}).Where("packet_id = ?", id).Pluck("modified_at", &modifiedAt).Error
+	}
+	if err != nil || len(modifiedAt) == 0 {
+		return time.Time{}, err
+	}
+	return unixTime(modifiedAt[0]), nil
+}
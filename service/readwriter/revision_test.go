@@ -0,0 +1,147 @@
+package readwriter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	packet "packet_cloud/biz/model/hertz/packet"
+)
+
+func TestLFSChanges(t *testing.T) {
+	useTempPacketsFile(t)
+	testChanges(t, &LocalFileSystem{})
+}
+
+func TestJournalChanges(t *testing.T) {
+	testChanges(t, useTempJournal(t, 0))
+}
+
+func TestSQLiteChanges(t *testing.T) {
+	testChanges(t, useTempSQLite(t))
+}
+
+func testChanges(t *testing.T, backend ReadWriter) {
+	ctx := context.Background()
+	s := NewIndexedStorage(backend)
+
+	if rev, err := s.Revision(ctx); err != nil || rev != 0 {
+		t.Fatalf("revision of an empty storage: %d %v", rev, err)
+	}
+	if m, err := s.Modified(ctx, 0); err != nil || !m.IsZero() {
+		t.Fatalf("modification time of an empty storage: %v %v", m, err)
+	}
+	start := time.Now().Add(-time.Second)
+
+	in := []*packet.CloudPacket{{Name: "a", UserPackets: []*packet.UserPacket{{Name: "u"}}}, {Name: "b"}, {Name: "c"}}
+	if err := s.Insert(ctx, in); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	full, err := s.Changes(ctx, 0)
+	if err != nil {
+		t.Fatalf("changes: %v", err)
+	}
+	if !full.Reset || len(full.Packets) != 3 || full.Revision == 0 {
+		t.Fatalf("unexpected full sync: %+v", full)
+	}
+	if len(full.Packets[0].UserPackets) != 0 {
+		t.Fatal("changes must only carry metadata")
+	}
+	if rev, _ := s.Revision(ctx); rev != full.Revision {
+		t.Fatalf("revision %d, changes report %d", rev, full.Revision)
+	}
+	if after, _ := s.ChangesAfter(ctx, 0); after.Reset || len(after.Packets) != 3 {
+		t.Fatalf("unexpected changes after revision 0: %+v", after)
+	}
+	for _, id := range []int32{0, in[0].Id} {
+		if m, err := s.Modified(ctx, id); err != nil || m.Before(start) || m.After(time.Now()) {
+			t.Fatalf("modification time of %d: %v %v", id, m, err)
+		}
+	}
+	if m, _ := s.Modified(ctx, 999); !m.IsZero() {
+		t.Fatalf("modification time of a missing packet: %v", m)
+	}
+
+	renamed := "a2"
+	if _, err := s.Patch(ctx, in[0].Id, PatchFields{Name: &renamed}); err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	if _, err := s.Trash(ctx, Selector{IDs: []int32{in[1].Id}}, "admin"); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+	more := &packet.CloudPacket{Name: "d"}
+	if err := s.Insert(ctx, []*packet.CloudPacket{more}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	delta, err := s.Changes(ctx, full.Revision)
+	if err != nil {
+		t.Fatalf("changes: %v", err)
+	}
+	if delta.Reset || delta.Revision != full.Revision+3 {
+		t.Fatalf("unexpected revision: %+v", delta)
+	}
+	if len(delta.Packets) != 2 || delta.Packets[0].Name != "a2" || delta.Packets[1].Id != more.Id {
+		t.Fatalf("unexpected changed packets: %+v", delta.Packets)
+	}
+	if len(delta.Deleted) != 1 || delta.Deleted[0] != in[1].Id {
+		t.Fatalf("unexpected tombstones: %+v", delta.Deleted)
+	}
+
+	none, err := s.Changes(ctx, delta.Revision)
+	if err != nil || none.Reset || len(none.Packets) != 0 || len(none.Deleted) != 0 {
+		t.Fatalf("expected no changes: %v %+v", err, none)
+	}
+	if ahead, _ := s.Changes(ctx, delta.Revision+1); !ahead.Reset || len(ahead.Packets) != 3 {
+		t.Fatalf("a revision from the future must reset: %+v", ahead)
+	}
+
+	// Replacing everything reports every old and new packet.
+	if err := s.SavePacket(ctx, []*packet.CloudPacket{{Id: in[2].Id, Name: "c2"}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	saved, _ := s.Changes(ctx, delta.Revision)
+	if len(saved.Packets) != 1 || saved.Packets[0].Name != "c2" || len(saved.Deleted) != 2 {
+		t.Fatalf("unexpected changes after save: %+v", saved)
+	}
+}
+
+func TestRevisionCached(t *testing.T) {
+	ctx := context.Background()
+	useTempPacketsFile(t)
+	backend := &LocalFileSystem{}
+	s := NewIndexedStorage(backend)
+
+	in := []*packet.CloudPacket{{Name: "a"}, {Name: "b"}}
+	if err := s.Insert(ctx, in); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	rev, err := s.Revision(ctx)
+	if err != nil || rev == 0 {
+		t.Fatalf("revision: %d %v", rev, err)
+	}
+
+	// A write behind the storage's back is not seen: reads come from memory.
+	if err := backend.Insert(ctx, []*packet.CloudPacket{{Name: "c"}}); err != nil {
+		t.Fatalf("insert into backend: %v", err)
+	}
+	if got, _ := s.Revision(ctx); got != rev {
+		t.Fatalf("revision read from the backend: %d, cached %d", got, rev)
+	}
+
+	renamed := "b2"
+	if _, err := s.Patch(ctx, in[1].Id, PatchFields{Name: &renamed}); err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	got, _ := s.Revision(ctx)
+	if _, want, _ := backend.revisions(ctx, 0); got != want || got <= rev {
+		t.Fatalf("revision after patch: %d, backend %d", got, want)
+	}
+	modified, _ := s.Modified(ctx, 0)
+	if m, _ := s.Modified(ctx, in[1].Id); !m.Equal(modified) {
+		t.Fatalf("patched packet modified at %v, storage at %v", m, modified)
+	}
+	if m, _ := backend.modified(ctx, in[1].Id); !m.Equal(modified) {
+		t.Fatalf("cached %v, backend %v", modified, m)
+	}
+}
@@ -0,0 +1,106 @@
+package readwriter
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"packet_cloud/biz/model/hertz/packet"
+)
+
+// ErrInvalidSelector is returned for a Selector that selects nothing by
+// construction: one without any condition, or with From above To. It is also
+// returned for From without To unless OpenEnded confirms it.
+var ErrInvalidSelector = errors.New("invalid selector")
+
+// Selector picks the packets to delete. A packet is selected when it matches
+// every condition that is set.
+type Selector struct {
+	IDs []int32
+	// From and To bound the ID inclusively. To 0 means no upper bound, which
+	// From needs OpenEnded for.
+	From      int32
+	To        int32
+	OpenEnded bool
+
+	Uploader string
+	Region   string
+	Channel  string
+	// Name matches the whole name, where * matches any run of characters and
+	// ? a single one.
+	Name string
+	// TimeBefore selects packets whose time sorts before it; times are
+	// compared as strings, like Filter.TimeTo.
+	TimeBefore string
+}
+
+// Validate reports ErrInvalidSelector for a selector without conditions,
+// which would otherwise select every packet.
+func (sel Selector) Validate() error {
+	if len(sel.IDs) == 0 && sel.From == 0 && sel.To == 0 && sel.Uploader == "" && sel.Region == "" &&
+		sel.Channel == "" && sel.Name == "" && sel.TimeBefore == "" {
+		return errors.Wrap(ErrInvalidSelector, "no condition")
+	}
+	if sel.To != 0 && sel.From > sel.To {
+		return errors.Wrapf(ErrInvalidSelector, "from %d is above to %d", sel.From, sel.To)
+	}
+	if sel.From != 0 && sel.To == 0 && !sel.OpenEnded {
+		return errors.Wrapf(ErrInvalidSelector, "from %d without to needs open_ended", sel.From)
+	}
+	if sel.OpenEnded && sel.To != 0 {
+		return errors.Wrapf(ErrInvalidSelector, "open_ended with to %d", sel.To)
+	}
+	return nil
+}
+
+// matcher returns a function reporting whether sel selects a packet.
+func (sel Selector) matcher() func(p *packet.CloudPacket) bool {
+	var ids map[int32]bool
+	if len(sel.IDs) > 0 {
+		ids = idSet(sel.IDs)
+	}
+	var name *regexp.Regexp
+	if sel.Name != "" {
+		name = globRegexp(sel.Name)
+	}
+
+	return func(p *packet.CloudPacket) bool {
+		switch {
+		case ids != nil && !ids[p.Id]:
+			return false
+		case p.Id < sel.From, sel.To != 0 && p.Id > sel.To:
+			return false
+		case sel.Uploader != "" && p.Uploader != sel.Uploader:
+			return false
+		case sel.Region != "" && p.Region != sel.Region:
+			return false
+		case sel.Channel != "" && p.Channel != sel.Channel:
+			return false
+		case name != nil && !name.MatchString(p.Name):
+			return false
+		case sel.TimeBefore != "" && p.Time >= sel.TimeBefore:
+			return false
+		}
+		return true
+	}
+}
+
+// globRegexp turns a pattern with the wildcards * and ? into a regexp
+// matching whole strings.
+func globRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString(`^(?s:`)
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(`.*`)
+		case '?':
+			b.WriteString(`.`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString(`)$`)
+	return regexp.MustCompile(b.String())
+}
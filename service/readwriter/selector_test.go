@@ -0,0 +1,82 @@
+package readwriter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	packet "packet_cloud/biz/model/hertz/packet"
+)
+
+func TestSelectorValidate(t *testing.T) {
+	for _, sel := range []Selector{{}, {IDs: []int32{}}, {From: 5, To: 3}, {From: 5}, {From: 5, Uploader: "alice"}, {From: 5, To: 6, OpenEnded: true}, {OpenEnded: true}} {
+		if err := sel.Validate(); !errors.Is(err, ErrInvalidSelector) {
+			t.Fatalf("%+v: %v", sel, err)
+		}
+	}
+	for _, sel := range []Selector{{From: 5, OpenEnded: true}, {From: 3, To: 3}, {IDs: []int32{1}}, {TimeBefore: "2024"}} {
+		if err := sel.Validate(); err != nil {
+			t.Fatalf("%+v: %v", sel, err)
+		}
+	}
+}
+
+func TestSelectorMatch(t *testing.T) {
+	p := &packet.CloudPacket{Id: 7, Region: "cn", Channel: "1", Uploader: "alice", Name: "boss [x].v2", Time: "2024-03-01"}
+	for _, c := range []struct {
+		sel  Selector
+		want bool
+	}{
+		{Selector{IDs: []int32{1, 7}}, true},
+		{Selector{IDs: []int32{1}}, false},
+		{Selector{From: 7, OpenEnded: true}, true},
+		{Selector{From: 8, OpenEnded: true}, false},
+		{Selector{From: 1, To: 6}, false},
+		{Selector{Uploader: "alice", Region: "cn", Channel: "1"}, true},
+		{Selector{Uploader: "alice", Region: "us"}, false},
+		{Selector{Name: "boss*"}, true},
+		{Selector{Name: "boss"}, false},
+		{Selector{Name: "*[x].v?"}, true},
+		{Selector{Name: "*.v"}, false},
+		{Selector{TimeBefore: "2024-03-02"}, true},
+		{Selector{TimeBefore: "2024-03-01"}, false},
+	} {
+		if got := c.sel.matcher()(p); got != c.want {
+			t.Fatalf("%+v: got %v, want %v", c.sel, got, c.want)
+		}
+	}
+}
+
+func TestSelectAndTrash(t *testing.T) {
+	ctx := context.Background()
+	useTempPacketsFile(t)
+	s := NewIndexedStorage(&LocalFileSystem{})
+
+	in := []*packet.CloudPacket{
+		{Name: "boss a", Uploader: "alice", Time: "2024-01-01"},
+		{Name: "boss b", Uploader: "bob", Time: "2024-01-01"},
+		{Name: "boss c", Uploader: "alice", Time: "2024-06-01"},
+		{Name: "mob", Uploader: "alice", Time: "2024-01-01"},
+	}
+	if err := s.Insert(ctx, in); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	sel := Selector{Uploader: "alice", Name: "boss*", TimeBefore: "2024-02"}
+	ids, err := s.Select(ctx, sel)
+	if err != nil || !reflect.DeepEqual(ids, []int32{in[0].Id}) {
+		t.Fatalf("select: %v %v", ids, err)
+	}
+	if _, err := s.Select(ctx, Selector{}); !errors.Is(err, ErrInvalidSelector) {
+		t.Fatalf("empty selector: %v", err)
+	}
+
+	trashed, err := s.Trash(ctx, Selector{Uploader: "alice"}, "admin")
+	if err != nil || !reflect.DeepEqual(trashed, []int32{in[0].Id, in[2].Id, in[3].Id}) {
+		t.Fatalf("trash: %v %v", trashed, err)
+	}
+	if packets, _ := s.ReadPacket(ctx); len(packets) != 1 || packets[0].Uploader != "bob" {
+		t.Fatalf("left after trash: %+v", packets)
+	}
+}
@@ -0,0 +1,93 @@
+package readwriter
+
+import (
+	"context"
+	"testing"
+
+	packet "packet_cloud/biz/model/hertz/packet"
+	cfg "packet_cloud/config"
+)
+
+func TestLFSListThenGet(t *testing.T) {
+	useTempPacketsFile(t)
+	testListThenGet(t, &LocalFileSystem{})
+}
+
+func TestIndexedListThenGet(t *testing.T) {
+	useTempPacketsFile(t)
+	testListThenGet(t, NewIndexedStorage(&LocalFileSystem{}))
+}
+
+func TestJournalListThenGet(t *testing.T) {
+	testListThenGet(t, useTempJournal(t, 0))
+}
+
+func TestSQLiteListThenGet(t *testing.T) {
+	testListThenGet(t, NewIndexedStorage(useTempSQLite(t)))
+}
+
+func TestMySQLListThenGet(t *testing.T) {
+	if cfg.Get().MySQL.DSN == "" {
+		t.Skip("mysql dsn missing")
+	}
+	s := NewMySQLStorageFromConfig()
+	if s == nil {
+		t.Skip("mysql not available")
+	}
+	if err := s.SavePacket(context.Background(), nil); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+
+	testListThenGet(t, s)
+	testListThenGet(t, NewIndexedStorage(s))
+}
+
+// testListThenGet defaces everything the read methods of s hand out, the way
+// the list and edit pages used to, and checks Get still returns the stored
+// content.
+func testListThenGet(t *testing.T, s ReadWriter) {
+	ctx := context.Background()
+	p := &packet.CloudPacket{Region: "r", Name: "n", Channel: "c", Uploader: "u", Time: "t", UserPackets: []*packet.UserPacket{{Name: "x", Content: "secret"}}}
+	if err := s.Insert(ctx, []*packet.CloudPacket{p}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	deface := func(packets []*packet.CloudPacket) {
+		for _, q := range packets {
+			for _, up := range q.UserPackets {
+				up.Content = "内容暂时不展示"
+			}
+			q.UserPackets = nil
+			q.Name = "changed"
+		}
+	}
+
+	listed, _, err := s.List(ctx, Filter{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	deface(listed)
+	summaries, _, err := s.List(ctx, Filter{Summary: true})
+	if err != nil {
+		t.Fatalf("list summary: %v", err)
+	}
+	deface(summaries)
+	all, err := s.ReadPacket(ctx)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	deface(all)
+	got, err := s.Get(ctx, p.Id)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	deface([]*packet.CloudPacket{got})
+
+	got, err = s.Get(ctx, p.Id)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Name != "n" || len(got.UserPackets) != 1 || got.UserPackets[0].Content != "secret" {
+		t.Fatalf("stored packet was modified through a read: %+v", got)
+	}
+}
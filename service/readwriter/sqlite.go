@@ -0,0 +1,133 @@
+package readwriter
+
+import (
+	"log"
+	cfg "packet_cloud/config"
+	"sync"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// SQLiteStorage keeps the packets in an embedded SQLite database. It shares the
+// GORM models and queries of MySQLStorage; only the connection differs.
+type SQLiteStorage struct {
+	MySQLStorage
+	path string
+}
+
+var (
+	// sqliteStorages holds one storage per database file, since every
+	// connection pool on the same file competes for its single write lock.
+	sqliteStorages     = make(map[string]*SQLiteStorage)
+	sqliteStoragesLock sync.Mutex
+)
+
+// NewSQLiteStorageFromConfig returns the storage for the configured database
+// file, opening it on first use.
+func NewSQLiteStorageFromConfig() *SQLiteStorage {
+	path := sqlitePath()
+
+	sqliteStoragesLock.Lock()
+	defer sqliteStoragesLock.Unlock()
+
+	if s, ok := sqliteStorages[path]; ok {
+		return s
+	}
+	s := NewSQLiteStorage(path)
+	if s != nil {
+		sqliteStorages[path] = s
+	}
+	return s
+}
+
+// sqlitePath returns the configured database file.
+func sqlitePath() string {
+	if path := cfg.Get().SQLite.Path; path != "" {
+		return path
+	}
+	return "./packets.db"
+}
+
+// NewSQLiteStorage opens or creates the database at path.
+func NewSQLiteStorage(path string) *SQLiteStorage {
+	// WAL lets readers run next to the writer; busy_timeout waits for the lock
+	// instead of failing while another statement holds it.
+	dsn := path + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		log.Printf("open sqlite %s error: %v", path, err)
+		return nil
+	}
+
+	// SQLite allows one writer at a time, so serialise on a single connection;
+	// connLock queues operations for it before their timeout starts.
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Printf("open sqlite %s error: %v", path, err)
+		return nil
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	// The files in db/migrations are written for MySQL; SQLite follows the models.
+	if err := keyUserPacketsByPosition(db); err != nil {
+		log.Printf("upgrade sqlite %s error: %v", path, err)
+		return nil
+	}
+	if err := db.AutoMigrate(&CloudPacketModel{}, &UserPacketModel{}, &APIKeyModel{}, &PacketRevisionModel{}, &RevisionCounterModel{}, &DeletedPacketModel{}); err != nil {
+		log.Printf("AutoMigrate error: %v", err)
+	}
+
+	return &SQLiteStorage{path: path, MySQLStorage: MySQLStorage{
+		writeDB:       db,
+		readDB:        db,
+		slowThreshold: time.Duration(intOr(cfg.Get().MySQL.SlowQueryMs, 200)) * time.Millisecond,
+		queryTimeout:  time.Duration(intOr(cfg.Get().MySQL.QueryTimeoutMs, 3000)) * time.Millisecond,
+		connLock:      &sync.Mutex{},
+	}}
+}
+
+// keyUserPacketsByPosition rebuilds a user_packets table keyed by
+// (cloud_packet_id, id), from before user packets kept the client's ID, with
+// the key AutoMigrate sets up. Such tables numbered entries by position, so id
+// becomes the position. AutoMigrate cannot change a SQLite primary key itself.
+func keyUserPacketsByPosition(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&UserPacketModel{}) || m.HasColumn(&UserPacketModel{}, "position") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Index names are global in SQLite, so the old one is in the way.
+		for _, stmt := range []string{
+			"DROP INDEX IF EXISTS idx_cloud_packet_id",
+			"ALTER TABLE user_packets RENAME TO user_packets_old",
+		} {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.AutoMigrate(&CloudPacketModel{}, &UserPacketModel{}); err != nil {
+			return err
+		}
+		if err := tx.Exec("INSERT INTO user_packets (id, cloud_packet_id, position, name, content, size, send_timing) " +
+			"SELECT id, cloud_packet_id, id, name, content, size, send_timing FROM user_packets_old").Error; err != nil {
+			return err
+		}
+		return tx.Exec("DROP TABLE user_packets_old").Error
+	})
+}
+
+// Close closes the database and forgets the shared storage of its file.
+func (s *SQLiteStorage) Close() error {
+	sqliteStoragesLock.Lock()
+	if sqliteStorages[s.path] == s {
+		delete(sqliteStorages, s.path)
+	}
+	sqliteStoragesLock.Unlock()
+
+	return s.MySQLStorage.Close()
+}
@@ -0,0 +1,246 @@
+package readwriter
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	packet "packet_cloud/biz/model/hertz/packet"
+)
+
+// useTempSQLite opens an empty database in a temporary directory.
+func useTempSQLite(t *testing.T) *SQLiteStorage {
+	t.Helper()
+	s := NewSQLiteStorage(filepath.Join(t.TempDir(), "packets.db"))
+	if s == nil {
+		t.Fatal("open sqlite failed")
+	}
+	t.Cleanup(func() {
+		if db, err := s.writeDB.DB(); err == nil {
+			_ = db.Close()
+		}
+	})
+	return s
+}
+
+func TestSQLiteRecordCRUD(t *testing.T) {
+	testRecordCRUD(t, useTempSQLite(t))
+}
+
+func TestSQLiteConcurrentInsertUniqueIDs(t *testing.T) {
+	testConcurrentInsertUniqueIDs(t, useTempSQLite(t))
+}
+
+func TestSQLiteDeletedIDsAreNotReused(t *testing.T) {
+	testDeletedIDsAreNotReused(t, useTempSQLite(t))
+}
+
+func TestSQLitePatchAndList(t *testing.T) {
+	ctx := context.Background()
+	s := useTempSQLite(t)
+
+	in := []*packet.CloudPacket{
+		{Region: "r1", Name: "100%_done", Channel: "c1", Uploader: "u1", Time: "2024-01-02"},
+		{Region: "r1", Name: "100 done", Channel: "c2", Uploader: "u2", Time: "2024-01-01"},
+		{Region: "r2", Name: "other", Channel: "c1", Uploader: "u1", Time: "2024-01-03", UserPackets: []*packet.UserPacket{{Name: "x", Content: "0a"}}},
+	}
+	if err := s.Insert(ctx, in); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	// Wildcards in the name filter match literally.
+	listed, total, err := s.List(ctx, Filter{Name: "%_"})
+	if err != nil || total != 1 || listed[0].Id != in[0].Id {
+		t.Fatalf("name filter: %v %d %+v", err, total, listed)
+	}
+
+	listed, total, err = s.List(ctx, Filter{Region: "r1", SortBy: SortByTime, Limit: 1})
+	if err != nil || total != 2 || len(listed) != 1 || listed[0].Id != in[1].Id {
+		t.Fatalf("sorted page: %v %d %+v", err, total, listed)
+	}
+
+	name := "renamed"
+	patched, err := s.Patch(ctx, in[2].Id, PatchFields{Name: &name})
+	if err != nil || patched.Name != name || len(patched.UserPackets) != 1 {
+		t.Fatalf("patch: %v %+v", err, patched)
+	}
+	if _, err := s.Patch(ctx, 999, PatchFields{Name: &name}); err != ErrNotFound {
+		t.Fatalf("patch missing: %v", err)
+	}
+
+	key := &APIKey{Name: "client", Hash: "h", Scopes: "read"}
+	if err := s.InsertKey(ctx, key); err != nil || key.ID == 0 {
+		t.Fatalf("insert key: %v %+v", err, key)
+	}
+	if err := s.RevokeKey(ctx, key.ID); err != nil {
+		t.Fatalf("revoke key: %v", err)
+	}
+	if found, err := s.FindKey(ctx, "h"); err != nil || found.RevokedAt == "" {
+		t.Fatalf("find key: %v %+v", err, found)
+	}
+}
+
+func TestSQLiteCanceledContext(t *testing.T) {
+	s := useTempSQLite(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := s.List(ctx, Filter{}); err == nil {
+		t.Fatal("expected an error for a canceled request")
+	}
+	if err := s.Insert(ctx, []*packet.CloudPacket{{Name: "a"}}); err == nil {
+		t.Fatal("expected an error for a canceled request")
+	}
+}
+
+func TestSQLiteBackup(t *testing.T) {
+	ctx := context.Background()
+	s := useTempSQLite(t)
+
+	if err := s.Insert(ctx, []*packet.CloudPacket{{Name: "a", UserPackets: []*packet.UserPacket{{Name: "u"}}}, {Name: "b"}}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if err := s.InsertKey(ctx, &APIKey{Name: "client", Hash: "h", Scopes: "read"}); err != nil {
+		t.Fatalf("insert key: %v", err)
+	}
+
+	data, err := s.Backup(ctx)
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if len(data.Packets) != 2 || len(data.Packets[0].UserPackets) != 1 || len(data.Keys) != 1 || data.Keys[0].Hash != "h" {
+		t.Fatalf("unexpected backup: %+v", data)
+	}
+}
+
+// TestUserPacketIDsMatchAcrossBackends checks that every backend hands back
+// the user packet IDs the client sent, duplicates and zeros included.
+func TestUserPacketIDsMatchAcrossBackends(t *testing.T) {
+	backends := map[string]func(t *testing.T) ReadWriter{
+		"lfs": func(t *testing.T) ReadWriter {
+			useTempPacketsFile(t)
+			return &LocalFileSystem{}
+		},
+		"journal": func(t *testing.T) ReadWriter { return useTempJournal(t, 0) },
+		"sqlite":  func(t *testing.T) ReadWriter { return useTempSQLite(t) },
+	}
+
+	got := make(map[string][]*packet.CloudPacket)
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := open(t)
+
+			in := []*packet.CloudPacket{
+				{Name: "a", UserPackets: []*packet.UserPacket{{Id: 7, Name: "x"}, {Id: 7, Name: "y"}, {Id: 0, Name: "z"}}},
+				{Name: "b", UserPackets: []*packet.UserPacket{{Id: 3, Name: "x"}}},
+			}
+			if err := s.Insert(ctx, in); err != nil {
+				t.Fatalf("insert: %v", err)
+			}
+			if err := s.Update(ctx, &packet.CloudPacket{Id: in[1].Id, Name: "b", UserPackets: []*packet.UserPacket{{Id: 9, Name: "u"}, {Id: 2, Name: "v"}}}); err != nil {
+				t.Fatalf("update: %v", err)
+			}
+			if _, err := s.Patch(ctx, in[0].Id, PatchFields{UserPackets: &[]*packet.UserPacket{{Id: 5, Name: "p"}, {Id: 1, Name: "q"}}}); err != nil {
+				t.Fatalf("patch: %v", err)
+			}
+
+			packets, err := s.ReadPacket(ctx)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			want := [][]int32{{5, 1}, {9, 2}}
+			for i, p := range packets {
+				if ids := userPacketIDs(p); !reflect.DeepEqual(ids, want[i]) {
+					t.Fatalf("packet %d: user packet ids %v, want %v", p.Id, ids, want[i])
+				}
+			}
+			if p, err := s.Get(ctx, in[0].Id); err != nil || !reflect.DeepEqual(userPacketIDs(p), want[0]) {
+				t.Fatalf("get: %v %+v", err, p)
+			}
+
+			dup := &packet.CloudPacket{Name: "c", UserPackets: []*packet.UserPacket{{Id: 7}, {Id: 7}, {Id: 0}}}
+			if err := s.Insert(ctx, []*packet.CloudPacket{dup}); err != nil {
+				t.Fatalf("insert duplicates: %v", err)
+			}
+			// An empty list clears the user packets; a missing one keeps them.
+			if _, err := s.Patch(ctx, in[1].Id, PatchFields{UserPackets: &[]*packet.UserPacket{}}); err != nil {
+				t.Fatalf("clear: %v", err)
+			}
+			if p, err := s.Get(ctx, in[1].Id); err != nil || len(p.UserPackets) != 0 {
+				t.Fatalf("get after clearing: %v %+v", err, p)
+			}
+			renamed := "c2"
+			if p, err := s.Patch(ctx, dup.Id, PatchFields{Name: &renamed}); err != nil || len(p.UserPackets) != 3 {
+				t.Fatalf("patch without user packets: %v %+v", err, p)
+			}
+			if packets, err = s.ReadPacket(ctx); err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			got[name] = packets
+		})
+	}
+
+	for name, packets := range got {
+		if packetsChecksum(packets) != packetsChecksum(got["lfs"]) {
+			t.Fatalf("%s stores other packets than lfs:\n%+v\n%+v", name, packets, got["lfs"])
+		}
+	}
+}
+
+func userPacketIDs(p *packet.CloudPacket) []int32 {
+	ids := make([]int32, len(p.UserPackets))
+	for i, up := range p.UserPackets {
+		ids[i] = up.Id
+	}
+	return ids
+}
+
+// TestSQLiteKeysOldUserPacketsByPosition opens a database whose user_packets
+// table is keyed by (cloud_packet_id, id), as before user packets kept the
+// client's ID.
+func TestSQLiteKeysOldUserPacketsByPosition(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "packets.db")
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE cloud_packets (id integer PRIMARY KEY AUTOINCREMENT, region varchar(32), name varchar(64), channel varchar(32), uploader varchar(64), time varchar(32))",
+		"CREATE TABLE user_packets (id integer, cloud_packet_id integer, name varchar(64), content longtext, size integer, send_timing varchar(32), PRIMARY KEY (id, cloud_packet_id))",
+		"CREATE INDEX idx_cloud_packet_id ON user_packets(cloud_packet_id)",
+		"INSERT INTO cloud_packets (id, name) VALUES (1, 'a')",
+		"INSERT INTO user_packets (id, cloud_packet_id, name) VALUES (0, 1, 'x'), (1, 1, 'y')",
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		_ = sqlDB.Close()
+	}
+
+	s := NewSQLiteStorage(path)
+	if s == nil {
+		t.Fatal("open sqlite failed")
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	p, err := s.Get(ctx, 1)
+	if err != nil || len(p.UserPackets) != 2 || p.UserPackets[0].Name != "x" || p.UserPackets[1].Id != 1 {
+		t.Fatalf("get: %v %+v", err, p)
+	}
+	p.UserPackets = []*packet.UserPacket{{Id: 4, Name: "u"}, {Id: 4, Name: "v"}}
+	if err := s.Update(ctx, p); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if p, err := s.Get(ctx, 1); err != nil || !reflect.DeepEqual(userPacketIDs(p), []int32{4, 4}) {
+		t.Fatalf("get after update: %v %+v", err, p)
+	}
+}
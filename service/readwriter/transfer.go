@@ -0,0 +1,421 @@
+package readwriter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"packet_cloud/biz/model/hertz/packet"
+)
+
+// Errors returned by Transfer.
+var (
+	ErrTargetNotEmpty   = errors.New("target storage already holds packets")
+	ErrTransferMismatch = errors.New("target does not match the source after the copy")
+	// ErrStateNotCarried is returned when the target cannot take over the
+	// recycle bin, ID sequence and revisions of the source.
+	ErrStateNotCarried = errors.New("target cannot take over the recycle bin, ID sequence and revisions")
+)
+
+// TransferOptions controls Transfer.
+type TransferOptions struct {
+	// DryRun reads both storages and reports what would be copied without writing.
+	DryRun bool
+	// Overwrite replaces the packets and the recycle bin of a target that is
+	// not empty.
+	Overwrite bool
+}
+
+// TransferReport describes a copy between two storages.
+type TransferReport struct {
+	Packets int
+	// Checksum covers the copied packets as the target stores them.
+	Checksum string
+	// TargetPackets is the number of packets the target held before the copy.
+	TargetPackets int
+	// Keys is the number of API keys copied; keys the target has are skipped.
+	Keys int
+	// Trashed is the number of packets in the recycle bin of the source.
+	Trashed int
+	// LastID is the last packet ID the source allocated. The target hands out
+	// IDs above it, so IDs of deleted packets are not reused.
+	LastID int32
+	// Revision is the revision of the source. The target continues above it,
+	// so clients that synced with the source see every later change.
+	Revision int64
+}
+
+// carriedState is what Transfer copies besides the packets and API keys.
+type carriedState struct {
+	trash  []*DeletedPacket
+	lastID int32
+	// revision is the current revision and touched the IDs in the revision
+	// log of the source.
+	revision int64
+	touched  []int32
+}
+
+// stateCarrier is implemented by backends that can hand over and take over
+// the recycle bin, ID sequence and revisions.
+type stateCarrier interface {
+	// lastID returns the last allocated packet ID.
+	lastID(ctx context.Context) (int32, error)
+	// carryState replaces the recycle bin with st.trash, makes new IDs
+	// continue above st.lastID and records st.touched at a revision above
+	// st.revision.
+	carryState(ctx context.Context, st *carriedState) error
+}
+
+// OpenStorage opens the backend named like the StorageMedia config value,
+// without the shared index of the server. Close it with CloseStorage.
+func OpenStorage(name string) (ReadWriter, error) {
+	media, ok := ParseStorageMedia(name)
+	if !ok {
+		return nil, errors.Errorf("unknown storage %q", name)
+	}
+	rw := openBackend(media)
+	if rw == nil {
+		return nil, errors.Errorf("open %s storage failed", name)
+	}
+	return rw, nil
+}
+
+// CloseStorage closes rw if it holds connections or files.
+func CloseStorage(rw ReadWriter) error {
+	if c, ok := rw.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Transfer copies every packet of from into to, keeping their IDs, along with
+// the recycle bin, the API keys to does not have yet, the ID sequence and the
+// revision. Afterwards it reads to back and compares the count and checksum
+// of the packets with the source.
+func Transfer(ctx context.Context, from, to ReadWriter, opts TransferOptions) (*TransferReport, error) {
+	packets, err := from.ReadPacket(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "read source")
+	}
+	seen := make(map[int32]bool, len(packets))
+	for _, p := range packets {
+		if p.Id <= 0 || seen[p.Id] {
+			return nil, errors.Errorf("source packet id %d is missing or duplicated", p.Id)
+		}
+		seen[p.Id] = true
+	}
+
+	report := &TransferReport{Packets: len(packets), Checksum: packetsChecksum(packets)}
+
+	st, err := readCarriedState(ctx, from, packets)
+	if err != nil {
+		return nil, err
+	}
+	report.Trashed, report.LastID, report.Revision = len(st.trash), st.lastID, st.revision
+	sc, ok := to.(stateCarrier)
+	if !ok {
+		return report, ErrStateNotCarried
+	}
+
+	existing, err := to.ReadPacket(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "read target")
+	}
+	report.TargetPackets = len(existing)
+	if len(existing) > 0 && !opts.Overwrite {
+		return report, ErrTargetNotEmpty
+	}
+
+	keys, err := missingKeys(ctx, from, to)
+	if err != nil {
+		return report, err
+	}
+	if opts.DryRun {
+		report.Keys = len(keys)
+		return report, nil
+	}
+
+	if err := to.SavePacket(ctx, packets); err != nil {
+		return report, errors.Wrap(err, "write target")
+	}
+	for _, k := range keys {
+		if err := to.(KeyStore).InsertKey(ctx, k); err != nil {
+			return report, errors.Wrap(err, "copy api key")
+		}
+		report.Keys++
+	}
+	// After the packets, so the revision of the copy ends up above theirs.
+	if err := sc.carryState(ctx, st); err != nil {
+		return report, errors.Wrap(err, "write target state")
+	}
+
+	stored, err := to.ReadPacket(ctx)
+	if err != nil {
+		return report, errors.Wrap(err, "read target back")
+	}
+	if len(stored) != report.Packets {
+		return report, errors.Wrapf(ErrTransferMismatch, "%d packets instead of %d", len(stored), report.Packets)
+	}
+	if sum := packetsChecksum(stored); sum != report.Checksum {
+		return report, errors.Wrapf(ErrTransferMismatch, "checksum %s instead of %s", sum, report.Checksum)
+	}
+	if rb, ok := to.(recycleBin); ok {
+		trash, err := rb.trashed(ctx)
+		if err != nil {
+			return report, errors.Wrap(err, "read target recycle bin back")
+		}
+		if len(trash) != report.Trashed {
+			return report, errors.Wrapf(ErrTransferMismatch, "%d packets in the recycle bin instead of %d", len(trash), report.Trashed)
+		}
+	}
+	return report, nil
+}
+
+// readCarriedState reads the recycle bin, ID sequence and revisions of from,
+// whose packets are given.
+func readCarriedState(ctx context.Context, from ReadWriter, packets []*packet.CloudPacket) (*carriedState, error) {
+	st := &carriedState{}
+	if rb, ok := from.(recycleBin); ok {
+		trash, err := rb.trashed(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "read source recycle bin")
+		}
+		st.trash = trash
+	}
+	if rl, ok := from.(revisionLog); ok {
+		touched, rev, err := rl.revisions(ctx, 0)
+		if err != nil {
+			return nil, errors.Wrap(err, "read source revisions")
+		}
+		st.touched, st.revision = touched, rev
+	}
+	if sc, ok := from.(stateCarrier); ok {
+		last, err := sc.lastID(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "read source id sequence")
+		}
+		st.lastID = last
+	}
+
+	// Every packet the source holds counts as touched, so clients that
+	// synced with it see them even if its revision log is incomplete.
+	st.touched = append(st.touched, packetIDs(packets)...)
+	for _, d := range st.trash {
+		st.touched = append(st.touched, d.Packet.Id)
+	}
+	st.lastID = maxID(st.lastID, st.touched)
+	return st, nil
+}
+
+// maxID returns the largest of last and ids.
+func maxID(last int32, ids []int32) int32 {
+	for _, id := range ids {
+		if id > last {
+			last = id
+		}
+	}
+	return last
+}
+
+// trashIDs returns the IDs of the packets in the recycle bin.
+func trashIDs(deleted []*DeletedPacket) []int32 {
+	ids := make([]int32, len(deleted))
+	for i, d := range deleted {
+		ids[i] = d.Packet.Id
+	}
+	return ids
+}
+
+// lastIDLocked returns the last packet ID allocated by LFS or the journal,
+// starting from last. Callers must hold syncLock.
+func lastIDLocked(last int32) (int32, error) {
+	seq, err := readSequenceFile()
+	if err != nil {
+		return 0, err
+	}
+	packets, err := readPacketsFile()
+	if err != nil {
+		return 0, err
+	}
+	trash, err := readTrashFile()
+	if err != nil {
+		return 0, err
+	}
+	revs, err := readRevisionsFile()
+	if err != nil {
+		return 0, err
+	}
+	last = maxID(maxID(last, []int32{seq}), packetIDs(packets))
+	last = maxID(last, trashIDs(trash))
+	for id := range revs.Packets {
+		last = maxID(last, []int32{id})
+	}
+	return last, nil
+}
+
+// carryStateLocked writes st into the LFS files and returns the last packet
+// ID afterwards, at least last. Callers must hold syncLock for writing.
+func carryStateLocked(st *carriedState, last int32) (int32, error) {
+	if err := writeTrashFile(append([]*DeletedPacket(nil), st.trash...)); err != nil {
+		return 0, err
+	}
+	if err := touchRevisionsFileAfter(st.revision, st.touched); err != nil {
+		return 0, err
+	}
+	seq, err := lastIDLocked(maxID(last, []int32{st.lastID}))
+	if err != nil {
+		return 0, err
+	}
+	return seq, writeSequenceFile(seq)
+}
+
+func (s *LocalFileSystem) lastID(ctx context.Context) (int32, error) {
+	syncLock.RLock()
+	defer syncLock.RUnlock()
+	return lastIDLocked(0)
+}
+
+func (s *LocalFileSystem) carryState(ctx context.Context, st *carriedState) error {
+	syncLock.Lock()
+	defer syncLock.Unlock()
+	_, err := carryStateLocked(st, 0)
+	return err
+}
+
+// lastID also counts the IDs the journal allocated since the last snapshot.
+func (s *JournalFileSystem) lastID(ctx context.Context) (int32, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	syncLock.RLock()
+	defer syncLock.RUnlock()
+	return lastIDLocked(s.seq)
+}
+
+func (s *JournalFileSystem) carryState(ctx context.Context, st *carriedState) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	syncLock.Lock()
+	defer syncLock.Unlock()
+	seq, err := carryStateLocked(st, s.seq)
+	if err != nil {
+		return err
+	}
+	s.seq = seq
+	return nil
+}
+
+func (s *MySQLStorage) lastID(ctx context.Context) (int32, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var last int32
+	for _, q := range []string{
+		"SELECT COALESCE(MAX(id), 0) FROM cloud_packets",
+		"SELECT COALESCE(MAX(id), 0) FROM deleted_packets",
+		"SELECT COALESCE(MAX(packet_id), 0) FROM packet_revisions",
+	} {
+		var id int32
+		if err := s.readDB.WithContext(ctx).Raw(q).Scan(&id).Error; err != nil {
+			return 0, err
+		}
+		last = maxID(last, []int32{id})
+	}
+	return last, nil
+}
+
+func (s *MySQLStorage) carryState(ctx context.Context, st *carriedState) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	models := make([]DeletedPacketModel, len(st.trash))
+	for i, d := range st.trash {
+		m, err := toDeletedModel(d.Packet, d.DeletedBy, d.DeletedAt)
+		if err != nil {
+			return err
+		}
+		models[i] = m
+	}
+	err := s.writeDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM deleted_packets").Error; err != nil {
+			return err
+		}
+		if len(models) > 0 {
+			if err := tx.CreateInBatches(models, 500).Error; err != nil {
+				return err
+			}
+		}
+		return touchRevisionsAfter(tx, st.revision, st.touched)
+	})
+	if err != nil {
+		return err
+	}
+	return setSequence(s.writeDB.WithContext(ctx), st.lastID)
+}
+
+// setSequence makes new cloud_packets rows get IDs above last. SQLite keeps
+// the counter of an AUTOINCREMENT column in sqlite_sequence; MySQL on the
+// table, where the ALTER commits on its own and so runs outside transactions.
+// Neither lowers a counter below the IDs in use.
+func setSequence(db *gorm.DB, last int32) error {
+	if db.Dialector.Name() == "sqlite" {
+		res := db.Exec("UPDATE sqlite_sequence SET seq = ? WHERE name = 'cloud_packets' AND seq < ?", last, last)
+		if res.Error != nil || res.RowsAffected > 0 {
+			return res.Error
+		}
+		return db.Exec("INSERT INTO sqlite_sequence (name, seq) SELECT 'cloud_packets', ? WHERE NOT EXISTS (SELECT 1 FROM sqlite_sequence WHERE name = 'cloud_packets')", last).Error
+	}
+	return db.Exec(fmt.Sprintf("ALTER TABLE cloud_packets AUTO_INCREMENT = %d", int64(last)+1)).Error
+}
+
+// missingKeys returns the API keys of from that to does not have, matched by
+// hash. Storages without keys have nothing to copy.
+func missingKeys(ctx context.Context, from, to ReadWriter) ([]*APIKey, error) {
+	src, ok := from.(KeyStore)
+	if !ok {
+		return nil, nil
+	}
+	dst, ok := to.(KeyStore)
+	if !ok {
+		return nil, nil
+	}
+
+	keys, err := src.ListKeys(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "read source api keys")
+	}
+	var missing []*APIKey
+	for _, k := range keys {
+		_, err := dst.FindKey(ctx, k.Hash)
+		if errors.Is(err, ErrNotFound) {
+			missing = append(missing, k)
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "read target api keys")
+		}
+	}
+	return missing, nil
+}
+
+// packetsChecksum hashes packets in ID order, so storages returning the same
+// packets in a different order agree.
+func packetsChecksum(packets []*packet.CloudPacket) string {
+	sorted := append([]*packet.CloudPacket(nil), packets...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Id < sorted[j].Id })
+
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	for _, p := range sorted {
+		_ = enc.Encode(p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
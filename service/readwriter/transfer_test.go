@@ -0,0 +1,227 @@
+package readwriter
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	packet "packet_cloud/biz/model/hertz/packet"
+)
+
+// seedTransferSource fills an LFS storage with packets whose IDs have gaps,
+// and one API key.
+func seedTransferSource(t *testing.T) *LocalFileSystem {
+	t.Helper()
+	ctx := context.Background()
+	useTempPacketsFile(t)
+	s := &LocalFileSystem{}
+
+	in := []*packet.CloudPacket{
+		{Name: "a", Region: "cn", UserPackets: []*packet.UserPacket{{Id: 7, Name: "u1"}, {Id: 9, Name: "u2"}}},
+		{Name: "b", Region: "us"},
+		{Name: "c", Channel: "beta"},
+	}
+	if err := s.Insert(ctx, in); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if _, err := s.trash(ctx, []int32{in[1].Id}, "admin", time.Now()); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+	if _, err := s.purge(ctx, []int32{in[1].Id}); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if err := s.InsertKey(ctx, &APIKey{Name: "ci", Hash: "h1", Scopes: "read"}); err != nil {
+		t.Fatalf("insert key: %v", err)
+	}
+	return s
+}
+
+func TestTransferLFSToSQLite(t *testing.T) {
+	ctx := context.Background()
+	src := seedTransferSource(t)
+	dst := useTempSQLite(t)
+
+	report, err := Transfer(ctx, src, dst, TransferOptions{})
+	if err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	if report.Packets != 2 || report.Keys != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	got, _ := dst.ReadPacket(ctx)
+	if len(got) != 2 || got[0].Id != 1 || got[1].Id != 3 || got[1].Name != "c" {
+		t.Fatalf("ids not preserved: %+v", got)
+	}
+	if _, err := dst.FindKey(ctx, "h1"); err != nil {
+		t.Fatalf("api key not copied: %v", err)
+	}
+
+	// New packets continue after the copied IDs.
+	next := &packet.CloudPacket{Name: "d"}
+	if err := dst.Insert(ctx, []*packet.CloudPacket{next}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if next.Id != 4 {
+		t.Fatalf("expected id 4 after transfer, got %d", next.Id)
+	}
+}
+
+func TestTransferSQLiteToLFS(t *testing.T) {
+	ctx := context.Background()
+	src := useTempSQLite(t)
+	if err := src.Insert(ctx, []*packet.CloudPacket{{Name: "a"}, {Name: "b"}}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	useTempPacketsFile(t)
+	dst := &LocalFileSystem{}
+
+	report, err := Transfer(ctx, src, dst, TransferOptions{})
+	if err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	back, _ := dst.ReadPacket(ctx)
+	if packetsChecksum(back) != report.Checksum {
+		t.Fatalf("checksum of the copy differs")
+	}
+}
+
+func TestTransferDryRun(t *testing.T) {
+	ctx := context.Background()
+	src := seedTransferSource(t)
+	dst := useTempSQLite(t)
+
+	report, err := Transfer(ctx, src, dst, TransferOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if report.Packets != 2 || report.Keys != 1 || report.Checksum == "" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if got, _ := dst.ReadPacket(ctx); len(got) != 0 {
+		t.Fatalf("dry run wrote %d packets", len(got))
+	}
+	if keys, _ := dst.ListKeys(ctx); len(keys) != 0 {
+		t.Fatalf("dry run wrote %d api keys", len(keys))
+	}
+}
+
+func TestTransferRefusesNonEmptyTarget(t *testing.T) {
+	ctx := context.Background()
+	src := seedTransferSource(t)
+	dst := useTempSQLite(t)
+	if err := dst.Insert(ctx, []*packet.CloudPacket{{Name: "old"}}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if err := dst.InsertKey(ctx, &APIKey{Name: "ci", Hash: "h1", Scopes: "read"}); err != nil {
+		t.Fatalf("insert key: %v", err)
+	}
+
+	if _, err := Transfer(ctx, src, dst, TransferOptions{}); !errors.Is(err, ErrTargetNotEmpty) {
+		t.Fatalf("expected ErrTargetNotEmpty, got %v", err)
+	}
+
+	report, err := Transfer(ctx, src, dst, TransferOptions{Overwrite: true})
+	if err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+	if report.TargetPackets != 1 || report.Keys != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if keys, _ := dst.ListKeys(ctx); len(keys) != 1 {
+		t.Fatalf("existing api key copied again: %d keys", len(keys))
+	}
+}
+
+func TestTransferRejectsBadIDs(t *testing.T) {
+	ctx := context.Background()
+	useTempPacketsFile(t)
+	src := &LocalFileSystem{}
+	if err := src.SavePacket(ctx, []*packet.CloudPacket{{Id: 1, Name: "a"}, {Id: 1, Name: "b"}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if _, err := Transfer(ctx, src, useTempSQLite(t), TransferOptions{}); err == nil {
+		t.Fatal("expected an error for duplicated ids")
+	}
+}
+
+func TestTransferCarriesRecycleBinAndSequence(t *testing.T) {
+	ctx := context.Background()
+	useTempPacketsFile(t)
+	src := &LocalFileSystem{}
+	in := []*packet.CloudPacket{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}}
+	if err := src.Insert(ctx, in); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	_, synced, _ := src.revisions(ctx, 0)
+	if _, err := src.trash(ctx, []int32{4}, "admin", time.Now()); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+	if _, err := src.trash(ctx, []int32{3}, "admin", time.Now()); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+	if _, err := src.purge(ctx, []int32{3}); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	_, srcRev, _ := src.revisions(ctx, 0)
+	dst := useTempSQLite(t)
+
+	report, err := Transfer(ctx, src, dst, TransferOptions{})
+	if err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	if report.Packets != 2 || report.Trashed != 1 || report.LastID != 4 || report.Revision != srcRev {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	if trash, _ := dst.trashed(ctx); len(trash) != 1 || trash[0].Packet.Id != 4 || trash[0].DeletedBy != "admin" {
+		t.Fatalf("recycle bin not copied: %+v", trash)
+	}
+	// A client that synced with the source learns about both deletions.
+	ix := NewIndexedStorage(dst)
+	cs, err := ix.ChangesAfter(ctx, synced)
+	if err != nil || cs.Reset || cs.Revision <= srcRev || !reflect.DeepEqual(cs.Deleted, []int32{3, 4}) {
+		t.Fatalf("changes after the synced revision: %+v %v", cs, err)
+	}
+	next := &packet.CloudPacket{Name: "e"}
+	if err := dst.Insert(ctx, []*packet.CloudPacket{next}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if next.Id != 5 {
+		t.Fatalf("expected id 5 after transfer, got %d", next.Id)
+	}
+}
+
+func TestTransferSQLiteToJournal(t *testing.T) {
+	ctx := context.Background()
+	src := useTempSQLite(t)
+	if err := src.Insert(ctx, []*packet.CloudPacket{{Name: "a"}, {Name: "b"}}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if _, err := src.trash(ctx, []int32{2}, "admin", time.Now()); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+	_, srcRev, _ := src.revisions(ctx, 0)
+	dst := useTempJournal(t, 0)
+
+	if _, err := Transfer(ctx, src, dst, TransferOptions{}); err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	if trash, _ := dst.trashed(ctx); len(trash) != 1 || trash[0].Packet.Id != 2 {
+		t.Fatalf("recycle bin not copied: %+v", trash)
+	}
+	if _, rev, _ := dst.revisions(ctx, 0); rev <= srcRev {
+		t.Fatalf("revision %d not above the source's %d", rev, srcRev)
+	}
+	next := &packet.CloudPacket{Name: "c"}
+	if err := dst.Insert(ctx, []*packet.CloudPacket{next}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if next.Id != 3 {
+		t.Fatalf("expected id 3 after transfer, got %d", next.Id)
+	}
+}
@@ -0,0 +1,55 @@
+package recyclebin
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	cfg "packet_cloud/config"
+	"packet_cloud/service/readwriter"
+)
+
+// Retention is how long deleted packets stay in the recycle bin; 0 keeps
+// them until they are purged by hand.
+func Retention() time.Duration {
+	return time.Duration(cfg.Get().RecycleBin.RetentionDays) * 24 * time.Hour
+}
+
+// PurgeExpired removes the packets that were deleted longer than Retention
+// ago and returns their IDs.
+func PurgeExpired(ctx context.Context, now time.Time) ([]int32, error) {
+	retention := Retention()
+	if retention <= 0 {
+		return nil, nil
+	}
+	return readwriter.PurgeTrashBefore(ctx, now.Add(-retention), readwriter.LFS)
+}
+
+// Start purges expired packets now and then every hour, and returns a
+// function that stops it. Without a retention it starts nothing.
+func Start() (stop func(), err error) {
+	if Retention() <= 0 {
+		return func() {}, nil
+	}
+
+	purge := func() {
+		ids, err := PurgeExpired(context.Background(), time.Now())
+		if err != nil {
+			log.Println("[RecycleBin] !!! purge failed:", err)
+			return
+		}
+		if len(ids) > 0 {
+			log.Printf("[RecycleBin] purged %d expired packets: %v", len(ids), ids)
+		}
+	}
+	purge()
+
+	c := cron.New()
+	if _, err = c.AddFunc("@hourly", purge); err != nil {
+		return nil, err
+	}
+	c.Start()
+	return func() { <-c.Stop().Done() }, nil
+}
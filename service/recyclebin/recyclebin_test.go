@@ -0,0 +1,65 @@
+package recyclebin
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	packet "packet_cloud/biz/model/hertz/packet"
+	cfg "packet_cloud/config"
+	"packet_cloud/service/readwriter"
+)
+
+// useRetention points the LFS storage into a temporary directory and sets
+// the retention.
+func useRetention(t *testing.T, days int) {
+	t.Helper()
+	dir := t.TempDir()
+	cp := filepath.Join(dir, "config.json")
+	b, _ := json.Marshal(cfg.Config{StorageMedia: "lfs", PacketsFilePath: filepath.Join(dir, "packets"), RecycleBin: cfg.RecycleBinConfig{RetentionDays: days}})
+	_ = os.WriteFile(cp, b, 0644)
+	if err := cfg.Load(cp); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	t.Cleanup(func() { _ = readwriter.Close() })
+}
+
+func trashOne(t *testing.T) {
+	t.Helper()
+	ctx := context.Background()
+	if err := readwriter.Insert(ctx, []*packet.CloudPacket{{Name: "a"}}, readwriter.LFS); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if _, err := readwriter.Trash(ctx, readwriter.Selector{IDs: []int32{1}}, "admin", readwriter.LFS); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+}
+
+func TestPurgeExpired(t *testing.T) {
+	useRetention(t, 7)
+	trashOne(t)
+	ctx := context.Background()
+
+	if ids, err := PurgeExpired(ctx, time.Now().Add(6*24*time.Hour)); err != nil || len(ids) != 0 {
+		t.Fatalf("purged before the retention ended: %v %v", ids, err)
+	}
+	ids, err := PurgeExpired(ctx, time.Now().Add(8*24*time.Hour))
+	if err != nil || len(ids) != 1 || ids[0] != 1 {
+		t.Fatalf("purge after the retention ended: %v %v", ids, err)
+	}
+}
+
+func TestPurgeExpiredWithoutRetention(t *testing.T) {
+	useRetention(t, 0)
+	trashOne(t)
+
+	if ids, err := PurgeExpired(context.Background(), time.Now().AddDate(10, 0, 0)); err != nil || len(ids) != 0 {
+		t.Fatalf("purged without a retention: %v %v", ids, err)
+	}
+	if deleted, _ := readwriter.ListTrash(context.Background(), readwriter.LFS); len(deleted) != 1 {
+		t.Fatalf("recycle bin: %+v", deleted)
+	}
+}
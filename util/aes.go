@@ -12,14 +12,15 @@ import (
 	"log"
 )
 
-var key = []byte{84, 72, 73, 82, 73, 83, 77, 90, 83, 69, 67, 82, 69, 84, 75, 69}
+// LegacyKey is the key compiled into older builds. It is only used when no
+// key is configured, so existing clients keep working until keys are rolled out.
+var LegacyKey = []byte{84, 72, 73, 82, 73, 83, 77, 90, 83, 69, 67, 82, 69, 84, 75, 69}
 
-func AESCBCEncrypt(src []byte) (string, error) {
+// AESCBCEncrypt encrypts src with key (16, 24 or 32 bytes) and returns the
+// base64 of the random IV followed by the ciphertext.
+func AESCBCEncrypt(key, src []byte) (string, error) {
 	//virtualizersdk.Macro(virtualizersdk.LION_RED_START)
 
-	if len(key) > 16 {
-		key = key[:16]
-	}
 	plaintext := src
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -44,7 +45,8 @@ func AESCBCEncrypt(src []byte) (string, error) {
 	return base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
-func AESCBCDecrypt(src []byte) (string, error) {
+// AESCBCDecrypt reverses AESCBCEncrypt.
+func AESCBCDecrypt(key, src []byte) (string, error) {
 	//virtualizersdk.Macro(virtualizersdk.LION_RED_START)
 	ciphertext, err := base64.StdEncoding.DecodeString(string(src))
 	if err != nil {
@@ -56,18 +58,22 @@ func AESCBCDecrypt(src []byte) (string, error) {
 		log.Println(fmt.Errorf("aes.NewCipher err:%s", err))
 		return "", err
 	}
-	if len(ciphertext) < aes.BlockSize {
+	if len(ciphertext) < 2*aes.BlockSize {
 		log.Println("ciphertext too short")
-		return "", err
+		return "", errors.New("ciphertext too short")
 	}
 	iv := ciphertext[:aes.BlockSize]
 	ciphertext = ciphertext[aes.BlockSize:]
 	if len(ciphertext)%aes.BlockSize != 0 {
 		log.Println("ciphertext is not a multiple of the block size")
+		return "", errors.New("ciphertext is not a multiple of the block size")
 	}
 	mode := cipher.NewCBCDecrypter(block, iv)
 	mode.CryptBlocks(ciphertext, ciphertext)
-	ciphertext = UnPadding(ciphertext)
+	ciphertext, err = UnPadding(ciphertext, aes.BlockSize)
+	if err != nil {
+		return "", err
+	}
 	//virtualizersdk.Macro(virtualizersdk.LION_RED_END)
 	return string(ciphertext), nil
 }
@@ -79,9 +85,24 @@ func Padding(plainText []byte, blockSize int) []byte {
 	return append(plainText, newPlain...)
 }
 
-func UnPadding(plainText []byte) []byte {
+// ErrPadding is returned for input that does not end in valid PKCS#7 padding.
+var ErrPadding = errors.New("invalid pkcs7 padding")
+
+// UnPadding strips the PKCS#7 padding added by Padding. The input must be a
+// non-empty multiple of blockSize ending in n bytes of value n, 1 <= n <= blockSize.
+func UnPadding(plainText []byte, blockSize int) ([]byte, error) {
 	length := len(plainText)
-	lastChar := plainText[length-1]
-	padding := int(lastChar)
-	return plainText[:length-padding]
+	if length == 0 || length%blockSize != 0 {
+		return nil, ErrPadding
+	}
+	padding := int(plainText[length-1])
+	if padding == 0 || padding > blockSize {
+		return nil, ErrPadding
+	}
+	for _, b := range plainText[length-padding:] {
+		if int(b) != padding {
+			return nil, ErrPadding
+		}
+	}
+	return plainText[:length-padding], nil
 }
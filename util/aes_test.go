@@ -6,15 +6,46 @@ import (
 
 func TestAESRoundTrip(t *testing.T) {
     src := []byte("hello")
-    enc, err := AESCBCEncrypt(src)
+    enc, err := AESCBCEncrypt(LegacyKey, src)
     if err != nil {
         t.Fatalf("enc: %v", err)
     }
-    dec, err := AESCBCDecrypt([]byte(enc))
+    dec, err := AESCBCDecrypt(LegacyKey, []byte(enc))
     if err != nil {
         t.Fatalf("dec: %v", err)
     }
     if dec != string(src) {
         t.Fatalf("mismatch: %s", dec)
     }
-}
\ No newline at end of file
+}
+
+func TestUnPadding(t *testing.T) {
+    cases := []struct {
+        name string
+        in   []byte
+        want string
+        ok   bool
+    }{
+        {"empty", nil, "", false},
+        {"not block aligned", []byte("abc\x01"), "", false},
+        {"zero padding", append([]byte("0123456789abcde"), 0), "", false},
+        {"padding too long", append([]byte("0123456789abcde"), 17), "", false},
+        {"inconsistent bytes", append([]byte("0123456789abc"), 1, 3, 3), "", false},
+        {"one byte", append([]byte("0123456789abcde"), 1), "0123456789abcde", true},
+        {"full block", append([]byte("0123456789abcdef"), Padding(nil, 16)...), "0123456789abcdef", true},
+    }
+    for _, c := range cases {
+        got, err := UnPadding(c.in, 16)
+        if (err == nil) != c.ok || string(got) != c.want {
+            t.Errorf("%s: got %q, %v", c.name, got, err)
+        }
+    }
+}
+
+func TestAESCBCDecryptRejectsMalformed(t *testing.T) {
+    for _, src := range []string{"", "!!!", "AAAA", "AAAAAAAAAAAAAAAAAAAAAA=="} {
+        if _, err := AESCBCDecrypt(LegacyKey, []byte(src)); err == nil {
+            t.Errorf("%q: expected error", src)
+        }
+    }
+}
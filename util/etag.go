@@ -0,0 +1,32 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// HashETag returns a strong entity tag built from the SHA-256 of parts, each
+// followed by a newline so adjacent parts cannot run together.
+func HashETag(parts ...[]byte) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write(p)
+		h.Write([]byte{'\n'})
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// MatchETag reports whether the If-None-Match header value matches etag.
+// As RFC 9110 asks for If-None-Match, the comparison is weak: a W/ prefix on
+// either side is ignored.
+func MatchETag(ifNoneMatch, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || (tag != "" && strings.TrimPrefix(tag, "W/") == etag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package util
+
+import "testing"
+
+func TestHashETag(t *testing.T) {
+	a := HashETag([]byte("ab"), []byte("c"))
+	if a != HashETag([]byte("ab"), []byte("c")) {
+		t.Fatal("etag is not stable")
+	}
+	if a == HashETag([]byte("a"), []byte("bc")) {
+		t.Fatal("parts run together")
+	}
+	if len(a) != 34 || a[0] != '"' || a[33] != '"' {
+		t.Fatalf("not a quoted strong etag: %s", a)
+	}
+}
+
+func TestMatchETag(t *testing.T) {
+	cases := []struct {
+		header string
+		want   bool
+	}{
+		{`"abc"`, true},
+		{`W/"abc"`, true},
+		{`"x", "abc"`, true},
+		{`*`, true},
+		{`"abcd"`, false},
+		{`abc`, false},
+		{``, false},
+	}
+	for _, c := range cases {
+		if got := MatchETag(c.header, `"abc"`); got != c.want {
+			t.Errorf("MatchETag(%q) = %v, want %v", c.header, got, c.want)
+		}
+	}
+}
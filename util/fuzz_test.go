@@ -0,0 +1,43 @@
+package util
+
+import (
+	"testing"
+)
+
+func FuzzUnPadding(f *testing.F) {
+	f.Add([]byte{})
+	f.Add(Padding([]byte("hello"), 16))
+	f.Add(append([]byte("0123456789abcde"), 0))
+	f.Fuzz(func(t *testing.T, in []byte) {
+		out, err := UnPadding(in, 16)
+		if err != nil {
+			return
+		}
+		if string(Padding(out, 16)) != string(in) {
+			t.Fatalf("accepted padding does not round trip: %x", in)
+		}
+	})
+}
+
+func FuzzKeyringDecrypt(f *testing.F) {
+	ring, err := NewKeyring("a", map[string][]byte{"a": LegacyKey})
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, mode := range []string{CipherCBC, CipherGCM} {
+		enc, err := ring.EncryptMode(mode, []byte(`[{"id":1}]`))
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(enc)
+	}
+	f.Add("")
+	f.Add("a:")
+	f.Add("v2:a:")
+	f.Add("v2:a:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA")
+
+	f.Fuzz(func(t *testing.T, src string) {
+		// Malformed input must fail cleanly instead of panicking.
+		_, _ = ring.Decrypt(src)
+	})
+}
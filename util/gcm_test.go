@@ -0,0 +1,44 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyringGCM(t *testing.T) {
+	ring, err := NewKeyring("a", map[string][]byte{"a": LegacyKey, "b": []byte("0123456789abcdef")})
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+
+	enc, err := ring.EncryptMode(CipherGCM, []byte("hello"))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if !strings.HasPrefix(enc, "v2:a:") {
+		t.Fatalf("unexpected envelope %s", enc)
+	}
+	dec, err := ring.Decrypt(enc)
+	if err != nil || string(dec) != "hello" {
+		t.Fatalf("decrypt: %q, %v", dec, err)
+	}
+
+	// Flip one bit of the ciphertext.
+	raw := []byte(enc)
+	raw[len(raw)-4] ^= 1
+	if _, err := ring.Decrypt(string(raw)); err == nil {
+		t.Fatal("tampered ciphertext accepted")
+	}
+	// The tag covers the key id, so the envelope cannot be relabelled.
+	if _, err := ring.Decrypt("v2:b:" + strings.TrimPrefix(enc, "v2:a:")); err == nil {
+		t.Fatal("relabelled key id accepted")
+	}
+
+	cbc, err := ring.EncryptMode(CipherCBC, []byte("hello"))
+	if err != nil || strings.Count(cbc, KeyIDSeparator) != 1 {
+		t.Fatalf("cbc envelope: %s, %v", cbc, err)
+	}
+	if _, err := ring.EncryptMode("ecb", []byte("hello")); err == nil {
+		t.Fatal("unknown mode accepted")
+	}
+}
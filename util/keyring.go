@@ -0,0 +1,126 @@
+package util
+
+import (
+	"crypto/aes"
+	"fmt"
+	"strings"
+)
+
+// KeyIDSeparator separates the parts of the envelopes produced by a Keyring.
+const KeyIDSeparator = ":"
+
+// Envelopes produced by a Keyring:
+//
+//	<key id>:<base64 of IV + CBC ciphertext>             legacy CBC, v1
+//	v2:<key id>:<base64 of nonce + GCM ciphertext + tag>  AES-GCM
+//
+// Key IDs cannot contain the separator, so the number of parts tells them
+// apart. The GCM tag also covers the "v2:<key id>" prefix.
+const EnvelopeGCM = "v2"
+
+// Cipher modes accepted by EncryptMode.
+const (
+	CipherCBC = "cbc"
+	CipherGCM = "gcm"
+)
+
+// Keyring holds the AES keys in use. New ciphertexts are made with the active
+// key and prefixed with its ID; any key in the ring can decrypt, so clients
+// keep working while a new key is rolled out.
+type Keyring struct {
+	active string
+	keys   map[string][]byte
+}
+
+// NewKeyring checks the keys and returns a ring encrypting with active.
+func NewKeyring(active string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("active key %q not in keyring", active)
+	}
+	ring := &Keyring{active: active, keys: make(map[string][]byte, len(keys))}
+	for id, k := range keys {
+		if id == "" || strings.Contains(id, KeyIDSeparator) {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+		if _, err := aes.NewCipher(k); err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		ring.keys[id] = k
+	}
+	return ring, nil
+}
+
+// Active returns the ID of the key new ciphertexts are made with.
+func (r *Keyring) Active() string {
+	return r.active
+}
+
+// Has reports whether the ring holds a key with the given ID.
+func (r *Keyring) Has(id string) bool {
+	_, ok := r.keys[id]
+	return ok
+}
+
+// Encrypt encrypts src with the active key and returns "<key id>:<ciphertext>".
+func (r *Keyring) Encrypt(src []byte) (string, error) {
+	ciphertext, err := AESCBCEncrypt(r.keys[r.active], src)
+	if err != nil {
+		return "", err
+	}
+	return r.active + KeyIDSeparator + ciphertext, nil
+}
+
+// EncryptGCM seals src with the active key and returns a v2 envelope.
+func (r *Keyring) EncryptGCM(src []byte) (string, error) {
+	prefix := EnvelopeGCM + KeyIDSeparator + r.active
+	ciphertext, err := AESGCMEncrypt(r.keys[r.active], src, []byte(prefix))
+	if err != nil {
+		return "", err
+	}
+	return prefix + KeyIDSeparator + ciphertext, nil
+}
+
+// EncryptMode encrypts src with CipherCBC or CipherGCM.
+func (r *Keyring) EncryptMode(mode string, src []byte) (string, error) {
+	switch mode {
+	case CipherCBC:
+		return r.Encrypt(src)
+	case CipherGCM:
+		return r.EncryptGCM(src)
+	}
+	return "", fmt.Errorf("unknown cipher mode %q", mode)
+}
+
+// Decrypt opens an envelope made by Encrypt or EncryptGCM with any key of the ring.
+func (r *Keyring) Decrypt(src string) ([]byte, error) {
+	parts := strings.Split(src, KeyIDSeparator)
+	switch {
+	case len(parts) == 2:
+		key, err := r.key(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := AESCBCDecrypt(key, []byte(parts[1]))
+		if err != nil {
+			return nil, err
+		}
+		return []byte(plaintext), nil
+
+	case len(parts) == 3 && parts[0] == EnvelopeGCM:
+		key, err := r.key(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		prefix := parts[0] + KeyIDSeparator + parts[1]
+		return AESGCMDecrypt(key, []byte(parts[2]), []byte(prefix))
+	}
+	return nil, fmt.Errorf("malformed envelope")
+}
+
+func (r *Keyring) key(id string) ([]byte, error) {
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", id)
+	}
+	return key, nil
+}
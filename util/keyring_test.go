@@ -0,0 +1,49 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyringRotation(t *testing.T) {
+	oldKey := []byte("0123456789abcdef")
+	newKey := []byte("0123456789abcdef0123456789abcdef")
+
+	before, err := NewKeyring("a", map[string][]byte{"a": oldKey})
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+	enc, err := before.Encrypt([]byte("hello"))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if !strings.HasPrefix(enc, "a:") {
+		t.Fatalf("missing key id: %s", enc)
+	}
+
+	after, err := NewKeyring("b", map[string][]byte{"a": oldKey, "b": newKey})
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+	dec, err := after.Decrypt(enc)
+	if err != nil || string(dec) != "hello" {
+		t.Fatalf("decrypt with old key: %q, %v", dec, err)
+	}
+	enc, _ = after.Encrypt([]byte("hello"))
+	if !strings.HasPrefix(enc, "b:") {
+		t.Fatalf("not encrypted with the active key: %s", enc)
+	}
+	if _, err := before.Decrypt(enc); err == nil {
+		t.Fatal("decrypted with an unknown key id")
+	}
+
+	if _, err := NewKeyring("c", map[string][]byte{"a": oldKey}); err == nil {
+		t.Fatal("missing active key accepted")
+	}
+	if _, err := NewKeyring("a", map[string][]byte{"a": []byte("short")}); err == nil {
+		t.Fatal("invalid key size accepted")
+	}
+	if _, err := NewKeyring("a:b", map[string][]byte{"a:b": oldKey}); err == nil {
+		t.Fatal("key id with separator accepted")
+	}
+}
@@ -0,0 +1,53 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// Headers of a signed request. For GET requests the user and timestamp may
+// instead be sent as the "username" and "time" query parameters.
+const (
+	SignUserHeader      = "X-Sign-User"
+	SignTimestampHeader = "X-Sign-Timestamp"
+	SignNonceHeader     = "X-Sign-Nonce"
+	SignatureHeader     = "X-Signature"
+)
+
+// CanonicalRequest is the string that gets signed: the upper-case method, the
+// request URI (path plus raw query, exactly as sent), the hex SHA-256 of the
+// body, the unix timestamp in seconds and the nonce, joined by "\n".
+func CanonicalRequest(method, uri string, body []byte, timestamp int64, nonce string) string {
+	bodyHash := sha256.Sum256(body)
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		uri,
+		hex.EncodeToString(bodyHash[:]),
+		strconv.FormatInt(timestamp, 10),
+		nonce,
+	}, "\n")
+}
+
+// SignRequest returns the hex HMAC-SHA256 of the canonical request under secret.
+func SignRequest(secret, method, uri string, body []byte, timestamp int64, nonce string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(CanonicalRequest(method, uri, body, timestamp, nonce)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether signature is the signature of the request
+// under secret. It does not check the timestamp or the nonce.
+func VerifySignature(secret, method, uri string, body []byte, timestamp int64, nonce, signature string) bool {
+	want, err := hex.DecodeString(SignRequest(secret, method, uri, body, timestamp, nonce))
+	if err != nil {
+		return false
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(got, want)
+}
@@ -0,0 +1,33 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestSignRequest(t *testing.T) {
+	body := []byte(`{"cloud_packet":{}}`)
+	sig := SignRequest("secret", "post", "/v1/packet/upload", body, 1700000000, "n1")
+
+	// Fixed vector so clients can check their implementation against it.
+	if sig != "b68563f5128b3dd9ade5c6cbc0bef755c62ce842dc0cb6d0a5d33551bf4a7686" {
+		t.Fatalf("unexpected signature %s", sig)
+	}
+	if !VerifySignature("secret", "POST", "/v1/packet/upload", body, 1700000000, "n1", sig) {
+		t.Fatal("valid signature rejected")
+	}
+
+	cases := map[string]bool{
+		"secret":  VerifySignature("other", "POST", "/v1/packet/upload", body, 1700000000, "n1", sig),
+		"method":  VerifySignature("secret", "GET", "/v1/packet/upload", body, 1700000000, "n1", sig),
+		"uri":     VerifySignature("secret", "POST", "/v1/packet/upload?x=1", body, 1700000000, "n1", sig),
+		"body":    VerifySignature("secret", "POST", "/v1/packet/upload", []byte("{}"), 1700000000, "n1", sig),
+		"time":    VerifySignature("secret", "POST", "/v1/packet/upload", body, 1700000001, "n1", sig),
+		"nonce":   VerifySignature("secret", "POST", "/v1/packet/upload", body, 1700000000, "n2", sig),
+		"garbage": VerifySignature("secret", "POST", "/v1/packet/upload", body, 1700000000, "n1", "zz"),
+	}
+	for name, ok := range cases {
+		if ok {
+			t.Errorf("tampered %s accepted", name)
+		}
+	}
+}